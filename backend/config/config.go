@@ -20,17 +20,22 @@ func LoadEnv() {
 	}
 }
 
-// ConnectDB connects to database and performs migrations
-func ConnectDB() {
+// DatabaseDSN builds the PostgreSQL connection string from environment variables
+func DatabaseDSN() string {
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
 	dbUser := os.Getenv("DB_USER")
 	dbPassword := os.Getenv("DB_PASSWORD")
 	dbName := os.Getenv("DB_NAME")
 
-	// PostgreSQL connection string
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Jakarta",
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Jakarta",
 		dbHost, dbUser, dbPassword, dbName, dbPort)
+}
+
+// ConnectDB connects to database and performs migrations
+func ConnectDB() {
+	// PostgreSQL connection string
+	dsn := DatabaseDSN()
 	log.Printf("Connection string: %s", dsn)
 	var err error
 	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
@@ -52,9 +57,44 @@ func ConnectDB() {
 		&model.RouteAvoidancePoint{},
 		&model.TruckRouteDeviation{},
 		&model.TruckIdleDetection{},
+		&model.IdleRule{},
+		&model.IdleZone{},
+		&model.IdleZonePoint{},
+		&model.EngineHoursDaily{},
+		&model.WaypointVisit{},
+		&model.RouteProgress{},
+		&model.RoutePlanStatusHistory{},
+		&model.DirectionsCacheEntry{},
+		&model.RouteGeometryRevision{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 	log.Println("Database migration completed")
-}
\ No newline at end of file
+
+	ensureActiveRoutePlanIndexes()
+	ensureGeometryRevisionIndex()
+}
+
+// ensureActiveRoutePlanIndexes lets the database reject a second active route plan for
+// the same truck or driver. Existing duplicates must be resolved before the index applies.
+func ensureActiveRoutePlanIndexes() {
+	indexes := []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_route_plans_active_truck ON route_plans (truck_id) WHERE status = 'active' AND deleted_at IS NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_route_plans_active_driver ON route_plans (driver_id) WHERE status = 'active' AND deleted_at IS NULL`,
+	}
+	for _, statement := range indexes {
+		if err := DB.Exec(statement).Error; err != nil {
+			log.Printf("Failed to create active route plan index, resolve duplicate active route plans: %v", err)
+		}
+	}
+}
+
+// ensureGeometryRevisionIndex keeps revision numbers unique per route plan, so two reroutes
+// recording a revision at the same time cannot both take the same number
+func ensureGeometryRevisionIndex() {
+	statement := `CREATE UNIQUE INDEX IF NOT EXISTS idx_route_geometry_revisions_plan_revision ON route_geometry_revisions (route_plan_id, revision)`
+	if err := DB.Exec(statement).Error; err != nil {
+		log.Printf("Failed to create geometry revision index, resolve duplicate revision numbers: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
+)
+
+// EngineHoursController handles HTTP requests related to engine hours
+type EngineHoursController struct {
+	engineHoursService service.EngineHoursService
+}
+
+// NewEngineHoursController creates a new instance of EngineHoursController
+func NewEngineHoursController(engineHoursService service.EngineHoursService) *EngineHoursController {
+	return &EngineHoursController{
+		engineHoursService: engineHoursService,
+	}
+}
+
+// GetEngineHoursReport godoc
+// @Summary Get fleet engine hours
+// @Description Get engine-on hours and engine-on idle hours per truck per day
+// @Tags engine-hours
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param start_date query string false "Start date (format: 2006-01-02), defaults to 30 days ago"
+// @Param end_date query string false "End date (format: 2006-01-02), defaults to today"
+// @Success 200 {object} model.BaseResponse "Engine hours report"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Router /engine-hours [get]
+func (c *EngineHoursController) GetEngineHoursReport(ctx *fiber.Ctx) error {
+	startDate, endDate, err := parseReportDateRange(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			err.Error(),
+		))
+	}
+
+	report, err := c.engineHoursService.GetReport(startDate, endDate)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(model.SimpleErrorResponse(
+			fiber.StatusInternalServerError,
+			"Error fetching engine hours: "+err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"engine-hours.getReport",
+		report,
+	))
+}
+
+// GetTruckEngineHours godoc
+// @Summary Get truck engine hours
+// @Description Get engine-on hours and engine-on idle hours of one truck per day
+// @Tags engine-hours
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Truck ID"
+// @Param start_date query string false "Start date (format: 2006-01-02), defaults to 30 days ago"
+// @Param end_date query string false "End date (format: 2006-01-02), defaults to today"
+// @Success 200 {object} model.BaseResponse "Engine hours report"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /trucks/{id}/engine-hours [get]
+func (c *EngineHoursController) GetTruckEngineHours(ctx *fiber.Ctx) error {
+	truckID, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid truck ID",
+		))
+	}
+
+	startDate, endDate, err := parseReportDateRange(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			err.Error(),
+		))
+	}
+
+	report, err := c.engineHoursService.GetTruckReport(uint(truckID), startDate, endDate)
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"engine-hours.getByTruck",
+		report,
+	))
+}
+
+// parseReportDateRange reads start_date and end_date, defaulting to the last 30 days
+func parseReportDateRange(ctx *fiber.Ctx) (time.Time, time.Time, error) {
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -30)
+
+	if startDateStr := ctx.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			return startDate, endDate, fiber.NewError(fiber.StatusBadRequest, "Invalid start date format. Use YYYY-MM-DD")
+		}
+		startDate = parsed
+	}
+
+	if endDateStr := ctx.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			return startDate, endDate, fiber.NewError(fiber.StatusBadRequest, "Invalid end date format. Use YYYY-MM-DD")
+		}
+		// Set end date to end of day
+		endDate = parsed.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+
+	return startDate, endDate, nil
+}
@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
+)
+
+// serviceErrorResponse maps service errors to a status code: ErrNotFound to 404, ErrNotAllowed
+// to 403, ErrRoutePlanConflict to 409 and anything else to 400
+func serviceErrorResponse(ctx *fiber.Ctx, err error) error {
+	code := fiber.StatusBadRequest
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		code = fiber.StatusNotFound
+	case errors.Is(err, service.ErrNotAllowed):
+		code = fiber.StatusForbidden
+	case errors.Is(err, service.ErrRoutePlanConflict):
+		code = fiber.StatusConflict
+	}
+	return ctx.Status(code).JSON(model.SimpleErrorResponse(code, err.Error()))
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
+)
+
+func TestServiceErrorResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", fmt.Errorf("route plan %w", service.ErrNotFound), fiber.StatusNotFound},
+		{"not allowed", fmt.Errorf("driver is %w to explain this idle detection", service.ErrNotAllowed), fiber.StatusForbidden},
+		{"conflict", fmt.Errorf("%w: truck already has active route plan 3", service.ErrRoutePlanConflict), fiber.StatusConflict},
+		{"validation", errors.New("name is required"), fiber.StatusBadRequest},
+		{"message mentioning not found", errors.New("reason code not found in list"), fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(ctx *fiber.Ctx) error {
+				return serviceErrorResponse(ctx, tt.err)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
+)
+
+// IdleRuleController handles HTTP requests related to idle rules
+type IdleRuleController struct {
+	ruleService service.IdleRuleService
+}
+
+// NewIdleRuleController creates a new instance of IdleRuleController
+func NewIdleRuleController(ruleService service.IdleRuleService) *IdleRuleController {
+	return &IdleRuleController{
+		ruleService: ruleService,
+	}
+}
+
+// GetAllIdleRules godoc
+// @Summary Get all idle rules
+// @Description Get all idle detection rules, highest priority first
+// @Tags idle-rules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Success 200 {object} model.BaseResponse "Idle rule data"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Router /idle-rules [get]
+func (c *IdleRuleController) GetAllIdleRules(ctx *fiber.Ctx) error {
+	rules, err := c.ruleService.GetAllRules()
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(model.SimpleErrorResponse(
+			fiber.StatusInternalServerError,
+			"Error fetching idle rules: "+err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-rules.getAll",
+		rules,
+	))
+}
+
+// GetIdleRuleByID godoc
+// @Summary Get idle rule by ID
+// @Description Get a single idle detection rule
+// @Tags idle-rules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Idle rule ID"
+// @Success 200 {object} model.BaseResponse "Idle rule data"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /idle-rules/{id} [get]
+func (c *IdleRuleController) GetIdleRuleByID(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid idle rule ID",
+		))
+	}
+
+	rule, err := c.ruleService.GetRuleByID(uint(id))
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-rules.getByID",
+		rule,
+	))
+}
+
+// CreateIdleRule godoc
+// @Summary Create idle rule
+// @Description Create a new idle detection rule
+// @Tags idle-rules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param request body model.IdleRuleRequest true "Idle rule data"
+// @Success 201 {object} model.BaseResponse "Successfully created idle rule"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Router /idle-rules [post]
+func (c *IdleRuleController) CreateIdleRule(ctx *fiber.Ctx) error {
+	var req model.IdleRuleRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid request body format",
+		))
+	}
+
+	userID := ctx.Locals("userId").(uint)
+
+	rule, err := c.ruleService.CreateRule(req, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(model.SuccessResponse(
+		"idle-rules.create",
+		rule,
+	))
+}
+
+// UpdateIdleRule godoc
+// @Summary Update idle rule
+// @Description Update an existing idle detection rule
+// @Tags idle-rules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Idle rule ID"
+// @Param request body model.IdleRuleRequest true "Idle rule data"
+// @Success 200 {object} model.BaseResponse "Successfully updated idle rule"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /idle-rules/{id} [put]
+func (c *IdleRuleController) UpdateIdleRule(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid idle rule ID",
+		))
+	}
+
+	var req model.IdleRuleRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid request body format",
+		))
+	}
+
+	rule, err := c.ruleService.UpdateRule(uint(id), req)
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-rules.update",
+		rule,
+	))
+}
+
+// DeleteIdleRule godoc
+// @Summary Delete idle rule
+// @Description Delete an idle detection rule
+// @Tags idle-rules
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Idle rule ID"
+// @Success 200 {object} model.BaseResponse "Successfully deleted idle rule"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /idle-rules/{id} [delete]
+func (c *IdleRuleController) DeleteIdleRule(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid idle rule ID",
+		))
+	}
+
+	if err := c.ruleService.DeleteRule(uint(id)); err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-rules.delete",
+		map[string]string{"message": "Idle rule deleted successfully"},
+	))
+}
@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
+)
+
+// IdleZoneController handles HTTP requests related to idle zones
+type IdleZoneController struct {
+	zoneService service.IdleZoneService
+}
+
+// NewIdleZoneController creates a new instance of IdleZoneController
+func NewIdleZoneController(zoneService service.IdleZoneService) *IdleZoneController {
+	return &IdleZoneController{
+		zoneService: zoneService,
+	}
+}
+
+// GetAllIdleZones godoc
+// @Summary Get all idle zones
+// @Description Get all idle exemption zones
+// @Tags idle-zones
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Success 200 {object} model.BaseResponse "Idle zone data"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Router /idle-zones [get]
+func (c *IdleZoneController) GetAllIdleZones(ctx *fiber.Ctx) error {
+	zones, err := c.zoneService.GetAllZones()
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(model.SimpleErrorResponse(
+			fiber.StatusInternalServerError,
+			"Error fetching idle zones: "+err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-zones.getAll",
+		zones,
+	))
+}
+
+// GetIdleZoneByID godoc
+// @Summary Get idle zone by ID
+// @Description Get a single idle exemption zone
+// @Tags idle-zones
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Idle zone ID"
+// @Success 200 {object} model.BaseResponse "Idle zone data"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /idle-zones/{id} [get]
+func (c *IdleZoneController) GetIdleZoneByID(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid idle zone ID",
+		))
+	}
+
+	zone, err := c.zoneService.GetZoneByID(uint(id))
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-zones.getByID",
+		zone,
+	))
+}
+
+// CreateIdleZone godoc
+// @Summary Create idle zone
+// @Description Create a new idle exemption zone
+// @Tags idle-zones
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param request body model.IdleZoneRequest true "Idle zone data"
+// @Success 201 {object} model.BaseResponse "Successfully created idle zone"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Router /idle-zones [post]
+func (c *IdleZoneController) CreateIdleZone(ctx *fiber.Ctx) error {
+	var req model.IdleZoneRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid request body format",
+		))
+	}
+
+	userID := ctx.Locals("userId").(uint)
+
+	zone, err := c.zoneService.CreateZone(req, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(model.SuccessResponse(
+		"idle-zones.create",
+		zone,
+	))
+}
+
+// UpdateIdleZone godoc
+// @Summary Update idle zone
+// @Description Update an existing idle exemption zone
+// @Tags idle-zones
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Idle zone ID"
+// @Param request body model.IdleZoneRequest true "Idle zone data"
+// @Success 200 {object} model.BaseResponse "Successfully updated idle zone"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /idle-zones/{id} [put]
+func (c *IdleZoneController) UpdateIdleZone(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid idle zone ID",
+		))
+	}
+
+	var req model.IdleZoneRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid request body format",
+		))
+	}
+
+	zone, err := c.zoneService.UpdateZone(uint(id), req)
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-zones.update",
+		zone,
+	))
+}
+
+// DeleteIdleZone godoc
+// @Summary Delete idle zone
+// @Description Delete an idle exemption zone
+// @Tags idle-zones
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Idle zone ID"
+// @Success 200 {object} model.BaseResponse "Successfully deleted idle zone"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /idle-zones/{id} [delete]
+func (c *IdleZoneController) DeleteIdleZone(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid idle zone ID",
+		))
+	}
+
+	if err := c.zoneService.DeleteZone(uint(id)); err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-zones.delete",
+		map[string]string{"message": "Idle zone deleted successfully"},
+	))
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
+)
+
+// RouteOptimizationController handles HTTP requests related to multi-stop route optimization
+type RouteOptimizationController struct {
+	optimizationService service.RouteOptimizationService
+}
+
+// NewRouteOptimizationController creates a new instance of RouteOptimizationController
+func NewRouteOptimizationController(optimizationService service.RouteOptimizationService) *RouteOptimizationController {
+	return &RouteOptimizationController{
+		optimizationService: optimizationService,
+	}
+}
+
+// OptimizeRoutes godoc
+// @Summary Optimize multi-stop routes
+// @Description Assign delivery stops with demand and time windows to the available trucks and order them, using the ORS optimization API or the built-in heuristic solver. Every route includes a route_plan_request for POST /route-plans; with create_plans the route plans are created right away, with their geometry computed by the routing service.
+// @Tags route-plans
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param request body model.RouteOptimizationRequest true "Depot, stops and trucks"
+// @Success 200 {object} model.BaseResponse "Optimized routes"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Router /route-plans/optimize [post]
+func (c *RouteOptimizationController) OptimizeRoutes(ctx *fiber.Ctx) error {
+	var req model.RouteOptimizationRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid request body: "+err.Error(),
+		))
+	}
+
+	plannerID := ctx.Locals("userId").(uint)
+
+	result, err := c.optimizationService.OptimizeRoutes(req, plannerID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"route-plans.optimize",
+		result,
+	))
+}
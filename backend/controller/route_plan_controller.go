@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"strconv"
 	"strings"
 
@@ -21,7 +22,7 @@ func NewRoutePlanController(routePlanService service.RoutePlanService) *RoutePla
 
 // CreateRoutePlan godoc
 // @Summary Create a new route plan
-// @Description Create a new route plan with waypoints and avoidance areas
+// @Description Create a new route plan with waypoints and avoidance areas. Without route_geometry the route is computed by the routing service; a route_geometry sent by the client must pass every waypoint in order and stay out of the approved avoidance areas. The validation report is returned in validation. Route plans of the same truck or driver whose planned window overlaps are returned in schedule_conflicts.
 // @Tags route-plans
 // @Accept json
 // @Produce json
@@ -31,6 +32,7 @@ func NewRoutePlanController(routePlanService service.RoutePlanService) *RoutePla
 // @Success 201 {object} model.BaseResponse "Successfully created route plan"
 // @Failure 400 {object} model.BaseResponse "Bad request"
 // @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 422 {object} model.BaseResponse "Route geometry does not match the waypoints or avoidance areas"
 // @Router /route-plans [post]
 func (c *RoutePlanController) CreateRoutePlan(ctx *fiber.Ctx) error {
 	// Parse request body
@@ -62,6 +64,10 @@ func (c *RoutePlanController) CreateRoutePlan(ctx *fiber.Ctx) error {
 
 	// Create route plan
 	result, err := c.routePlanService.CreateRoutePlan(req, plannerId)
+	var validationErr *service.RouteValidationError
+	if errors.As(err, &validationErr) {
+		return routeValidationErrorResponse(ctx, validationErr)
+	}
 	if err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
 			fiber.StatusBadRequest,
@@ -146,18 +152,20 @@ func (c *RoutePlanController) GetAllRoutePlans(ctx *fiber.Ctx) error {
 
 // UpdateRoutePlanStatus godoc
 // @Summary Update route plan status
-// @Description Update the status of a route plan
+// @Description Move a route plan to a new status. Allowed transitions: planned to active, on confirmation or cancelled; active to completed, on confirmation or cancelled; on confirmation to planned, active or cancelled. Drivers may start, complete and send their own route plans for confirmation, planners may re-plan and cancel, management may do all. The change is recorded in the status history.
 // @Tags route-plans
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param Authorization header string true "Bearer token" default(Bearer <token>)
 // @Param id path int true "Route plan ID"
-// @Param request body map[string]string true "Status update"
+// @Param request body model.RoutePlanStatusUpdateRequest true "Status update"
 // @Success 200 {object} model.BaseResponse "Success message"
-// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 400 {object} model.BaseResponse "Bad request or transition not allowed"
 // @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Role not allowed to set this status"
 // @Failure 404 {object} model.BaseResponse "Not found"
+// @Failure 409 {object} model.BaseResponse "Truck or driver already has an active route plan"
 // @Router /route-plans/{id}/status [put]
 func (c *RoutePlanController) UpdateRoutePlanStatus(ctx *fiber.Ctx) error {
 	// Get ID from params
@@ -171,7 +179,7 @@ func (c *RoutePlanController) UpdateRoutePlanStatus(ctx *fiber.Ctx) error {
 	}
 
 	// Parse request body
-	var req map[string]string
+	var req model.RoutePlanStatusUpdateRequest
 	if err := ctx.BodyParser(&req); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
 			fiber.StatusBadRequest,
@@ -180,20 +188,19 @@ func (c *RoutePlanController) UpdateRoutePlanStatus(ctx *fiber.Ctx) error {
 	}
 
 	// Check if status is provided
-	status, ok := req["status"]
-	if !ok || status == "" {
+	if req.Status == "" {
 		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
 			fiber.StatusBadRequest,
 			"Status is required",
 		))
 	}
 
+	userID := ctx.Locals("userId").(uint)
+	role, _ := ctx.Locals("role").(string)
+
 	// Update status
-	if err := c.routePlanService.UpdateRoutePlanStatus(uint(id), status); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
-			fiber.StatusBadRequest,
-			err.Error(),
-		))
+	if err := c.routePlanService.UpdateRoutePlanStatus(uint(id), req.Status, req.Reason, userID, role); err != nil {
+		return serviceErrorResponse(ctx, err)
 	}
 
 	// Return response
@@ -203,6 +210,74 @@ func (c *RoutePlanController) UpdateRoutePlanStatus(ctx *fiber.Ctx) error {
 	))
 }
 
+// GetStatusHistory godoc
+// @Summary Get route plan status history
+// @Description Get every status change of a route plan with its actor, time and reason, oldest first
+// @Tags route-plans
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Route plan ID"
+// @Success 200 {object} model.BaseResponse "Status history"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /route-plans/{id}/status-history [get]
+func (c *RoutePlanController) GetStatusHistory(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid route plan ID",
+		))
+	}
+
+	history, err := c.routePlanService.GetStatusHistory(uint(id))
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"route-plans.getStatusHistory",
+		history,
+	))
+}
+
+// GetGeometryRevisions godoc
+// @Summary Get route plan geometry revisions
+// @Description Get every route geometry a route plan has had, oldest first. Active routes are recomputed around newly approved avoidance areas, each recomputation is a new revision.
+// @Tags route-plans
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Route plan ID"
+// @Success 200 {object} model.BaseResponse "Geometry revisions"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /route-plans/{id}/geometry-revisions [get]
+func (c *RoutePlanController) GetGeometryRevisions(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid route plan ID",
+		))
+	}
+
+	revisions, err := c.routePlanService.GetGeometryRevisions(uint(id))
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"route-plans.getGeometryRevisions",
+		revisions,
+	))
+}
+
 // DeleteRoutePlan godoc
 // @Summary Delete a route plan
 // @Description Delete a route plan and all associated data
@@ -361,7 +436,7 @@ func (c *RoutePlanController) AddAvoidanceArea(ctx *fiber.Ctx) error {
 
 // UpdateAvoidanceAreaStatus godoc
 // @Summary Update avoidance area status
-// @Description Update the status of an avoidance area
+// @Description Update the status of an avoidance area. Approving an area recomputes, in the background, the active routes whose remaining route enters it (every active route for a permanent area, the area's own route otherwise) from the truck's current position through the remaining waypoints, and notifies the driver over WebSocket and push. reroute_pending tells whether that check was started; its outcome is published to the alerts channel as an avoidance_area_reroute event.
 // @Tags route-plans
 // @Accept json
 // @Produce json
@@ -404,7 +479,8 @@ func (c *RoutePlanController) UpdateAvoidanceAreaStatus(ctx *fiber.Ctx) error {
     }
 
     // Update status
-    if err := c.routePlanService.UpdateAvoidanceAreaStatus(uint(id), status); err != nil {
+    result, err := c.routePlanService.UpdateAvoidanceAreaStatus(uint(id), status)
+    if err != nil {
         if strings.Contains(err.Error(), "not found") {
             return ctx.Status(fiber.StatusNotFound).JSON(model.SimpleErrorResponse(
                 fiber.StatusNotFound,
@@ -420,7 +496,7 @@ func (c *RoutePlanController) UpdateAvoidanceAreaStatus(ctx *fiber.Ctx) error {
     // Return response
     return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
         "route-plans.updateAvoidanceAreaStatus",
-        map[string]string{"message": "Avoidance area status updated successfully"},
+        result,
     ))
 }
 
@@ -535,6 +611,50 @@ func (c *RoutePlanController) UpdateRoutePlan(ctx *fiber.Ctx) error {
 	))
 }
 
+// UpdateDeviationSettings godoc
+// @Summary Update route plan deviation settings
+// @Description Override the global route deviation thresholds for one route plan. Omitted fields use the global settings. Only management and planners may change them.
+// @Tags route-plans
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Route plan ID"
+// @Param request body model.RouteDeviationSettings true "Deviation settings"
+// @Success 200 {object} model.BaseResponse "Updated route plan"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /route-plans/{id}/deviation-settings [put]
+func (c *RoutePlanController) UpdateDeviationSettings(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid route plan ID",
+		))
+	}
+
+	var req model.RouteDeviationSettings
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid request body: " + err.Error(),
+		))
+	}
+
+	updatedRoutePlan, err := c.routePlanService.UpdateDeviationSettings(uint(id), &req)
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"route-plans.updateDeviationSettings",
+		updatedRoutePlan,
+	))
+}
+
 // GetPermanentAvoidanceAreas godoc
 // @Summary Get all permanent avoidance areas
 // @Description Get a list of all permanent avoidance areas
@@ -589,4 +709,23 @@ func (c *RoutePlanController) GetNonPermanentAvoidanceAreas(ctx *fiber.Ctx) erro
         "route-plans.getNonPermanentAvoidanceAreas",
         avoidanceAreas,
     ))
-}
\ No newline at end of file
+}
+
+// routeValidationErrorResponse returns the validation report of a rejected route geometry,
+// with one error entry per issue
+func routeValidationErrorResponse(ctx *fiber.Ctx, validationErr *service.RouteValidationError) error {
+	var infos []model.ErrorInfo
+	for _, issue := range validationErr.Report.Issues {
+		infos = append(infos, model.ErrorInfo{
+			Domain:       "route-plans",
+			Reason:       issue.Code,
+			Message:      issue.Message,
+			Location:     issue.Location,
+			LocationType: "body",
+		})
+	}
+
+	response := model.ErrorResponse(fiber.StatusUnprocessableEntity, validationErr.Error(), infos)
+	response.Data = validationErr.Report
+	return ctx.Status(fiber.StatusUnprocessableEntity).JSON(response)
+}
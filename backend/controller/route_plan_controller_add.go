@@ -7,7 +7,7 @@ import (
 
 // GetAllActiveRoutePlans godoc
 // @Summary Get all active route plans
-// @Description Get a list of all route plans with status "active"
+// @Description Get a list of all route plans with status "active", each with the truck's progress along the route once known
 // @Tags route-plans
 // @Accept json
 // @Produce json
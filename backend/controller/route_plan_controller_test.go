@@ -0,0 +1,74 @@
+package controller_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/controller"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/middleware"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+// fakeDeviationSettingsService records deviation settings updates
+type fakeDeviationSettingsService struct {
+	service.RoutePlanService
+	updates int
+}
+
+func (s *fakeDeviationSettingsService) UpdateDeviationSettings(id uint, settings *model.RouteDeviationSettings) (*model.RoutePlanResponse, error) {
+	s.updates++
+	return &model.RoutePlanResponse{ID: id}, nil
+}
+
+func TestUpdateDeviationSettingsRoles(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	tests := []struct {
+		role        string
+		want        int
+		wantUpdates int
+	}{
+		{"management", fiber.StatusOK, 1},
+		{"planner", fiber.StatusOK, 1},
+		{"driver", fiber.StatusForbidden, 0},
+		{"", fiber.StatusUnauthorized, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			svc := &fakeDeviationSettingsService{}
+			routePlanController := controller.NewRoutePlanController(svc)
+
+			// Same middleware as the route in main.go
+			app := fiber.New()
+			routePlans := app.Group("/route-plans")
+			routePlans.Use(middleware.Protected())
+			routePlans.Put("/:id/deviation-settings", middleware.RoleAuthorization("management", "planner"), routePlanController.UpdateDeviationSettings)
+
+			req := httptest.NewRequest(fiber.MethodPut, "/route-plans/5/deviation-settings", strings.NewReader(`{"enter_meters":500}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.role != "" {
+				token, err := utils.GenerateToken(model.User{ID: 9, Role: tt.role})
+				if err != nil {
+					t.Fatalf("generate token: %v", err)
+				}
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if svc.updates != tt.wantUpdates {
+				t.Errorf("updates = %d, want %d", svc.updates, tt.wantUpdates)
+			}
+		})
+	}
+}
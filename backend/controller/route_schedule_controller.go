@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
+)
+
+// RouteScheduleController handles HTTP requests related to route plan schedules
+type RouteScheduleController struct {
+	scheduleService service.RouteScheduleService
+}
+
+// NewRouteScheduleController creates a new instance of RouteScheduleController
+func NewRouteScheduleController(scheduleService service.RouteScheduleService) *RouteScheduleController {
+	return &RouteScheduleController{
+		scheduleService: scheduleService,
+	}
+}
+
+// GetOnTimeReport godoc
+// @Summary Get on-time performance report
+// @Description Compare waypoint arrival windows and planned start/end times with the actual visits, per route and per driver. Defaults to the last 30 days.
+// @Tags route-plans
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Param driver_id query int false "Only route plans of this driver"
+// @Success 200 {object} model.BaseResponse "On-time performance report"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Router /route-plans/on-time-report [get]
+func (c *RouteScheduleController) GetOnTimeReport(ctx *fiber.Ctx) error {
+	startDate, endDate, err := parseReportDateRange(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			err.Error(),
+		))
+	}
+
+	var driverID *uint
+	if driverIDStr := ctx.Query("driver_id"); driverIDStr != "" {
+		parsed, err := strconv.ParseUint(driverIDStr, 10, 32)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+				fiber.StatusBadRequest,
+				"Invalid driver ID",
+			))
+		}
+		id := uint(parsed)
+		driverID = &id
+	}
+
+	report, err := c.scheduleService.GetOnTimeReport(startDate, endDate, driverID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(model.SimpleErrorResponse(
+			fiber.StatusInternalServerError,
+			"Error fetching on-time report: "+err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"route-plans.getOnTimeReport",
+		report,
+	))
+}
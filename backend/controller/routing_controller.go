@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
@@ -17,14 +19,15 @@ func NewRoutingController(routingService service.RoutingService) *RoutingControl
 }
 
 // GetDirections godoc
-// @Summary Get directions from external routing service (OpenRouteService)
-// @Description Proxy API call to routing service to get directions
+// @Summary Get directions from the configured routing backend
+// @Description Get directions in OpenRouteService format from the routing providers in ROUTING_PROVIDERS (public ORS, self-hosted ORS, OSRM or Valhalla), falling back to the next provider on failure. Responses are cached by normalized request; the X-Cache header tells HIT, MISS or BYPASS. Approved permanent avoidance areas, plus the approved areas of route_plan_id, are added to options.avoid_polygons automatically and listed in avoidance_areas with whether they affected the route. When only providers that cannot avoid polygons (OSRM) answer, the route is computed without them and avoidance_areas_applied is false.
 // @Tags routing
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param Authorization header string true "Bearer token" default(Bearer <token>)
 // @Param request body model.RoutingRequest true "Routing request parameters"
+// @Param cache query bool false "Set to false to bypass the directions cache (Cache-Control: no-cache works too)"
 // @Success 200 {object} interface{} "Directions data"
 // @Failure 400 {object} model.BaseResponse "Bad request"
 // @Failure 500 {object} model.BaseResponse "Internal server error"
@@ -50,8 +53,11 @@ func (c *RoutingController) GetDirections(ctx *fiber.Ctx) error {
 		))
 	}
 	
+	// Planner can force fresh directions, e.g. after the road situation changed
+	bypassCache := ctx.Query("cache") == "false" || strings.Contains(ctx.Get("Cache-Control"), "no-cache")
+
 	// Call service to get directions
-	responseBody, err := c.routingService.GetDirections(requestBody)
+	responseBody, cached, err := c.routingService.GetDirections(ctx.UserContext(), requestBody, bypassCache)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(model.SimpleErrorResponse(
 			fiber.StatusInternalServerError,
@@ -60,6 +66,14 @@ func (c *RoutingController) GetDirections(ctx *fiber.Ctx) error {
 	}
 	
 	// Send response to client
+	switch {
+	case bypassCache:
+		ctx.Set("X-Cache", "BYPASS")
+	case cached:
+		ctx.Set("X-Cache", "HIT")
+	default:
+		ctx.Set("X-Cache", "MISS")
+	}
 	ctx.Set("Content-Type", "application/json")
 	return ctx.Send(responseBody)
 }
\ No newline at end of file
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
@@ -149,7 +150,7 @@ func (c *TruckIdleController) GetActiveIdleDetections(ctx *fiber.Ctx) error {
 
 // ResolveIdleDetection godoc
 // @Summary Resolve idle detection
-// @Description Mark an idle detection as resolved
+// @Description Mark an idle detection as resolved, recording the resolving user and time
 // @Tags idle-detections
 // @Accept json
 // @Produce json
@@ -171,8 +172,10 @@ func (c *TruckIdleController) ResolveIdleDetection(ctx *fiber.Ctx) error {
 		))
 	}
 
+	userID := ctx.Locals("userId").(uint)
+
 	// Resolve idle detection
-	if err := c.idleService.ResolveIdleDetection(uint(idleID)); err != nil {
+	if err := c.idleService.ResolveIdleDetection(uint(idleID), userID); err != nil {
 		return ctx.Status(fiber.StatusNotFound).JSON(model.SimpleErrorResponse(
 			fiber.StatusNotFound,
 			err.Error(),
@@ -185,3 +188,188 @@ func (c *TruckIdleController) ResolveIdleDetection(ctx *fiber.Ctx) error {
 		map[string]string{"message": "Idle detection resolved successfully"},
 	))
 }
+
+// AcknowledgeIdleDetection godoc
+// @Summary Acknowledge idle detection
+// @Description Acknowledge an idle event with a reason code (traffic, loading, breakdown, rest, unauthorized_stop) and comment, resolving it if still open
+// @Tags idle-detections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Idle detection ID"
+// @Param request body model.IdleAcknowledgeRequest true "Reason code and comment"
+// @Success 200 {object} model.BaseResponse "Acknowledged idle detection"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /idle-detections/{id}/acknowledge [put]
+func (c *TruckIdleController) AcknowledgeIdleDetection(ctx *fiber.Ctx) error {
+	idleID, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid idle detection ID",
+		))
+	}
+
+	var req model.IdleAcknowledgeRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid request body format",
+		))
+	}
+
+	userID := ctx.Locals("userId").(uint)
+
+	idle, err := c.idleService.AcknowledgeIdleDetection(uint(idleID), userID, req)
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-detections.acknowledge",
+		idle,
+	))
+}
+
+// ExplainIdleDetection godoc
+// @Summary Explain idle detection
+// @Description Let the driver of the route add an explanation to an idle event
+// @Tags idle-detections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Idle detection ID"
+// @Param request body model.IdleExplanationRequest true "Driver explanation"
+// @Success 200 {object} model.BaseResponse "Idle detection with explanation"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Failure 404 {object} model.BaseResponse "Not found"
+// @Router /idle-detections/{id}/explanation [put]
+func (c *TruckIdleController) ExplainIdleDetection(ctx *fiber.Ctx) error {
+	idleID, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid idle detection ID",
+		))
+	}
+
+	var req model.IdleExplanationRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid request body format",
+		))
+	}
+
+	driverID := ctx.Locals("userId").(uint)
+
+	idle, err := c.idleService.ExplainIdleDetection(uint(idleID), driverID, req)
+	if err != nil {
+		return serviceErrorResponse(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-detections.explain",
+		idle,
+	))
+}
+
+// GetIdleReasonStats godoc
+// @Summary Get idle reason statistics
+// @Description Aggregate idle events by acknowledgement reason code for each driver
+// @Tags idle-detections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param start_date query string false "Start date (format: 2006-01-02), defaults to 30 days ago"
+// @Param end_date query string false "End date (format: 2006-01-02), defaults to today"
+// @Success 200 {object} model.BaseResponse "Idle reason statistics"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Failure 403 {object} model.BaseResponse "Forbidden"
+// @Router /idle-detections/reason-stats [get]
+func (c *TruckIdleController) GetIdleReasonStats(ctx *fiber.Ctx) error {
+	startDate, endDate, err := parseReportDateRange(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			err.Error(),
+		))
+	}
+
+	stats, err := c.idleService.GetIdleReasonStats(startDate, endDate)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(model.SimpleErrorResponse(
+			fiber.StatusInternalServerError,
+			"Error fetching idle reason statistics: "+err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-detections.reasonStats",
+		stats,
+	))
+}
+
+// GetIdleSummary godoc
+// @Summary Get idle summary
+// @Description Summarize idle time inside idle zones (productive waiting) and outside zones (unexplained stops)
+// @Tags idle-detections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param start_date query string false "Start date (format: 2006-01-02), defaults to 30 days ago"
+// @Param end_date query string false "End date (format: 2006-01-02), defaults to today"
+// @Success 200 {object} model.BaseResponse "Idle summary"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Router /idle-detections/summary [get]
+func (c *TruckIdleController) GetIdleSummary(ctx *fiber.Ctx) error {
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -30)
+
+	if startDateStr := ctx.Query("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+				fiber.StatusBadRequest,
+				"Invalid start date format. Use YYYY-MM-DD",
+			))
+		}
+		startDate = parsed
+	}
+
+	if endDateStr := ctx.Query("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+				fiber.StatusBadRequest,
+				"Invalid end date format. Use YYYY-MM-DD",
+			))
+		}
+		// Set end date to end of day
+		endDate = parsed.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+
+	summary, err := c.idleService.GetIdleSummary(startDate, endDate)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(model.SimpleErrorResponse(
+			fiber.StatusInternalServerError,
+			"Error fetching idle summary: "+err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"idle-detections.summary",
+		summary,
+	))
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/service"
+)
+
+// WaypointVisitController handles HTTP requests related to waypoint visits
+type WaypointVisitController struct {
+	visitService service.WaypointVisitService
+}
+
+// NewWaypointVisitController creates a new instance of WaypointVisitController
+func NewWaypointVisitController(visitService service.WaypointVisitService) *WaypointVisitController {
+	return &WaypointVisitController{
+		visitService: visitService,
+	}
+}
+
+// GetWaypointVisits godoc
+// @Summary Get waypoint visits of a route plan
+// @Description Get the arrival, departure and dwell time of the truck at each waypoint, in arrival order
+// @Tags route-plans
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" default(Bearer <token>)
+// @Param id path int true "Route plan ID"
+// @Success 200 {object} model.BaseResponse "Waypoint visits"
+// @Failure 400 {object} model.BaseResponse "Bad request"
+// @Failure 401 {object} model.BaseResponse "Unauthorized"
+// @Router /route-plans/{id}/visits [get]
+func (c *WaypointVisitController) GetWaypointVisits(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(model.SimpleErrorResponse(
+			fiber.StatusBadRequest,
+			"Invalid route plan ID",
+		))
+	}
+
+	visits, err := c.visitService.GetVisitsByRoutePlanID(uint(id))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(model.SimpleErrorResponse(
+			fiber.StatusInternalServerError,
+			"Error fetching waypoint visits: "+err.Error(),
+		))
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(model.SuccessResponse(
+		"route-plans.getVisits",
+		visits,
+	))
+}
@@ -1,6 +1,7 @@
 import ws from 'k6/ws';
+import http from 'k6/http';
 import { check, sleep } from 'k6';
-import { BASE_URL, OPTIONS } from './config.js';
+import { BASE_URL, OPTIONS, getAuthToken } from './config.js';
 
 // Modify options for WebSocket testing
 export const options = {
@@ -19,7 +20,8 @@ export const options = {
 export default function () {
   // Convert BASE_URL from http to ws
   const wsBaseUrl = BASE_URL.replace('http://', 'ws://').replace('/api/v1', '');
-  const wsUrl = `${wsBaseUrl}/ws`;
+  const token = getAuthToken(http);
+  const wsUrl = `${wsBaseUrl}/ws?token=${token}`;
   
   // Define WebSocket parameters
   const params = {
@@ -40,6 +42,12 @@ export default function () {
         content: 'Load test message',
         timestamp: Date.now()
       }));
+
+      // Subscribe to fleet telemetry and alerts
+      socket.send(JSON.stringify({
+        type: 'subscribe',
+        channels: ['fleet', 'alerts']
+      }));
     });
     
     // Message received (onmessage)
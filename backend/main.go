@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -68,26 +71,66 @@ func main() {
 	deviationRepo := repository.NewRouteDeviationRepository()
 	fuelReceiptRepo := repository.NewFuelReceiptRepository()
 	truckIdleRepo := repository.NewTruckIdleRepository()
+	idleRuleRepo := repository.NewIdleRuleRepository()
+	idleZoneRepo := repository.NewIdleZoneRepository()
+	engineHoursRepo := repository.NewEngineHoursRepository()
+	waypointVisitRepo := repository.NewWaypointVisitRepository()
+	routeProgressRepo := repository.NewRouteProgressRepository()
+	routeStatusHistoryRepo := repository.NewRoutePlanStatusHistoryRepository()
+	directionsCacheRepo := repository.NewDirectionsCacheRepository()
+	routeRevisionRepo := repository.NewRouteGeometryRevisionRepository()
+
+	// Tugas background yang ditunggu saat server berhenti
+	backgroundWorker := service.NewBackgroundWorker()
 
 	// Initialize services
 	authService := service.NewAuthService(userRepo)
 	truckService := service.NewTruckService(truckRepo)
 	truckHistoryService := service.NewTruckHistoryService(truckHistoryRepo)
-	routingSerivce := service.NewRoutingService()
+	directionsCache := service.NewDirectionsCache(service.LoadDirectionsCacheConfig(), directionsCacheRepo)
+	routingConfig := service.LoadRoutingConfig()
+	routingSerivce := service.NewRoutingService(routingConfig, directionsCache, routePlanRepo)
 	userService := service.NewUserService(userRepo)
-	routingPlanService := service.NewRoutePlanService(routePlanRepo, truckRepo, userRepo)
+	routeGeometryCache := service.NewRouteGeometryCache(routePlanRepo)
+	routeProgressService := service.NewRouteProgressService(routeProgressRepo, truckRepo, routePlanRepo, routeGeometryCache)
+	routingPlanService := service.NewRoutePlanService(routePlanRepo, truckRepo, userRepo, waypointVisitRepo, routeStatusHistoryRepo, routeGeometryCache, routingSerivce, routeRevisionRepo, routeProgressService, backgroundWorker)
+	waypointVisitService := service.NewWaypointVisitService(waypointVisitRepo, truckRepo, routePlanRepo)
+	routeLifecycleService := service.NewRouteLifecycleService(
+		truckRepo,
+		routePlanRepo,
+		waypointVisitRepo,
+		routeProgressRepo,
+		routeStatusHistoryRepo,
+		routeGeometryCache,
+	)
+	routeScheduleService := service.NewRouteScheduleService(
+		routePlanRepo,
+		waypointVisitRepo,
+		routeProgressRepo,
+		truckRepo,
+		userRepo,
+	)
+	routeOptimizationService := service.NewRouteOptimizationService(routingPlanService, routingSerivce, routingConfig)
 	deviationService := service.NewRouteDeviationService(
 		truckRepo,
 		routePlanRepo,
 		deviationRepo,
 		userRepo, // Add userRepo here so we can get driver names
+		routeGeometryCache,
 	)
 	// Initialize idle detection service
+	idleRuleService := service.NewIdleRuleService(idleRuleRepo)
+	idleZoneService := service.NewIdleZoneService(idleZoneRepo)
+	engineHoursService := service.NewEngineHoursService(engineHoursRepo, truckRepo)
 	truckIdleService := service.NewTruckIdleService(
 		truckIdleRepo,
 		truckRepo,
 		userRepo,
 		routePlanRepo,
+		truckHistoryRepo,
+		idleRuleService,
+		idleZoneService,
+		engineHoursService,
 	)
 	// Initialize OCR service
 	ocrService := service.NewOCRService()
@@ -99,6 +142,7 @@ func main() {
 		routePlanRepo,
 		userRepo,
 		routingPlanService,
+		routeProgressService,
 	)
 
 	// Set repositories and services for MQTT handlers
@@ -107,9 +151,37 @@ func main() {
 	mqtt.SetTruckHistoryRepository(truckHistoryRepo)
 	mqtt.SetRouteDeviationService(deviationService)
 	mqtt.SetTruckIdleService(truckIdleService)
+	mqtt.SetWaypointVisitService(waypointVisitService)
+	mqtt.SetRouteProgressService(routeProgressService)
+	mqtt.SetRouteLifecycleService(routeLifecycleService)
+
+	// Restore idle detector state lost on restart and close idles of offline trucks
+	if err := truckIdleService.RebuildState(time.Now().Add(-service.IdleRebuildWindow)); err != nil {
+		log.Printf("Failed to rebuild idle detection state: %v", err)
+	}
+	truckIdleService.StartWatchdog(service.IdleWatchdogInterval, service.IdleOfflineTimeout)
+
+	// Flag route plans that were never started
+	routeLifecycleService.StartWatchdog(service.RouteLifecycleWatchdogInterval, service.RouteNotStartedAfter)
+
+	// Alert on waypoints that will be or already are reached after their latest arrival time
+	routeScheduleService.StartWatchdog(service.RouteScheduleWatchdogInterval)
 
 	// Websocket
-	websocket.InitHub()
+	backplane, err := websocket.NewBackplane(websocket.BackplaneConfig{
+		Kind:        os.Getenv("WS_BACKPLANE"),
+		RedisURL:    os.Getenv("REDIS_URL"),
+		PostgresDSN: config.DatabaseDSN(),
+	})
+	if err != nil {
+		log.Printf("Failed to initialize WebSocket backplane, using in-memory: %v", err)
+		backplane = websocket.NewMemoryBackplane()
+	}
+	websocket.InitHub(backplane)
+	websocket.GetHub().SetAuthorizer(service.NewWebSocketAuthorizer(routePlanRepo, truckRepo))
+	replayBufferSize, _ := strconv.Atoi(os.Getenv("WS_REPLAY_BUFFER_SIZE"))
+	fleetReplayBufferSize, _ := strconv.Atoi(os.Getenv("WS_FLEET_REPLAY_BUFFER_SIZE"))
+	websocket.GetHub().SetReplayBufferSizes(replayBufferSize, fleetReplayBufferSize)
 
 	// S3
 	s3Service, _ := service.NewS3Service()
@@ -134,9 +206,16 @@ func main() {
 	fuelReceiptController := controller.NewFuelReceiptController(fuelReceiptService)
 	ocrController := controller.NewOCRController(ocrService)
 	truckIdleController := controller.NewTruckIdleController(truckIdleService)
+	idleRuleController := controller.NewIdleRuleController(idleRuleService)
+	idleZoneController := controller.NewIdleZoneController(idleZoneService)
+	engineHoursController := controller.NewEngineHoursController(engineHoursService)
+	waypointVisitController := controller.NewWaypointVisitController(waypointVisitService)
+	routeScheduleController := controller.NewRouteScheduleController(routeScheduleService)
+	routeOptimizationController := controller.NewRouteOptimizationController(routeOptimizationService)
 	// Initialize route deviation controller
 	routeDeviationController := controller.NewRouteDeviationController(deviationService)
 	metricsController := controller.NewMetricsController()
+	service.RegisterDirectionsCacheMetrics(controller.GetRegistry())
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
@@ -178,6 +257,29 @@ func main() {
 		// IsWebSocketUpgrade returns true if the client requested upgrade to the WebSocket protocol
 		if ws.IsWebSocketUpgrade(c) {
 			c.Locals("allowed", true)
+
+			// Authenticate on connect when a token is supplied, otherwise the
+			// client has to authenticate with its first message
+			token := c.Query("token")
+			if token == "" {
+				token = strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+			}
+			if token != "" {
+				userId, role, err := utils.ValidateToken(token)
+				if err != nil {
+					return fiber.ErrUnauthorized
+				}
+				c.Locals("userId", userId)
+				c.Locals("role", role)
+			}
+
+			// Reconnecting clients pass the last event they saw to get the missed ones replayed
+			if lastEventID, err := strconv.ParseUint(c.Query("last_event_id"), 10, 64); err == nil {
+				c.Locals("lastEventId", lastEventID)
+			}
+
+			// Clients opt into the compact binary fleet stream with ?stream=compact
+			c.Locals("compact", c.Query("stream") == websocket.StreamCompact)
 			return c.Next()
 		}
 		return fiber.ErrUpgradeRequired
@@ -188,17 +290,39 @@ func main() {
 	// WebSocket endpoint
 	app.Get("/ws", ws.New(func(c *ws.Conn) {
 		// Register client
-		client := &websocket.Client{Conn: c, LastPing: time.Now()}
+		compact, _ := c.Locals("compact").(bool)
+		client := &websocket.Client{Conn: c, LastPing: time.Now(), Compact: compact}
 		hub := websocket.GetHub()
+		if userId, ok := c.Locals("userId").(uint); ok {
+			role, _ := c.Locals("role").(string)
+			hub.SetIdentity(client, userId, role)
+		}
+		if lastEventID, ok := c.Locals("lastEventId").(uint64); ok {
+			hub.SetResumePoint(client, lastEventID)
+		}
 		hub.Register(client)
 
+		// Close connections that do not authenticate in time
+		if !hub.IsAuthenticated(client) {
+			authTimer := hub.RequireAuthentication(client, websocket.AuthTimeout, func() {
+				log.Println("Closing WebSocket connection that did not authenticate")
+				c.Close()
+			})
+			defer authTimer.Stop()
+		}
+
 		// Create a done channel to signal when the connection is closed
 		done := make(chan struct{})
 
 		// Send connection confirmation message
 		testMsg := map[string]interface{}{
-			"type":    "connection_established",
-			"message": "WebSocket connection successfully established",
+			"type":          "connection_established",
+			"message":       "WebSocket connection successfully established",
+			"authenticated": hub.IsAuthenticated(client),
+			"stream":        websocket.StreamJSON,
+		}
+		if compact {
+			testMsg["stream"] = websocket.StreamCompact
 		}
 		testJSON, _ := json.Marshal(testMsg)
 		if err := c.WriteMessage(ws.TextMessage, testJSON); err != nil {
@@ -241,13 +365,9 @@ func main() {
 			// Update the client's LastPing time when we receive any message
 			client.LastPing = time.Now()
 
-			// Add special handling for pong messages
-			var msg map[string]interface{}
-			if err := json.Unmarshal(message, &msg); err == nil {
-				if msgType, ok := msg["type"].(string); ok && msgType == "pong" {
-					// Explicitly update LastPing in the hub
-					hub.UpdateClientPing(client)
-				}
+			// Handle auth, pong and subscribe/unsubscribe control messages
+			if err := hub.HandleClientMessage(client, message); err != nil {
+				break
 			}
 		}
 
@@ -256,6 +376,9 @@ func main() {
 
 		// Unregister client when function returns
 		hub.Unregister(client)
+	}, ws.Config{
+		// Negotiate permessage-deflate with clients that support it
+		EnableCompression: true,
 	}))
 
 	// Routes
@@ -290,13 +413,41 @@ func main() {
 	// Add truck idle detection routes
 	trucks.Get("/:id/idle-detections", truckIdleController.GetIdleDetectionsByTruckID)
 	trucks.Get("/mac/:macID/idle-detections", truckIdleController.GetIdleDetectionsByMacID)
+	trucks.Get("/:id/engine-hours", engineHoursController.GetTruckEngineHours)
 
 	// Idle detection routes
 	idle := api.Group("/idle-detections")
 	idle.Use(middleware.Protected())
 	idle.Get("/", truckIdleController.GetAllIdleDetections)
 	idle.Get("/active", truckIdleController.GetActiveIdleDetections)
+	idle.Get("/summary", truckIdleController.GetIdleSummary)
+	idle.Get("/reason-stats", middleware.RoleAuthorization("management"), truckIdleController.GetIdleReasonStats)
 	idle.Put("/:id/resolve", truckIdleController.ResolveIdleDetection)
+	idle.Put("/:id/acknowledge", middleware.RoleAuthorization("management"), truckIdleController.AcknowledgeIdleDetection)
+	idle.Put("/:id/explanation", middleware.RoleAuthorization("driver"), truckIdleController.ExplainIdleDetection)
+
+	// Engine hours routes
+	engineHours := api.Group("/engine-hours")
+	engineHours.Use(middleware.RoleAuthorization("management"))
+	engineHours.Get("/", engineHoursController.GetEngineHoursReport)
+
+	// Idle rule routes
+	idleRules := api.Group("/idle-rules")
+	idleRules.Use(middleware.RoleAuthorization("management"))
+	idleRules.Get("/", idleRuleController.GetAllIdleRules)
+	idleRules.Get("/:id", idleRuleController.GetIdleRuleByID)
+	idleRules.Post("/", idleRuleController.CreateIdleRule)
+	idleRules.Put("/:id", idleRuleController.UpdateIdleRule)
+	idleRules.Delete("/:id", idleRuleController.DeleteIdleRule)
+
+	// Idle zone routes
+	idleZones := api.Group("/idle-zones")
+	idleZones.Use(middleware.RoleAuthorization("management"))
+	idleZones.Get("/", idleZoneController.GetAllIdleZones)
+	idleZones.Get("/:id", idleZoneController.GetIdleZoneByID)
+	idleZones.Post("/", idleZoneController.CreateIdleZone)
+	idleZones.Put("/:id", idleZoneController.UpdateIdleZone)
+	idleZones.Delete("/:id", idleZoneController.DeleteIdleZone)
 
 	// Route deviation routes
 	deviations := api.Group("/route-deviations")
@@ -315,14 +466,20 @@ func main() {
 	// Endpoint untuk rute aktif - HARUS sebelum /:id agar tidak bentrok
 	routePlans.Get("/active", driverLocationController.GetActiveRoute)
 	routePlans.Get("/active/all", routePlanController.GetAllActiveRoutePlans)
+	routePlans.Post("/optimize", middleware.RoleAuthorization("management", "planner"), routeOptimizationController.OptimizeRoutes)
+	routePlans.Get("/on-time-report", middleware.RoleAuthorization("management", "planner"), routeScheduleController.GetOnTimeReport)
 	routePlans.Get("/:id", routePlanController.GetRoutePlanByID)
 	routePlans.Put("/:id", routePlanController.UpdateRoutePlan)
 	// Route untuk tracking lokasi driver
 	routePlans.Post("/:id/location", driverLocationController.UpdateDriverLocation)
 	routePlans.Get("/:id/location/latest", driverLocationController.GetLatestDriverLocation)
 	routePlans.Get("/:id/location/history", driverLocationController.GetDriverLocationHistory)
+	routePlans.Get("/:id/visits", waypointVisitController.GetWaypointVisits)
 	routePlans.Delete("/:id/location/history", driverLocationController.DeleteLocationHistory)
 	routePlans.Put("/:id/status", routePlanController.UpdateRoutePlanStatus)
+	routePlans.Get("/:id/status-history", routePlanController.GetStatusHistory)
+	routePlans.Get("/:id/geometry-revisions", routePlanController.GetGeometryRevisions)
+	routePlans.Put("/:id/deviation-settings", middleware.RoleAuthorization("management", "planner"), routePlanController.UpdateDeviationSettings)
 	routePlans.Delete("/:id", routePlanController.DeleteRoutePlan)
 	routePlans.Get("/driver/:driverID", routePlanController.GetRoutePlansByDriverID)
 	routePlans.Get("/avoidance/permanent", routePlanController.GetPermanentAvoidanceAreas)
@@ -369,21 +526,37 @@ func main() {
 	mqttClient := mqtt.StartMQTTClient()
 	if mqttClient != nil {
 		log.Println("MQTT client started successfully")
-
-		// Setup graceful shutdown
-		go func() {
-			quit := make(chan os.Signal, 1)
-			signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-			<-quit
-			log.Println("Shutting down MQTT client...")
-			mqttClient.Disconnect()
-		}()
 	} else {
 		log.Println("Failed to start MQTT client")
 	}
 
+	// Setup graceful shutdown
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		<-quit
+		if mqttClient != nil {
+			log.Println("Shutting down MQTT client...")
+			mqttClient.Disconnect()
+		}
+		log.Println("Shutting down HTTP server...")
+		if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
+			log.Printf("Error shutting down HTTP server: %v", err)
+		}
+	}()
+
 	// Start server HTTP
-	log.Fatal(app.Listen(fmt.Sprintf(":%s", port)))
+	if err := app.Listen(fmt.Sprintf(":%s", port)); err != nil {
+		log.Fatal(err)
+	}
+
+	// Tunggu tugas background, misalnya perhitungan ulang rute, sebelum keluar
+	log.Println("Waiting for background tasks...")
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), service.BackgroundDrainTimeout)
+	defer cancelDrain()
+	if err := backgroundWorker.Shutdown(drainCtx); err != nil {
+		log.Printf("Background tasks did not finish before shutdown: %v", err)
+	}
 
 	// Start server HTTPS
 	// log.Fatal(app.ListenTLS(fmt.Sprintf(":%s", port), "./cert/cert.pem", "./cert/key.pem"))
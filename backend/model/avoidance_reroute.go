@@ -0,0 +1,30 @@
+// backend/model/avoidance_reroute.go
+package model
+
+import (
+	"time"
+)
+
+// AvoidanceAreaStatusResponse DTO untuk hasil perubahan status area hindari
+type AvoidanceAreaStatusResponse struct {
+	ID     uint   `json:"id"`
+	Status string `json:"status"`
+	// Rute aktif sedang diperiksa di background, hasilnya dikirim lewat event avoidance_area_reroute
+	ReroutePending bool `json:"reroute_pending"`
+}
+
+// AvoidanceRerouteResult adalah hasil perhitungan ulang rute aktif setelah area hindari disetujui
+type AvoidanceRerouteResult struct {
+	AvoidanceAreaID uint                      `json:"avoidance_area_id"`
+	Checked         int                       `json:"checked"`  // Route plan aktif yang diperiksa
+	Rerouted        []uint                    `json:"rerouted"` // Route plan yang geometrinya diganti
+	Failed          []AvoidanceRerouteFailure `json:"failed"`
+	Error           string                    `json:"error,omitempty"` // Pemeriksaan gagal sebelum route plan diproses
+	CompletedAt     time.Time                 `json:"completed_at"`
+}
+
+// AvoidanceRerouteFailure adalah route plan yang gagal dihitung ulang
+type AvoidanceRerouteFailure struct {
+	RoutePlanID uint   `json:"route_plan_id"`
+	Error       string `json:"error"`
+}
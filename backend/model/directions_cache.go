@@ -0,0 +1,15 @@
+// backend/model/directions_cache.go
+package model
+
+import (
+	"time"
+)
+
+// DirectionsCacheEntry menyimpan respons directions untuk request yang sudah dinormalisasi
+type DirectionsCacheEntry struct {
+	Key        string    `gorm:"primaryKey;size:64" json:"key"` // SHA-256 request yang dinormalisasi
+	Response   string    `gorm:"type:text" json:"-"`            // Respons dalam format ORS
+	ExpiresAt  time.Time `gorm:"index" json:"expires_at"`
+	LastUsedAt time.Time `gorm:"index" json:"last_used_at"` // Untuk membuang entri yang paling lama tidak dipakai
+	CreatedAt  time.Time `json:"created_at"`
+}
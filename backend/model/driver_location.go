@@ -50,4 +50,5 @@ type DriverLocationResponse struct {
 type ActiveRouteResponse struct {
 	RoutePlan *RoutePlanResponse    `json:"route_plan"`
 	Location  *DriverLocationResponse `json:"current_location,omitempty"`
+	Progress  *RouteProgressResponse  `json:"progress,omitempty"` // Progres truk di sepanjang rute
 }
\ No newline at end of file
@@ -0,0 +1,43 @@
+// backend/model/engine_hours.go
+package model
+
+import (
+	"time"
+)
+
+// EngineHoursDaily mengakumulasi lama mesin menyala per truk per hari
+type EngineHoursDaily struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	TruckID             uint      `json:"truck_id"`
+	MacID               string    `json:"mac_id" gorm:"uniqueIndex:idx_engine_hours_mac_date"`
+	Date                time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_engine_hours_mac_date"`
+	EngineOnSeconds     int       `json:"engine_on_seconds"`      // Total mesin menyala
+	IdleEngineOnSeconds int       `json:"idle_engine_on_seconds"` // Bagian dari total saat truk diam
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// EngineHoursDayResponse adalah jam mesin satu truk pada satu hari
+type EngineHoursDayResponse struct {
+	Date              string  `json:"date"`
+	EngineOnHours     float64 `json:"engine_on_hours"`
+	IdleEngineOnHours float64 `json:"idle_engine_on_hours"`
+}
+
+// EngineHoursTruckReport merangkum jam mesin satu truk dalam rentang tanggal
+type EngineHoursTruckReport struct {
+	TruckID           uint                     `json:"truck_id"`
+	MacID             string                   `json:"mac_id"`
+	PlateNumber       string                   `json:"plate_number,omitempty"`
+	EngineOnHours     float64                  `json:"engine_on_hours"`
+	IdleEngineOnHours float64                  `json:"idle_engine_on_hours"`
+	IdlePercentage    float64                  `json:"idle_percentage"` // Persentase jam mesin yang terpakai untuk idle
+	Days              []EngineHoursDayResponse `json:"days"`
+}
+
+// EngineHoursReportResponse adalah laporan jam mesin armada
+type EngineHoursReportResponse struct {
+	StartDate string                    `json:"start_date"`
+	EndDate   string                    `json:"end_date"`
+	Trucks    []*EngineHoursTruckReport `json:"trucks"`
+}
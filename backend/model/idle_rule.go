@@ -0,0 +1,87 @@
+// backend/model/idle_rule.go
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IdleRule mendefinisikan kapan truk dianggap idle
+type IdleRule struct {
+	ID                   uint           `gorm:"primaryKey" json:"id"`
+	Name                 string         `json:"name"`
+	Radius               float64        `json:"radius"`               // Radius dalam meter
+	MinDurationMinutes   int            `json:"min_duration_minutes"` // Lama diam minimal sebelum dianggap idle
+	TruckTypes           string         `json:"truck_types"`          // Daftar tipe truk dipisah koma, kosong = semua tipe
+	ScheduleStart        string         `json:"schedule_start"`       // Format HH:MM, kosong = sepanjang hari
+	ScheduleEnd          string         `json:"schedule_end"`         // Format HH:MM, boleh melewati tengah malam
+	Priority             int            `json:"priority" gorm:"default:0"`
+	EngineOnAlertMinutes int            `json:"engine_on_alert_minutes"` // Alert saat idle dengan mesin menyala melewati batas ini, 0 = default
+	IsActive             bool           `json:"is_active"`
+	CreatedByID          uint           `json:"created_by_id"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// IdleRuleRequest DTO untuk membuat atau mengubah idle rule
+type IdleRuleRequest struct {
+	Name                 string   `json:"name"`
+	Radius               float64  `json:"radius"`
+	MinDurationMinutes   int      `json:"min_duration_minutes"`
+	TruckTypes           []string `json:"truck_types"`
+	ScheduleStart        string   `json:"schedule_start"`
+	ScheduleEnd          string   `json:"schedule_end"`
+	Priority             int      `json:"priority"`
+	EngineOnAlertMinutes int      `json:"engine_on_alert_minutes"`
+	IsActive             *bool    `json:"is_active"`
+}
+
+// IdleRuleResponse DTO untuk mengembalikan data idle rule
+type IdleRuleResponse struct {
+	ID                   uint      `json:"id"`
+	Name                 string    `json:"name"`
+	Radius               float64   `json:"radius"`
+	MinDurationMinutes   int       `json:"min_duration_minutes"`
+	TruckTypes           []string  `json:"truck_types"`
+	ScheduleStart        string    `json:"schedule_start,omitempty"`
+	ScheduleEnd          string    `json:"schedule_end,omitempty"`
+	Priority             int       `json:"priority"`
+	EngineOnAlertMinutes int       `json:"engine_on_alert_minutes,omitempty"`
+	IsActive             bool      `json:"is_active"`
+	CreatedByID          uint      `json:"created_by_id"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// TruckTypeList returns the truck types the rule applies to
+func (r *IdleRule) TruckTypeList() []string {
+	types := []string{}
+	for _, truckType := range strings.Split(r.TruckTypes, ",") {
+		if truckType = strings.TrimSpace(truckType); truckType != "" {
+			types = append(types, truckType)
+		}
+	}
+	return types
+}
+
+// ToIdleRuleResponse converts IdleRule model to IdleRuleResponse DTO
+func (r *IdleRule) ToIdleRuleResponse() IdleRuleResponse {
+	return IdleRuleResponse{
+		ID:                   r.ID,
+		Name:                 r.Name,
+		Radius:               r.Radius,
+		MinDurationMinutes:   r.MinDurationMinutes,
+		TruckTypes:           r.TruckTypeList(),
+		ScheduleStart:        r.ScheduleStart,
+		ScheduleEnd:          r.ScheduleEnd,
+		Priority:             r.Priority,
+		EngineOnAlertMinutes: r.EngineOnAlertMinutes,
+		IsActive:             r.IsActive,
+		CreatedByID:          r.CreatedByID,
+		CreatedAt:            r.CreatedAt,
+		UpdatedAt:            r.UpdatedAt,
+	}
+}
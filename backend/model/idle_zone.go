@@ -0,0 +1,113 @@
+// backend/model/idle_zone.go
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kebijakan pengecualian idle di dalam zona
+const (
+	IdleZonePolicySuppress = "suppress" // Idle tetap dicatat tanpa alert
+	IdleZonePolicyExtended = "extended" // Alert baru muncul setelah ExtendedMinutes
+)
+
+// IdleZone adalah area (depot, lokasi customer, rest area) tempat idle dikecualikan
+type IdleZone struct {
+	ID              uint            `gorm:"primaryKey" json:"id"`
+	Name            string          `json:"name"`
+	Category        string          `json:"category"` // depot, customer_site, rest_area, other
+	Policy          string          `json:"policy" gorm:"default:'suppress'"`
+	ExtendedMinutes int             `json:"extended_minutes"` // Ambang idle untuk policy extended
+	IsActive        bool            `json:"is_active"`
+	CreatedByID     uint            `json:"created_by_id"`
+	Points          []IdleZonePoint `json:"points" gorm:"foreignKey:IdleZoneID"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt  `json:"-" gorm:"index"`
+}
+
+// IdleZonePoint adalah titik sudut polygon idle zone
+type IdleZonePoint struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	IdleZoneID uint           `json:"idle_zone_id"`
+	Latitude   float64        `json:"latitude"`
+	Longitude  float64        `json:"longitude"`
+	Order      int            `json:"order"` // Sequence order of the point
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// IdleZoneRequest DTO untuk membuat atau mengubah idle zone
+type IdleZoneRequest struct {
+	Name            string                 `json:"name"`
+	Category        string                 `json:"category"`
+	Policy          string                 `json:"policy"`
+	ExtendedMinutes int                    `json:"extended_minutes"`
+	IsActive        *bool                  `json:"is_active"`
+	Points          []IdleZonePointRequest `json:"points"`
+}
+
+// IdleZonePointRequest represents a point in an idle zone request
+type IdleZonePointRequest struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// IdleZoneResponse DTO untuk mengembalikan data idle zone
+type IdleZoneResponse struct {
+	ID              uint                   `json:"id"`
+	Name            string                 `json:"name"`
+	Category        string                 `json:"category"`
+	Policy          string                 `json:"policy"`
+	ExtendedMinutes int                    `json:"extended_minutes,omitempty"`
+	IsActive        bool                   `json:"is_active"`
+	CreatedByID     uint                   `json:"created_by_id"`
+	Points          []IdleZonePointRequest `json:"points"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+}
+
+// IdleZoneSummary merangkum idle di dalam satu zona
+type IdleZoneSummary struct {
+	ZoneID        uint   `json:"zone_id"`
+	ZoneName      string `json:"zone_name"`
+	Count         int    `json:"count"`
+	TotalDuration int    `json:"total_duration"` // Detik
+}
+
+// IdleSummaryResponse memisahkan idle di zona (menunggu produktif) dari idle tanpa keterangan
+type IdleSummaryResponse struct {
+	StartDate           time.Time         `json:"start_date"`
+	EndDate             time.Time         `json:"end_date"`
+	TotalCount          int               `json:"total_count"`
+	TotalDuration       int               `json:"total_duration"`
+	InZoneCount         int               `json:"in_zone_count"`
+	InZoneDuration      int               `json:"in_zone_duration"`
+	OutsideZoneCount    int               `json:"outside_zone_count"`
+	OutsideZoneDuration int               `json:"outside_zone_duration"`
+	Zones               []IdleZoneSummary `json:"zones"`
+}
+
+// ToIdleZoneResponse converts IdleZone model to IdleZoneResponse DTO
+func (z *IdleZone) ToIdleZoneResponse() IdleZoneResponse {
+	points := make([]IdleZonePointRequest, len(z.Points))
+	for i, point := range z.Points {
+		points[i] = IdleZonePointRequest{Latitude: point.Latitude, Longitude: point.Longitude}
+	}
+
+	return IdleZoneResponse{
+		ID:              z.ID,
+		Name:            z.Name,
+		Category:        z.Category,
+		Policy:          z.Policy,
+		ExtendedMinutes: z.ExtendedMinutes,
+		IsActive:        z.IsActive,
+		CreatedByID:     z.CreatedByID,
+		Points:          points,
+		CreatedAt:       z.CreatedAt,
+		UpdatedAt:       z.UpdatedAt,
+	}
+}
@@ -0,0 +1,69 @@
+// backend/model/on_time_report.go
+package model
+
+import (
+	"time"
+)
+
+// Hasil ketepatan waktu kedatangan di waypoint
+const (
+	PunctualityOnTime  = "on_time"
+	PunctualityEarly   = "early"
+	PunctualityLate    = "late"
+	PunctualityMissed  = "missed"  // Rute selesai tanpa kunjungan ke waypoint
+	PunctualityPending = "pending" // Rute masih berjalan dan waypoint belum dikunjungi
+)
+
+// WaypointPunctuality membandingkan kedatangan di waypoint dengan jendela waktunya
+type WaypointPunctuality struct {
+	WaypointID      uint       `json:"waypoint_id"`
+	Order           int        `json:"order"`
+	Address         string     `json:"address,omitempty"`
+	EarliestArrival *time.Time `json:"earliest_arrival,omitempty"`
+	LatestArrival   *time.Time `json:"latest_arrival,omitempty"`
+	ArrivalTime     *time.Time `json:"arrival_time,omitempty"`
+	Result          string     `json:"result"`
+	DelayMinutes    float64    `json:"delay_minutes"` // Menit setelah latest_arrival, 0 jika tidak terlambat
+}
+
+// PunctualityCounts menghitung hasil ketepatan waktu
+type PunctualityCounts struct {
+	OnTime     int     `json:"on_time"`
+	Early      int     `json:"early"`
+	Late       int     `json:"late"`
+	Missed     int     `json:"missed"`
+	OnTimeRate float64 `json:"on_time_rate"` // Persentase on_time dan early dari waypoint yang sudah dinilai
+}
+
+// RouteOnTimeReport merangkum ketepatan waktu satu route plan
+type RouteOnTimeReport struct {
+	RoutePlanID       uint                  `json:"route_plan_id"`
+	DriverID          uint                  `json:"driver_id"`
+	DriverName        string                `json:"driver_name,omitempty"`
+	Status            string                `json:"status"`
+	PlannedStartAt    *time.Time            `json:"planned_start_at,omitempty"`
+	StartedAt         *time.Time            `json:"started_at,omitempty"`
+	StartDelayMinutes *float64              `json:"start_delay_minutes,omitempty"` // Negatif jika berangkat lebih awal
+	PlannedEndAt      *time.Time            `json:"planned_end_at,omitempty"`
+	CompletedAt       *time.Time            `json:"completed_at,omitempty"`
+	EndDelayMinutes   *float64              `json:"end_delay_minutes,omitempty"`
+	Counts            PunctualityCounts     `json:"counts"`
+	Waypoints         []WaypointPunctuality `json:"waypoints"`
+}
+
+// DriverOnTimeReport merangkum ketepatan waktu seorang driver
+type DriverOnTimeReport struct {
+	DriverID        uint              `json:"driver_id"`
+	DriverName      string            `json:"driver_name,omitempty"`
+	Routes          int               `json:"routes"`
+	Counts          PunctualityCounts `json:"counts"`
+	AvgDelayMinutes float64           `json:"avg_delay_minutes"` // Rata-rata keterlambatan dari kedatangan yang terlambat
+}
+
+// OnTimeReportResponse adalah laporan ketepatan waktu per driver dan per rute
+type OnTimeReportResponse struct {
+	StartDate string                `json:"start_date"`
+	EndDate   string                `json:"end_date"`
+	Drivers   []*DriverOnTimeReport `json:"drivers"`
+	Routes    []*RouteOnTimeReport  `json:"routes"`
+}
@@ -1,11 +1,13 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 	"gorm.io/gorm"
 )
 
-// TruckRouteDeviation represents a deviation from the planned route
+// TruckRouteDeviation represents one deviation episode: the truck leaving the planned
+// route until it rejoins it. Position fields describe the first off-route sample.
 type TruckRouteDeviation struct {
 	ID              uint           `gorm:"primaryKey" json:"id"`
 	TruckID         uint           `json:"truck_id"`
@@ -19,10 +21,25 @@ type TruckRouteDeviation struct {
 	Distance        float64        `json:"distance"`          // Distance in meters from route
 	SegmentIndex    int            `json:"segment_index"`     // Index of the route segment where deviation occurred
 	Timestamp       time.Time      `json:"timestamp"`         // When the deviation was detected
+	EndTime         time.Time      `json:"end_time"`          // Last off-route sample, or rejoin time once closed
+	IsOpen          bool           `json:"is_open" gorm:"index"` // Truck is still off-route
+	MaxDistance     float64        `json:"max_distance"`      // Furthest distance from route during the episode
+	SampleCount     int            `json:"sample_count"`      // Number of off-route samples in the episode
+	PathData        string         `json:"-" gorm:"type:text"` // JSON array of off-route samples
+	EscalationLevel int            `json:"escalation_level"`  // Number of escalation notifications sent
 	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// DeviationPathPoint is one off-route sample of a deviation episode
+type DeviationPathPoint struct {
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Distance  float64   `json:"distance"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
 // RouteDeviationResponse is the DTO for returning route deviation data
 type RouteDeviationResponse struct {
 	ID              uint      `json:"id"`
@@ -37,6 +54,13 @@ type RouteDeviationResponse struct {
 	Distance        float64   `json:"distance"`
 	SegmentIndex    int       `json:"segment_index"`
 	Timestamp       time.Time `json:"timestamp"`
+	EndTime         time.Time `json:"end_time"`
+	Duration        int       `json:"duration"` // Duration of the episode in seconds
+	IsOpen          bool      `json:"is_open"`
+	MaxDistance     float64   `json:"max_distance"`
+	SampleCount     int       `json:"sample_count"`
+	EscalationLevel int       `json:"escalation_level"`
+	Path            []DeviationPathPoint `json:"path"`
 }
 
 // ToRouteDeviationResponse converts TruckRouteDeviation model to RouteDeviationResponse DTO
@@ -54,5 +78,34 @@ func (d *TruckRouteDeviation) ToRouteDeviationResponse() RouteDeviationResponse
 		Distance:     d.Distance,
 		SegmentIndex: d.SegmentIndex,
 		Timestamp:    d.Timestamp,
+		EndTime:      d.EndTime,
+		Duration:     int(d.EndTime.Sub(d.Timestamp).Seconds()),
+		IsOpen:       d.IsOpen,
+		MaxDistance:  d.MaxDistance,
+		SampleCount:  d.SampleCount,
+		EscalationLevel: d.EscalationLevel,
+		Path:         d.GetPath(),
+	}
+}
+
+// GetPath decodes the off-route samples of the episode
+func (d *TruckRouteDeviation) GetPath() []DeviationPathPoint {
+	path := []DeviationPathPoint{}
+	if d.PathData == "" {
+		return path
+	}
+	if err := json.Unmarshal([]byte(d.PathData), &path); err != nil {
+		return []DeviationPathPoint{}
+	}
+	return path
+}
+
+// SetPath encodes the off-route samples of the episode
+func (d *TruckRouteDeviation) SetPath(path []DeviationPathPoint) error {
+	data, err := json.Marshal(path)
+	if err != nil {
+		return err
 	}
+	d.PathData = string(data)
+	return nil
 }
@@ -0,0 +1,14 @@
+// backend/model/route_deviation_settings.go
+package model
+
+// RouteDeviationSettings override the global deviation detector settings for one route plan.
+// Nil fields fall back to the global value.
+type RouteDeviationSettings struct {
+	EnterMeters        *float64 `json:"enter_meters,omitempty"`         // Jarak dari rute untuk mulai dianggap menyimpang
+	ExitMeters         *float64 `json:"exit_meters,omitempty"`          // Jarak dari rute untuk dianggap kembali ke rute
+	HighwayEnterMeters *float64 `json:"highway_enter_meters,omitempty"` // Ambang masuk pada jalan tol/highway
+	HighwayExitMeters  *float64 `json:"highway_exit_meters,omitempty"`  // Ambang keluar pada jalan tol/highway
+	MinPoints          *int     `json:"min_points,omitempty"`           // Jumlah titik berturut-turut sebelum penyimpangan dikonfirmasi
+	MinDurationSeconds *int     `json:"min_duration_seconds,omitempty"` // Atau lama minimal di luar rute sebelum dikonfirmasi
+	AccuracyFactor     *float64 `json:"accuracy_factor,omitempty"`      // Pengali akurasi GPS yang ditambahkan ke ambang masuk
+}
@@ -0,0 +1,62 @@
+// backend/model/route_geometry_revision.go
+package model
+
+import (
+	"time"
+)
+
+// Sumber revisi geometri route plan
+const (
+	GeometryRevisionSourceInitial   = "initial"   // Geometri saat route plan dibuat
+	GeometryRevisionSourceManual    = "manual"    // Diubah lewat PUT /route-plans/:id
+	GeometryRevisionSourceAvoidance = "avoidance" // Dihitung ulang karena area hindari disetujui
+)
+
+// RouteGeometryRevision menyimpan setiap geometri yang pernah dipakai sebuah route plan
+type RouteGeometryRevision struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	RoutePlanID     uint      `json:"route_plan_id" gorm:"index"`
+	Revision        int       `json:"revision"` // Mulai dari 1 per route plan, unik per route plan
+	RouteGeometry   string    `json:"route_geometry" gorm:"type:text"`
+	ExtrasData      string    `json:"-" gorm:"type:text"`
+	DistanceMeters  *float64  `json:"distance_meters,omitempty"`
+	DurationSeconds *float64  `json:"duration_seconds,omitempty"`
+	Source          string    `json:"source"`                      // initial, manual, avoidance
+	AvoidanceAreaID *uint     `json:"avoidance_area_id,omitempty"` // Area yang menyebabkan perhitungan ulang
+	StartLatitude   *float64  `json:"start_latitude,omitempty"`    // Posisi truk saat rute dihitung ulang
+	StartLongitude  *float64  `json:"start_longitude,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// RouteGeometryRevisionResponse DTO untuk mengembalikan revisi geometri route plan
+type RouteGeometryRevisionResponse struct {
+	ID              uint      `json:"id"`
+	RoutePlanID     uint      `json:"route_plan_id"`
+	Revision        int       `json:"revision"`
+	RouteGeometry   string    `json:"route_geometry"`
+	DistanceMeters  *float64  `json:"distance_meters,omitempty"`
+	DurationSeconds *float64  `json:"duration_seconds,omitempty"`
+	Source          string    `json:"source"`
+	AvoidanceAreaID *uint     `json:"avoidance_area_id,omitempty"`
+	StartLatitude   *float64  `json:"start_latitude,omitempty"`
+	StartLongitude  *float64  `json:"start_longitude,omitempty"`
+	Current         bool      `json:"current"` // Geometri yang sedang dipakai route plan
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ToRouteGeometryRevisionResponse converts RouteGeometryRevision model to RouteGeometryRevisionResponse DTO
+func (r *RouteGeometryRevision) ToRouteGeometryRevisionResponse() RouteGeometryRevisionResponse {
+	return RouteGeometryRevisionResponse{
+		ID:              r.ID,
+		RoutePlanID:     r.RoutePlanID,
+		Revision:        r.Revision,
+		RouteGeometry:   r.RouteGeometry,
+		DistanceMeters:  r.DistanceMeters,
+		DurationSeconds: r.DurationSeconds,
+		Source:          r.Source,
+		AvoidanceAreaID: r.AvoidanceAreaID,
+		StartLatitude:   r.StartLatitude,
+		StartLongitude:  r.StartLongitude,
+		CreatedAt:       r.CreatedAt,
+	}
+}
@@ -0,0 +1,91 @@
+// backend/model/route_optimization.go
+package model
+
+import (
+	"time"
+)
+
+// Solver untuk optimasi rute multi-stop
+const (
+	OptimizationSolverAuto      = "auto"      // ORS jika API key tersedia, heuristik jika gagal
+	OptimizationSolverORS       = "ors"       // OpenRouteService / VROOM optimization API
+	OptimizationSolverHeuristic = "heuristic" // Solver bawaan, tanpa layanan eksternal
+)
+
+// OptimizationLocation is a point used by the route optimization
+type OptimizationLocation struct {
+	Latitude  float64 `json:"latitude" validate:"required"`
+	Longitude float64 `json:"longitude" validate:"required"`
+	Address   string  `json:"address,omitempty"`
+}
+
+// OptimizationStop is a delivery stop that has to be assigned to one of the trucks
+type OptimizationStop struct {
+	ID              string     `json:"id,omitempty"` // Referensi dari planner, default urutan stop
+	Latitude        float64    `json:"latitude" validate:"required"`
+	Longitude       float64    `json:"longitude" validate:"required"`
+	Address         string     `json:"address,omitempty"`
+	Demand          int        `json:"demand"`          // Muatan yang diantar ke stop ini
+	ServiceMinutes  float64    `json:"service_minutes"` // Lama bongkar muat di stop
+	EarliestArrival *time.Time `json:"earliest_arrival,omitempty"`
+	LatestArrival   *time.Time `json:"latest_arrival,omitempty"`
+}
+
+// OptimizationVehicle is a truck available for the route optimization
+type OptimizationVehicle struct {
+	VehiclePlate string `json:"vehicle_plate" validate:"required"` // Format "PLAT / MAC ID" seperti saat membuat route plan
+	DriverName   string `json:"driver_name" validate:"required"`
+	Capacity     int    `json:"capacity"` // 0 = tanpa batas muatan
+}
+
+// RouteOptimizationRequest is the DTO for optimizing multi-stop routes over several trucks
+type RouteOptimizationRequest struct {
+	Depot         OptimizationLocation  `json:"depot" validate:"required"`
+	Stops         []OptimizationStop    `json:"stops" validate:"required,min=1"`
+	Vehicles      []OptimizationVehicle `json:"vehicles" validate:"required,min=1"`
+	StartAt       *time.Time            `json:"start_at,omitempty"` // Waktu berangkat dari depot, default sekarang
+	ReturnToDepot bool                  `json:"return_to_depot"`    // Rute diakhiri kembali ke depot
+	Solver        string                `json:"solver,omitempty"`   // auto, ors, heuristic
+	CreatePlans   bool                  `json:"create_plans"`       // Langsung buat route plan dari hasil optimasi
+}
+
+// OptimizedStop is a stop in an optimized route
+type OptimizedStop struct {
+	StopID           string     `json:"stop_id"`
+	Latitude         float64    `json:"latitude"`
+	Longitude        float64    `json:"longitude"`
+	Address          string     `json:"address,omitempty"`
+	Demand           int        `json:"demand"`
+	EstimatedArrival time.Time  `json:"estimated_arrival"`
+	EarliestArrival  *time.Time `json:"earliest_arrival,omitempty"`
+	LatestArrival    *time.Time `json:"latest_arrival,omitempty"`
+}
+
+// OptimizedRoute is the optimized stop sequence of one truck
+type OptimizedRoute struct {
+	VehiclePlate     string                  `json:"vehicle_plate"`
+	DriverName       string                  `json:"driver_name"`
+	Load             int                     `json:"load"`
+	Capacity         int                     `json:"capacity"`
+	DistanceMeters   float64                 `json:"distance_meters"`
+	DurationSeconds  float64                 `json:"duration_seconds"`
+	Stops            []OptimizedStop         `json:"stops"`
+	RoutePlanRequest *RoutePlanCreateRequest `json:"route_plan_request"`     // Siap dikirim ke POST /route-plans
+	RoutePlan        *RoutePlanResponse      `json:"route_plan,omitempty"`   // Diisi jika create_plans
+	CreateError      string                  `json:"create_error,omitempty"` // Alasan route plan gagal dibuat
+}
+
+// UnassignedStop is a stop that no truck could serve
+type UnassignedStop struct {
+	StopID string `json:"stop_id"`
+	Reason string `json:"reason"`
+}
+
+// RouteOptimizationResponse is the DTO for returning optimized routes
+type RouteOptimizationResponse struct {
+	Solver               string           `json:"solver"` // Solver yang benar-benar dipakai
+	Routes               []OptimizedRoute `json:"routes"`
+	Unassigned           []UnassignedStop `json:"unassigned"`
+	TotalDistanceMeters  float64          `json:"total_distance_meters"`
+	TotalDurationSeconds float64          `json:"total_duration_seconds"`
+}
@@ -15,6 +15,14 @@ type RoutePlan struct {
 	RouteGeometry string         `json:"route_geometry" gorm:"type:text"`
 	ExtrasData    string         `json:"extras_data,omitempty" gorm:"type:text"` // JSON string untuk menyimpan extras data
 	Status        string         `json:"status" gorm:"default:'planned'"` // planned, active, completed, cancelled
+	DeviationSettingsData string `json:"-" gorm:"type:text"` // JSON RouteDeviationSettings, kosong = pengaturan global
+	PlannedStartAt *time.Time    `json:"planned_start_at,omitempty"` // Jadwal keberangkatan
+	PlannedEndAt  *time.Time     `json:"planned_end_at,omitempty"`   // Jadwal tiba di tujuan akhir
+	DistanceMeters  *float64     `json:"distance_meters,omitempty"`  // Panjang rute
+	DurationSeconds *float64     `json:"duration_seconds,omitempty"` // Perkiraan lama perjalanan dari routing service
+	StartedAt     *time.Time     `json:"started_at,omitempty"`   // Waktu rute menjadi active
+	CompletedAt   *time.Time     `json:"completed_at,omitempty"` // Waktu rute selesai
+	NotStartedFlaggedAt *time.Time `json:"not_started_flagged_at,omitempty"` // Ditandai karena tidak pernah dimulai
 	CreatedAt     time.Time      `json:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
@@ -49,6 +57,9 @@ type RouteWaypoint struct {
 	Longitude  float64        `json:"longitude"`
 	Address    string         `json:"address" gorm:"type:text"`
 	Order      int            `json:"order"` // Sequence order of the waypoint
+	EarliestArrival *time.Time `json:"earliest_arrival,omitempty"` // Awal jendela waktu kedatangan
+	LatestArrival   *time.Time `json:"latest_arrival,omitempty"`   // Akhir jendela waktu kedatangan
+	LateAlertSentAt *time.Time `json:"-"`                          // Peringatan terlambat sudah dikirim
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
@@ -89,6 +100,10 @@ type RoutePlanCreateRequest struct {
 	ExtrasData        string                      `json:"extras_data,omitempty"` // JSON string data untuk extras
 	Waypoints         []WaypointRequest           `json:"waypoints" validate:"required,min=2"`
 	AvoidanceAreas    []AvoidanceAreaRequest      `json:"avoidance_areas,omitempty"`
+	DeviationSettings *RouteDeviationSettings     `json:"deviation_settings,omitempty"`
+	PlannedStartAt    *time.Time                  `json:"planned_start_at,omitempty"`
+	PlannedEndAt      *time.Time                  `json:"planned_end_at,omitempty"`
+	DurationSeconds   *float64                    `json:"duration_seconds,omitempty"` // Summary duration dari respons directions, jika route_geometry dikirim
 }
 
 // WaypointRequest represents a waypoint in a route plan creation request
@@ -96,6 +111,8 @@ type WaypointRequest struct {
 	Latitude   float64 `json:"latitude" validate:"required"`
 	Longitude  float64 `json:"longitude" validate:"required"`
 	Address    string  `json:"address,omitempty"`
+	EarliestArrival *time.Time `json:"earliest_arrival,omitempty"`
+	LatestArrival   *time.Time `json:"latest_arrival,omitempty"`
 }
 
 // AvoidanceAreaRequest represents an avoidance area in a route plan creation request
@@ -126,10 +143,30 @@ type RoutePlanResponse struct {
 	Status          string                `json:"status"`
 	Waypoints       []WaypointResponse    `json:"waypoints"`
 	AvoidanceAreas  []AvoidanceAreaResponse `json:"avoidance_areas,omitempty"`
+	DeviationSettings *RouteDeviationSettings `json:"deviation_settings,omitempty"`
+	PlannedStartAt  *time.Time            `json:"planned_start_at,omitempty"`
+	PlannedEndAt    *time.Time            `json:"planned_end_at,omitempty"`
+	DistanceMeters  *float64              `json:"distance_meters,omitempty"`
+	DurationSeconds *float64              `json:"duration_seconds,omitempty"`
+	StartedAt       *time.Time            `json:"started_at,omitempty"`
+	CompletedAt     *time.Time            `json:"completed_at,omitempty"`
+	NotStartedFlaggedAt *time.Time        `json:"not_started_flagged_at,omitempty"`
+	ScheduleConflicts []ScheduleConflict  `json:"schedule_conflicts,omitempty"` // Hanya diisi saat route plan dibuat
+	Validation      *RouteValidationReport `json:"validation,omitempty"`          // Hanya diisi saat route plan dibuat
+	Progress        *RouteProgressResponse `json:"progress,omitempty"`            // Hanya diisi untuk daftar route plan aktif
 	CreatedAt       time.Time             `json:"created_at"`
 	UpdatedAt       time.Time             `json:"updated_at"`
 }
 
+// ScheduleConflict is another route plan of the same truck or driver whose planned window overlaps
+type ScheduleConflict struct {
+	RoutePlanID    uint       `json:"route_plan_id"`
+	Status         string     `json:"status"`
+	ConflictOn     string     `json:"conflict_on"` // truck, driver
+	PlannedStartAt *time.Time `json:"planned_start_at"`
+	PlannedEndAt   *time.Time `json:"planned_end_at"`
+}
+
 // WaypointResponse represents a waypoint in a route plan response
 type WaypointResponse struct {
 	ID        uint    `json:"id"`
@@ -137,6 +174,9 @@ type WaypointResponse struct {
 	Longitude float64 `json:"longitude"`
 	Address   string  `json:"address,omitempty"`
 	Order     int     `json:"order"`
+	EarliestArrival *time.Time `json:"earliest_arrival,omitempty"`
+	LatestArrival   *time.Time `json:"latest_arrival,omitempty"`
+	Visits    []WaypointVisitResponse `json:"visits,omitempty"` // Kunjungan truk ke waypoint ini
 }
 
 // AvoidanceAreaResponse represents an avoidance area in a route plan response
@@ -193,4 +233,34 @@ func (r *RoutePlan) GetExtras() (*RouteExtras, error) {
 	}
 	
 	return &extras, nil
-}
\ No newline at end of file
+}
+
+// SetDeviationSettings stores the deviation detector overrides of the route plan
+func (r *RoutePlan) SetDeviationSettings(settings *RouteDeviationSettings) error {
+	if settings == nil {
+		r.DeviationSettingsData = ""
+		return nil
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	r.DeviationSettingsData = string(data)
+	return nil
+}
+
+// GetDeviationSettings returns the deviation detector overrides of the route plan, or nil
+func (r *RoutePlan) GetDeviationSettings() (*RouteDeviationSettings, error) {
+	if r.DeviationSettingsData == "" {
+		return nil, nil
+	}
+
+	var settings RouteDeviationSettings
+	if err := json.Unmarshal([]byte(r.DeviationSettingsData), &settings); err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
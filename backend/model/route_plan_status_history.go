@@ -0,0 +1,66 @@
+// backend/model/route_plan_status_history.go
+package model
+
+import (
+	"time"
+)
+
+// Status route plan
+const (
+	RoutePlanStatusPlanned        = "planned"
+	RoutePlanStatusActive         = "active"
+	RoutePlanStatusCompleted      = "completed"
+	RoutePlanStatusCancelled      = "cancelled"
+	RoutePlanStatusOnConfirmation = "on confirmation"
+)
+
+// Sumber perubahan status route plan
+const (
+	StatusChangeSourceManual    = "manual"    // Lewat API oleh pengguna
+	StatusChangeSourceAutomatic = "automatic" // Oleh lifecycle engine berdasarkan telemetri
+)
+
+// RoutePlanStatusHistory mencatat setiap perubahan status sebuah route plan
+type RoutePlanStatusHistory struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	RoutePlanID uint      `json:"route_plan_id" gorm:"index"`
+	FromStatus  string    `json:"from_status"`
+	ToStatus    string    `json:"to_status"`
+	Source      string    `json:"source"`                  // manual, automatic
+	Reason      string    `json:"reason" gorm:"type:text"` // Alasan perubahan
+	ChangedByID *uint     `json:"changed_by_id,omitempty"` // nil untuk perubahan otomatis
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RoutePlanStatusHistoryResponse DTO untuk mengembalikan riwayat status route plan
+type RoutePlanStatusHistoryResponse struct {
+	ID            uint      `json:"id"`
+	RoutePlanID   uint      `json:"route_plan_id"`
+	FromStatus    string    `json:"from_status"`
+	ToStatus      string    `json:"to_status"`
+	Source        string    `json:"source"`
+	Reason        string    `json:"reason,omitempty"`
+	ChangedByID   *uint     `json:"changed_by_id,omitempty"`
+	ChangedByName string    `json:"changed_by_name,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// RoutePlanStatusUpdateRequest is the DTO for changing the status of a route plan
+type RoutePlanStatusUpdateRequest struct {
+	Status string `json:"status" validate:"required"`
+	Reason string `json:"reason,omitempty"` // Alasan perubahan, dicatat di riwayat status
+}
+
+// ToRoutePlanStatusHistoryResponse converts RoutePlanStatusHistory model to RoutePlanStatusHistoryResponse DTO
+func (h *RoutePlanStatusHistory) ToRoutePlanStatusHistoryResponse() RoutePlanStatusHistoryResponse {
+	return RoutePlanStatusHistoryResponse{
+		ID:          h.ID,
+		RoutePlanID: h.RoutePlanID,
+		FromStatus:  h.FromStatus,
+		ToStatus:    h.ToStatus,
+		Source:      h.Source,
+		Reason:      h.Reason,
+		ChangedByID: h.ChangedByID,
+		CreatedAt:   h.CreatedAt,
+	}
+}
@@ -0,0 +1,73 @@
+// backend/model/route_progress.go
+package model
+
+import (
+	"time"
+)
+
+// RouteProgress menyimpan progres terakhir truk di sepanjang rute aktif, satu baris per route plan
+type RouteProgress struct {
+	RoutePlanID          uint       `gorm:"primaryKey;autoIncrement:false" json:"route_plan_id"`
+	TruckID              uint       `json:"truck_id"`
+	MacID                string     `json:"mac_id"`
+	DistanceTravelled    float64    `json:"distance_travelled"` // Meter sepanjang rute, tidak pernah mundur
+	TotalDistance        float64    `json:"total_distance"`
+	SegmentIndex         int        `json:"segment_index"`
+	NextWaypointID       *uint      `json:"next_waypoint_id,omitempty"`
+	NextWaypointDistance float64    `json:"next_waypoint_distance"`
+	NextWaypointETA      *time.Time `json:"next_waypoint_eta,omitempty"`
+	DestinationETA       *time.Time `json:"destination_eta,omitempty"`
+	Speed                float64    `json:"speed"`     // Kecepatan rata-rata sepanjang rute dalam m/s
+	Timestamp            time.Time  `json:"timestamp"` // Waktu posisi terakhir
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+// RouteProgressResponse DTO untuk mengembalikan progres rute
+type RouteProgressResponse struct {
+	RoutePlanID          uint       `json:"route_plan_id"`
+	TruckID              uint       `json:"truck_id"`
+	MacID                string     `json:"mac_id"`
+	PercentComplete      float64    `json:"percent_complete"`
+	DistanceTravelled    float64    `json:"distance_travelled"`
+	DistanceRemaining    float64    `json:"distance_remaining"`
+	TotalDistance        float64    `json:"total_distance"`
+	NextWaypointID       *uint      `json:"next_waypoint_id,omitempty"`
+	NextWaypointDistance float64    `json:"next_waypoint_distance"`
+	NextWaypointETA      *time.Time `json:"next_waypoint_eta,omitempty"`
+	DestinationETA       *time.Time `json:"destination_eta,omitempty"`
+	Speed                float64    `json:"speed"`
+	Timestamp            time.Time  `json:"timestamp"`
+}
+
+// ToRouteProgressResponse converts RouteProgress model to RouteProgressResponse DTO
+func (p *RouteProgress) ToRouteProgressResponse() RouteProgressResponse {
+	remaining := p.TotalDistance - p.DistanceTravelled
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	percent := 0.0
+	if p.TotalDistance > 0 {
+		percent = p.DistanceTravelled / p.TotalDistance * 100
+		if percent > 100 {
+			percent = 100
+		}
+	}
+
+	return RouteProgressResponse{
+		RoutePlanID:          p.RoutePlanID,
+		TruckID:              p.TruckID,
+		MacID:                p.MacID,
+		PercentComplete:      percent,
+		DistanceTravelled:    p.DistanceTravelled,
+		DistanceRemaining:    remaining,
+		TotalDistance:        p.TotalDistance,
+		NextWaypointID:       p.NextWaypointID,
+		NextWaypointDistance: p.NextWaypointDistance,
+		NextWaypointETA:      p.NextWaypointETA,
+		DestinationETA:       p.DestinationETA,
+		Speed:                p.Speed,
+		Timestamp:            p.Timestamp,
+	}
+}
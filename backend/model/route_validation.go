@@ -0,0 +1,50 @@
+// backend/model/route_validation.go
+package model
+
+// Asal geometri rute sebuah route plan
+const (
+	RouteGeometrySourceClient = "client" // Dikirim frontend, divalidasi backend
+	RouteGeometrySourceServer = "server" // Dihitung backend lewat routing service
+)
+
+// Kode masalah validasi rute
+const (
+	RouteIssueInvalidGeometry      = "invalid_geometry"
+	RouteIssueWaypointOffRoute     = "waypoint_off_route"
+	RouteIssueWaypointOutOfOrder   = "waypoint_out_of_order"
+	RouteIssueAvoidanceAreaCrossed = "avoidance_area_crossed"
+)
+
+// RouteValidationReport describes how a route geometry matches the waypoints and avoidance areas of a route plan
+type RouteValidationReport struct {
+	Valid           bool                      `json:"valid"`
+	GeometrySource  string                    `json:"geometry_source"` // client, server
+	DistanceMeters  float64                   `json:"distance_meters"`
+	DurationSeconds *float64                  `json:"duration_seconds,omitempty"`
+	Waypoints       []WaypointValidation      `json:"waypoints"`
+	AvoidanceAreas  []AvoidanceAreaValidation `json:"avoidance_areas"`
+	Issues          []RouteValidationIssue    `json:"issues"`
+}
+
+// WaypointValidation tells whether the route passes a waypoint, in the planned order
+type WaypointValidation struct {
+	Index          int     `json:"index"`
+	DistanceMeters float64 `json:"distance_meters"` // Jarak waypoint ke rute
+	OnRoute        bool    `json:"on_route"`
+	InOrder        bool    `json:"in_order"`
+}
+
+// AvoidanceAreaValidation tells whether the route enters an approved avoidance area
+type AvoidanceAreaValidation struct {
+	Index        int    `json:"index"`
+	Reason       string `json:"reason,omitempty"`
+	Crossed      bool   `json:"crossed"`
+	PointsInside int    `json:"points_inside"` // Titik geometri rute di dalam area
+}
+
+// RouteValidationIssue is a single problem found in a route geometry
+type RouteValidationIssue struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Location string `json:"location,omitempty"` // Mis. waypoints[2] atau avoidance_areas[0]
+}
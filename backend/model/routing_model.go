@@ -9,4 +9,15 @@ type RoutingRequest struct {
 	InstructionsFormat string                    `json:"instructions_format,omitempty"`
 	Language           string                    `json:"language,omitempty"`
 	Options            map[string]interface{}    `json:"options,omitempty"`
+	RoutePlanID        *uint                     `json:"route_plan_id,omitempty"` // Area hindaran non-permanen dari route plan ini ikut diterapkan, tidak diteruskan ke ORS
+}
+
+// AppliedAvoidanceArea is an approved avoidance area that was merged into avoid_polygons of a directions request
+type AppliedAvoidanceArea struct {
+	ID             uint    `json:"id"`
+	RoutePlanID    uint    `json:"route_plan_id"`
+	Reason         string  `json:"reason"`
+	IsPermanent    bool    `json:"is_permanent"`
+	Affected       bool    `json:"affected"`        // Rute melintas dekat area, jadi area ikut membentuk rute
+	DistanceMeters *float64 `json:"distance_meters,omitempty"` // Jarak terdekat rute ke batas area, kosong jika lebih dari sekitar 1 km
 }
\ No newline at end of file
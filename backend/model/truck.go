@@ -16,6 +16,7 @@ type Truck struct {
 	Fuel         float64        `json:"fuel"`
 	LastPosition time.Time      `json:"last_position"`
 	LastFuel     time.Time      `json:"last_fuel"`
+	Ignition     *bool          `json:"ignition,omitempty"` // Status kunci kontak terakhir, nil jika tracker tidak mengirim
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
@@ -32,6 +33,7 @@ type TruckResponse struct {
 	Fuel         float64   `json:"fuel"`
 	LastPosition time.Time `json:"last_position"`
 	LastFuel     time.Time `json:"last_fuel"`
+	Ignition     *bool     `json:"ignition,omitempty"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
@@ -64,6 +66,7 @@ func (t *Truck) ToTruckResponse() TruckResponse {
 		Fuel:         t.Fuel,
 		LastPosition: t.LastPosition,
 		LastFuel:     t.LastFuel,
+		Ignition:     t.Ignition,
 		UpdatedAt:    t.UpdatedAt,
 	}
 }
\ No newline at end of file
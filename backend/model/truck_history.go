@@ -13,6 +13,7 @@ type TruckPositionHistory struct {
 	MacID     string         `json:"mac_id"`
 	Latitude  float64        `json:"latitude"`
 	Longitude float64        `json:"longitude"`
+	Ignition  *bool          `json:"ignition,omitempty"`
 	Timestamp time.Time      `json:"timestamp"`
 	CreatedAt time.Time      `json:"created_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
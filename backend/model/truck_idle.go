@@ -16,11 +16,59 @@ type TruckIdleDetection struct {
 	EndTime    time.Time      `json:"end_time"`
 	Duration   int            `json:"duration"` // Durasi dalam detik
 	IsResolved bool           `json:"is_resolved" gorm:"default:false"`
+	RuleID     *uint          `json:"rule_id,omitempty"` // Idle rule yang memicu deteksi, nil untuk rule default
+	RuleName   string         `json:"rule_name"`
+	ZoneID     *uint          `json:"zone_id,omitempty"` // Idle zone tempat truk berhenti, nil jika di luar zona
+	ZoneName   string         `json:"zone_name,omitempty"`
+	IsSuppressed bool         `json:"is_suppressed" gorm:"default:false"` // Alert tidak dikirim karena zona suppress
+	CloseReason string        `json:"close_reason,omitempty"` // moved, manual, atau offline
+	IdleType   string         `json:"idle_type" gorm:"default:'unknown'"` // engine_on_idle, parked, atau unknown
+	EngineOnSeconds int       `json:"engine_on_seconds"` // Lama mesin menyala selama idle
+	EngineAlertSent bool      `json:"engine_alert_sent" gorm:"default:false"`
+	DriverID   *uint          `json:"driver_id,omitempty" gorm:"index"` // Driver rute aktif saat idle terdeteksi
+	AckReasonCode  string     `json:"ack_reason_code,omitempty"` // traffic, loading, breakdown, rest, atau unauthorized_stop
+	AckComment     string     `json:"ack_comment,omitempty"`
+	AcknowledgedByID *uint    `json:"acknowledged_by_id,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	DriverExplanation string  `json:"driver_explanation,omitempty"`
+	DriverExplainedAt *time.Time `json:"driver_explained_at,omitempty"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+// Alasan idle detection ditutup
+const (
+	IdleCloseReasonMoved   = "moved"   // Truk bergerak keluar radius idle
+	IdleCloseReasonManual  = "manual"  // Diselesaikan lewat API
+	IdleCloseReasonOffline = "offline" // Truk berhenti mengirim posisi
+)
+
+// Kode alasan saat idle di-acknowledge
+const (
+	IdleReasonTraffic          = "traffic"
+	IdleReasonLoading          = "loading"
+	IdleReasonBreakdown        = "breakdown"
+	IdleReasonRest             = "rest"
+	IdleReasonUnauthorizedStop = "unauthorized_stop"
+)
+
+// IdleReasonCodes adalah daftar kode alasan yang valid
+var IdleReasonCodes = []string{
+	IdleReasonTraffic,
+	IdleReasonLoading,
+	IdleReasonBreakdown,
+	IdleReasonRest,
+	IdleReasonUnauthorizedStop,
+}
+
+// Klasifikasi idle berdasarkan status kunci kontak
+const (
+	IdleTypeEngineOn = "engine_on_idle" // Mesin menyala saat truk diam
+	IdleTypeParked   = "parked"         // Mesin mati
+	IdleTypeUnknown  = "unknown"        // Tracker tidak mengirim status kunci kontak
+)
+
 // TruckIdleResponse DTO untuk mengembalikan data idle
 type TruckIdleResponse struct {
 	ID         uint      `json:"id"`
@@ -33,9 +81,61 @@ type TruckIdleResponse struct {
 	EndTime    time.Time `json:"end_time"`
 	Duration   int       `json:"duration"`
 	IsResolved bool      `json:"is_resolved"`
+	RuleID     *uint     `json:"rule_id,omitempty"`
+	RuleName   string    `json:"rule_name"`
+	ZoneID     *uint     `json:"zone_id,omitempty"`
+	ZoneName   string    `json:"zone_name,omitempty"`
+	IsSuppressed bool    `json:"is_suppressed"`
+	CloseReason string   `json:"close_reason,omitempty"`
+	IdleType   string    `json:"idle_type"`
+	EngineOnSeconds int  `json:"engine_on_seconds"`
+	DriverID   *uint     `json:"driver_id,omitempty"`
+	DriverName string    `json:"driver_name,omitempty"`
+	AckReasonCode string `json:"ack_reason_code,omitempty"`
+	AckComment string    `json:"ack_comment,omitempty"`
+	AcknowledgedByID *uint `json:"acknowledged_by_id,omitempty"`
+	AcknowledgedByName string `json:"acknowledged_by_name,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	DriverExplanation string `json:"driver_explanation,omitempty"`
+	DriverExplainedAt *time.Time `json:"driver_explained_at,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+// IdleAcknowledgeRequest DTO untuk acknowledge idle oleh management
+type IdleAcknowledgeRequest struct {
+	ReasonCode string `json:"reason_code"`
+	Comment    string `json:"comment"`
+}
+
+// IdleExplanationRequest DTO untuk penjelasan idle dari driver
+type IdleExplanationRequest struct {
+	Explanation string `json:"explanation"`
+}
+
+// IdleReasonCount adalah jumlah dan durasi idle untuk satu kode alasan
+type IdleReasonCount struct {
+	ReasonCode    string `json:"reason_code"`
+	Count         int    `json:"count"`
+	TotalDuration int    `json:"total_duration"` // Dalam detik
+}
+
+// IdleDriverReasonStats merangkum alasan idle seorang driver
+type IdleDriverReasonStats struct {
+	DriverID            uint              `json:"driver_id"` // 0 untuk idle tanpa rute aktif
+	DriverName          string            `json:"driver_name"`
+	TotalCount          int               `json:"total_count"`
+	TotalDuration       int               `json:"total_duration"`
+	UnacknowledgedCount int               `json:"unacknowledged_count"`
+	Reasons             []IdleReasonCount `json:"reasons"`
+}
+
+// IdleReasonStatsResponse adalah statistik alasan idle per driver
+type IdleReasonStatsResponse struct {
+	StartDate time.Time                `json:"start_date"`
+	EndDate   time.Time                `json:"end_date"`
+	Drivers   []*IdleDriverReasonStats `json:"drivers"`
+}
+
 // WebsocketIdleNotification format notifikasi untuk dikirim via websocket
 type WebsocketIdleNotification struct {
 	Type        string    `json:"type"`
@@ -45,4 +145,7 @@ type WebsocketIdleNotification struct {
 	Longitude   float64   `json:"longitude"`
 	StartTime   time.Time `json:"start_time"`
 	Duration    int       `json:"duration"`
+	RuleName    string    `json:"rule_name,omitempty"`
+	ZoneName    string    `json:"zone_name,omitempty"`
+	IdleType    string    `json:"idle_type,omitempty"`
 }
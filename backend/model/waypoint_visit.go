@@ -0,0 +1,52 @@
+// backend/model/waypoint_visit.go
+package model
+
+import (
+	"time"
+)
+
+// WaypointVisit mencatat kedatangan dan keberangkatan truk di sebuah waypoint
+type WaypointVisit struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	RoutePlanID        uint       `json:"route_plan_id" gorm:"index"`
+	WaypointID         uint       `json:"waypoint_id" gorm:"index"`
+	TruckID            uint       `json:"truck_id"`
+	MacID              string     `json:"mac_id"`
+	ArrivalTime        time.Time  `json:"arrival_time"`
+	DepartureTime      *time.Time `json:"departure_time,omitempty"` // nil selama truk masih di waypoint
+	DwellSeconds       int        `json:"dwell_seconds"`
+	DistanceToWaypoint float64    `json:"distance_to_waypoint"` // Jarak terdekat ke waypoint dalam meter
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// WaypointVisitResponse DTO untuk mengembalikan data kunjungan waypoint
+type WaypointVisitResponse struct {
+	ID                 uint       `json:"id"`
+	RoutePlanID        uint       `json:"route_plan_id"`
+	WaypointID         uint       `json:"waypoint_id"`
+	WaypointOrder      int        `json:"waypoint_order"`
+	Address            string     `json:"address,omitempty"`
+	ArrivalTime        time.Time  `json:"arrival_time"`
+	DepartureTime      *time.Time `json:"departure_time,omitempty"`
+	DwellSeconds       int        `json:"dwell_seconds"`
+	DistanceToWaypoint float64    `json:"distance_to_waypoint"`
+}
+
+// ToWaypointVisitResponse converts WaypointVisit model to WaypointVisitResponse DTO
+func (v *WaypointVisit) ToWaypointVisitResponse(waypoint *RouteWaypoint) WaypointVisitResponse {
+	response := WaypointVisitResponse{
+		ID:                 v.ID,
+		RoutePlanID:        v.RoutePlanID,
+		WaypointID:         v.WaypointID,
+		ArrivalTime:        v.ArrivalTime,
+		DepartureTime:      v.DepartureTime,
+		DwellSeconds:       v.DwellSeconds,
+		DistanceToWaypoint: v.DistanceToWaypoint,
+	}
+	if waypoint != nil {
+		response.WaypointOrder = waypoint.Order
+		response.Address = waypoint.Address
+	}
+	return response
+}
@@ -3,6 +3,7 @@ package mqtt
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"strings"
@@ -23,10 +24,49 @@ const (
 
 // VehicleData menyimpan semua data kendaraan (posisi dan bahan bakar)
 type VehicleData struct {
-	T   string  `json:"T"`
-	Lat float64 `json:"Lat"`
-	Lon float64 `json:"Lon"`
-	F   float64 `json:"F"`
+	T    string    `json:"T"`
+	Lat  float64   `json:"Lat"`
+	Lon  float64   `json:"Lon"`
+	F    float64   `json:"F"`
+	Ign  *Ignition `json:"Ign,omitempty"`  // Status kunci kontak, opsional
+	Acc  *float64  `json:"Acc,omitempty"`  // Akurasi GPS dalam meter, opsional
+	HDOP *float64  `json:"HDOP,omitempty"` // Horizontal dilution of precision, dipakai jika Acc tidak ada
+}
+
+// Ignition menerima status kunci kontak sebagai boolean, angka 0/1, atau string "on"/"off"
+type Ignition bool
+
+// UnmarshalJSON accepts the ignition formats sent by the different trackers
+func (i *Ignition) UnmarshalJSON(data []byte) error {
+	switch strings.ToLower(strings.Trim(string(data), `"`)) {
+	case "true", "1", "on":
+		*i = true
+	case "false", "0", "off":
+		*i = false
+	default:
+		return fmt.Errorf("invalid ignition value: %s", data)
+	}
+	return nil
+}
+
+// Accuracy returns the GPS accuracy in meters, or 0 when the tracker did not report it
+func (v VehicleData) Accuracy() float64 {
+	if v.Acc != nil {
+		return *v.Acc
+	}
+	if v.HDOP != nil {
+		return service.AccuracyFromHDOP(*v.HDOP)
+	}
+	return 0
+}
+
+// Ignition returns the ignition state, or nil when the tracker did not send it
+func (v VehicleData) Ignition() *bool {
+	if v.Ign == nil {
+		return nil
+	}
+	ignition := bool(*v.Ign)
+	return &ignition
 }
 
 // Struct untuk data yang dikirim ke frontend
@@ -35,6 +75,7 @@ type RealtimePositionUpdate struct {
 	MacID     string  `json:"mac_id"`
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
+	Ignition  *bool   `json:"ignition,omitempty"`
 	Timestamp string  `json:"timestamp"`
 }
 
@@ -55,6 +96,9 @@ var (
 	truckHistoryRepo repository.TruckHistoryRepository
 	deviationService service.RouteDeviationService
 	idleService      service.TruckIdleService
+	visitService     service.WaypointVisitService
+	progressService  service.RouteProgressService
+	lifecycleService service.RouteLifecycleService
 )
 
 // SetTruckRepository sets the truck repository for MQTT handlers
@@ -72,6 +116,21 @@ func SetRouteDeviationService(svc service.RouteDeviationService) {
 	deviationService = svc
 }
 
+// SetWaypointVisitService sets the waypoint visit service for MQTT handlers
+func SetWaypointVisitService(svc service.WaypointVisitService) {
+	visitService = svc
+}
+
+// SetRouteProgressService sets the route progress service for MQTT handlers
+func SetRouteProgressService(svc service.RouteProgressService) {
+	progressService = svc
+}
+
+// SetRouteLifecycleService sets the route lifecycle service for MQTT handlers
+func SetRouteLifecycleService(svc service.RouteLifecycleService) {
+	lifecycleService = svc
+}
+
 // SetTruckIdleService sets the truck idle detection service for MQTT handlers
 func SetTruckIdleService(svc service.TruckIdleService) {
 	idleService = svc
@@ -133,6 +192,8 @@ func (mc *MQTTClient) Subscribe() {
 			return
 		}
 
+		ignition := vehicleData.Ignition()
+
 		log.Printf("Received vehicle data for device %s: Timestamp=%s, Lat=%f, Lng=%f, Fuel=%f%%",
 			macID, vehicleData.T, vehicleData.Lat, vehicleData.Lon, vehicleData.F)
 
@@ -162,6 +223,7 @@ func (mc *MQTTClient) Subscribe() {
 					Fuel:         vehicleData.F,
 					LastPosition: dataTime,
 					LastFuel:     dataTime,
+					Ignition:     ignition,
 					CreatedAt:    time.Now(),
 					UpdatedAt:    time.Now(),
 				}
@@ -187,6 +249,9 @@ func (mc *MQTTClient) Subscribe() {
 				truck.Fuel = vehicleData.F
 				truck.LastPosition = dataTime
 				truck.LastFuel = dataTime
+				if ignition != nil {
+					truck.Ignition = ignition
+				}
 				truck.UpdatedAt = time.Now()
 
 				log.Printf("Timestamp for truck %s: %s", macID, dataTime)
@@ -205,6 +270,7 @@ func (mc *MQTTClient) Subscribe() {
 				MacID:     macID,
 				Latitude:  vehicleData.Lat,
 				Longitude: vehicleData.Lon,
+				Ignition:  ignition,
 				Timestamp: dataTime,
 				CreatedAt: time.Now(),
 			}
@@ -233,23 +299,47 @@ func (mc *MQTTClient) Subscribe() {
 			// Check and record route deviation if needed
 			if deviationService != nil {
 				log.Printf("Checking for route deviation for truck %s", macID)
-				if err := deviationService.DetectAndSaveDeviation(macID, vehicleData.Lat, vehicleData.Lon, dataTime); err != nil {
+				if err := deviationService.DetectAndSaveDeviation(macID, vehicleData.Lat, vehicleData.Lon, vehicleData.Accuracy(), dataTime); err != nil {
 					// Just log the error, don't interrupt the main flow
 					log.Printf("Error checking route deviation: %v", err)
 				}
 			}
 
+			// Record arrivals and departures at route waypoints
+			if visitService != nil {
+				if err := visitService.ProcessPosition(macID, vehicleData.Lat, vehicleData.Lon, dataTime); err != nil {
+					// Just log the error, don't interrupt the main flow
+					log.Printf("Error processing waypoint visit: %v", err)
+				}
+			}
+
+			// Update progress and ETA along the active route
+			if progressService != nil {
+				if err := progressService.ProcessPosition(macID, vehicleData.Lat, vehicleData.Lon, dataTime); err != nil {
+					// Just log the error, don't interrupt the main flow
+					log.Printf("Error updating route progress: %v", err)
+				}
+			}
+
+			// Start or complete route plans from the visits and progress above
+			if lifecycleService != nil {
+				if err := lifecycleService.ProcessPosition(macID, vehicleData.Lat, vehicleData.Lon, dataTime); err != nil {
+					// Just log the error, don't interrupt the main flow
+					log.Printf("Error updating route lifecycle: %v", err)
+				}
+			}
+
 			// Process position for idle detection
 			if idleService != nil {
 				log.Printf("Processing position for idle detection for truck %s", macID)
-				if err := idleService.ProcessPosition(macID, vehicleData.Lat, vehicleData.Lon, dataTime); err != nil {
+				if err := idleService.ProcessPosition(macID, vehicleData.Lat, vehicleData.Lon, ignition, dataTime); err != nil {
 					// Just log the error, don't interrupt the main flow
 					log.Printf("Error processing idle detection: %v", err)
 				}
 			}
 		}
 
-		// Kirim update ke semua client WebSocket
+		// Kirim update ke client WebSocket yang berlangganan
 		wsHub := websocket.GetHub()
 		if wsHub != nil {
 			// Buat pesan realtime untuk posisi
@@ -258,6 +348,7 @@ func (mc *MQTTClient) Subscribe() {
 				MacID:     macID,
 				Latitude:  vehicleData.Lat,
 				Longitude: vehicleData.Lon,
+				Ignition:  ignition,
 				Timestamp: dataTime.Format("2006-01-02 15:04:05"),
 			}
 
@@ -269,17 +360,23 @@ func (mc *MQTTClient) Subscribe() {
 				Timestamp: dataTime.Format("2006-01-02 15:04:05"),
 			}
 
+			// State terbaru truk untuk snapshot client compact
+			truckState := &websocket.TruckState{
+				MacID:     macID,
+				Latitude:  vehicleData.Lat,
+				Longitude: vehicleData.Lon,
+				Fuel:      vehicleData.F,
+				Timestamp: dataTime.UnixMilli(),
+			}
+
 			// Marshal position update ke JSON
 			positionJsonData, err := json.Marshal(positionUpdate)
 			if err != nil {
 				log.Printf("Error marshaling position update: %v", err)
 			} else {
-				// Broadcast position update ke semua client
-				clientCount := wsHub.GetClientCount()
-				if clientCount > 0 {
-					wsHub.Broadcast(positionJsonData)
-					log.Printf("Broadcasted position update to %d clients", clientCount)
-				}
+				// Publish position update ke subscriber truck dan fleet di semua replica
+				wsHub.PublishTelemetry(positionJsonData, truckState, websocket.TruckChannel(macID), websocket.ChannelFleet)
+				log.Printf("Published position update for %s", macID)
 			}
 
 			// Marshal fuel update ke JSON
@@ -287,16 +384,9 @@ func (mc *MQTTClient) Subscribe() {
 			if err != nil {
 				log.Printf("Error marshaling fuel update: %v", err)
 			} else {
-				// Broadcast fuel update ke semua client
-				clientCount := wsHub.GetClientCount()
-				if clientCount > 0 {
-					wsHub.Broadcast(fuelJsonData)
-					log.Printf("Broadcasted fuel update to %d clients", clientCount)
-				}
-			}
-
-			if wsHub.GetClientCount() == 0 {
-				log.Printf("No WebSocket clients connected, updates not broadcasted")
+				// Publish fuel update ke subscriber truck dan fleet di semua replica
+				wsHub.PublishTelemetry(fuelJsonData, nil, websocket.TruckChannel(macID), websocket.ChannelFleet)
+				log.Printf("Published fuel update for %s", macID)
 			}
 		}
 	}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DirectionsCacheRepository provides access to cached directions responses
+type DirectionsCacheRepository interface {
+	FindByKey(key string) (*model.DirectionsCacheEntry, error)
+	Save(entry *model.DirectionsCacheEntry) error
+	Touch(key string, usedAt time.Time) error
+	DeleteExpired(now time.Time) (int64, error)
+	TrimToSize(maxEntries int) (int64, error)
+}
+
+type directionsCacheRepository struct{}
+
+// NewDirectionsCacheRepository creates a new instance of DirectionsCacheRepository
+func NewDirectionsCacheRepository() DirectionsCacheRepository {
+	return &directionsCacheRepository{}
+}
+
+// FindByKey returns the cache entry of a key, expired or not
+func (r *directionsCacheRepository) FindByKey(key string) (*model.DirectionsCacheEntry, error) {
+	var entry model.DirectionsCacheEntry
+	err := config.DB.Where("key = ?", key).First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("directions cache entry %w", ErrNotFound)
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Save creates or replaces a cache entry
+func (r *directionsCacheRepository) Save(entry *model.DirectionsCacheEntry) error {
+	return config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		UpdateAll: true,
+	}).Create(entry).Error
+}
+
+// Touch records that a cache entry was used
+func (r *directionsCacheRepository) Touch(key string, usedAt time.Time) error {
+	return config.DB.Model(&model.DirectionsCacheEntry{}).
+		Where("key = ?", key).
+		Update("last_used_at", usedAt).Error
+}
+
+// DeleteExpired removes the entries that expired before now
+func (r *directionsCacheRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := config.DB.Where("expires_at < ?", now).Delete(&model.DirectionsCacheEntry{})
+	return result.RowsAffected, result.Error
+}
+
+// TrimToSize removes the least recently used entries beyond maxEntries
+func (r *directionsCacheRepository) TrimToSize(maxEntries int) (int64, error) {
+	keep := config.DB.Model(&model.DirectionsCacheEntry{}).
+		Select("key").
+		Order("last_used_at DESC").
+		Limit(maxEntries)
+	result := config.DB.Where("key NOT IN (?)", keep).Delete(&model.DirectionsCacheEntry{})
+	return result.RowsAffected, result.Error
+}
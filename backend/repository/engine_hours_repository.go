@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EngineHoursRepository provides access to daily engine hours
+type EngineHoursRepository interface {
+	AddSeconds(truckID uint, macID string, date time.Time, engineOnSeconds, idleEngineOnSeconds int) error
+	FindByDateRange(start, end time.Time, truckID *uint) ([]*model.EngineHoursDaily, error)
+}
+
+type engineHoursRepository struct{}
+
+// NewEngineHoursRepository creates a new instance of EngineHoursRepository
+func NewEngineHoursRepository() EngineHoursRepository {
+	return &engineHoursRepository{}
+}
+
+// AddSeconds increments the engine hours of a truck for a day, creating the row if needed
+func (r *engineHoursRepository) AddSeconds(truckID uint, macID string, date time.Time, engineOnSeconds, idleEngineOnSeconds int) error {
+	row := &model.EngineHoursDaily{
+		TruckID:             truckID,
+		MacID:               macID,
+		Date:                date,
+		EngineOnSeconds:     engineOnSeconds,
+		IdleEngineOnSeconds: idleEngineOnSeconds,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+
+	return config.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "mac_id"}, {Name: "date"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"engine_on_seconds":      gorm.Expr("engine_hours_dailies.engine_on_seconds + ?", engineOnSeconds),
+			"idle_engine_on_seconds": gorm.Expr("engine_hours_dailies.idle_engine_on_seconds + ?", idleEngineOnSeconds),
+			"updated_at":             time.Now(),
+		}),
+	}).Create(row).Error
+}
+
+// FindByDateRange returns daily engine hours between two dates, optionally for one truck
+func (r *engineHoursRepository) FindByDateRange(start, end time.Time, truckID *uint) ([]*model.EngineHoursDaily, error) {
+	var rows []*model.EngineHoursDaily
+	query := config.DB.Where("date >= ? AND date <= ?", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if truckID != nil {
+		query = query.Where("truck_id = ?", *truckID)
+	}
+
+	err := query.Order("truck_id asc, date asc").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
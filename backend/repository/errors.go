@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrNotFound is wrapped by the lookups that report a missing record, e.g. "idle rule not found"
+var ErrNotFound = errors.New("not found")
+
+// pgUniqueViolation is the PostgreSQL SQLSTATE of a unique constraint violation
+const pgUniqueViolation = "23505"
+
+// IsUniqueViolation reports whether err is a PostgreSQL unique violation on a constraint or index
+// whose name starts with constraintPrefix
+func IsUniqueViolation(err error, constraintPrefix string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == pgUniqueViolation && strings.HasPrefix(pgErr.ConstraintName, constraintPrefix)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	activeTruck := &pgconn.PgError{Code: "23505", ConstraintName: "idx_route_plans_active_truck"}
+
+	tests := []struct {
+		name   string
+		err    error
+		prefix string
+		want   bool
+	}{
+		{"matching index", activeTruck, "idx_route_plans_active_", true},
+		{"wrapped error", fmt.Errorf("update route plan: %w", activeTruck), "idx_route_plans_active_", true},
+		{"other index", &pgconn.PgError{Code: "23505", ConstraintName: "idx_users_email"}, "idx_route_plans_active_", false},
+		{"other sqlstate", &pgconn.PgError{Code: "23503", ConstraintName: "idx_route_plans_active_truck"}, "idx_route_plans_active_", false},
+		{"index name only in message", errors.New(`duplicate key value violates unique constraint "idx_route_plans_active_truck"`), "idx_route_plans_active_", false},
+		{"nil error", nil, "idx_route_plans_active_", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUniqueViolation(tt.err, tt.prefix); got != tt.want {
+				t.Errorf("IsUniqueViolation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"gorm.io/gorm"
+)
+
+// IdleRuleRepository provides access to idle rule data
+type IdleRuleRepository interface {
+	Create(rule *model.IdleRule) error
+	Update(rule *model.IdleRule) error
+	Delete(id uint) error
+	FindByID(id uint) (*model.IdleRule, error)
+	FindAll() ([]*model.IdleRule, error)
+	FindAllActive() ([]*model.IdleRule, error)
+}
+
+type idleRuleRepository struct{}
+
+// NewIdleRuleRepository creates a new instance of IdleRuleRepository
+func NewIdleRuleRepository() IdleRuleRepository {
+	return &idleRuleRepository{}
+}
+
+// Create creates a new idle rule
+func (r *idleRuleRepository) Create(rule *model.IdleRule) error {
+	return config.DB.Create(rule).Error
+}
+
+// Update updates an existing idle rule
+func (r *idleRuleRepository) Update(rule *model.IdleRule) error {
+	return config.DB.Save(rule).Error
+}
+
+// Delete soft-deletes an idle rule
+func (r *idleRuleRepository) Delete(id uint) error {
+	return config.DB.Delete(&model.IdleRule{}, id).Error
+}
+
+// FindByID finds an idle rule by ID
+func (r *idleRuleRepository) FindByID(id uint) (*model.IdleRule, error) {
+	var rule model.IdleRule
+	if err := config.DB.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("idle rule %w", ErrNotFound)
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// FindAll returns all idle rules, highest priority first
+func (r *idleRuleRepository) FindAll() ([]*model.IdleRule, error) {
+	var rules []*model.IdleRule
+	err := config.DB.Order("priority desc, id asc").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// FindAllActive returns all active idle rules, highest priority first
+func (r *idleRuleRepository) FindAllActive() ([]*model.IdleRule, error) {
+	var rules []*model.IdleRule
+	err := config.DB.Where("is_active = ?", true).Order("priority desc, id asc").Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
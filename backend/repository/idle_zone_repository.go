@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"gorm.io/gorm"
+)
+
+// IdleZoneRepository provides access to idle zone data
+type IdleZoneRepository interface {
+	Create(zone *model.IdleZone) error
+	Update(zone *model.IdleZone) error
+	Delete(id uint) error
+	FindByID(id uint) (*model.IdleZone, error)
+	FindAll() ([]*model.IdleZone, error)
+	FindAllActive() ([]*model.IdleZone, error)
+}
+
+type idleZoneRepository struct{}
+
+// NewIdleZoneRepository creates a new instance of IdleZoneRepository
+func NewIdleZoneRepository() IdleZoneRepository {
+	return &idleZoneRepository{}
+}
+
+// orderIdleZonePoints preloads zone points in polygon order
+func orderIdleZonePoints(db *gorm.DB) *gorm.DB {
+	return db.Order(`"order" asc`)
+}
+
+// Create creates an idle zone together with its points
+func (r *idleZoneRepository) Create(zone *model.IdleZone) error {
+	return config.DB.Create(zone).Error
+}
+
+// Update updates an idle zone and replaces its points
+func (r *idleZoneRepository) Update(zone *model.IdleZone) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("idle_zone_id = ?", zone.ID).Delete(&model.IdleZonePoint{}).Error; err != nil {
+			return err
+		}
+		for i := range zone.Points {
+			zone.Points[i].ID = 0
+			zone.Points[i].IdleZoneID = zone.ID
+		}
+		return tx.Session(&gorm.Session{FullSaveAssociations: true}).Save(zone).Error
+	})
+}
+
+// Delete deletes an idle zone and its points
+func (r *idleZoneRepository) Delete(id uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("idle_zone_id = ?", id).Delete(&model.IdleZonePoint{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.IdleZone{}, id).Error
+	})
+}
+
+// FindByID finds an idle zone by ID
+func (r *idleZoneRepository) FindByID(id uint) (*model.IdleZone, error) {
+	var zone model.IdleZone
+	if err := config.DB.Preload("Points", orderIdleZonePoints).First(&zone, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("idle zone %w", ErrNotFound)
+		}
+		return nil, err
+	}
+	return &zone, nil
+}
+
+// FindAll returns all idle zones
+func (r *idleZoneRepository) FindAll() ([]*model.IdleZone, error) {
+	var zones []*model.IdleZone
+	err := config.DB.Preload("Points", orderIdleZonePoints).Order("name asc").Find(&zones).Error
+	if err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// FindAllActive returns all active idle zones
+func (r *idleZoneRepository) FindAllActive() ([]*model.IdleZone, error) {
+	var zones []*model.IdleZone
+	err := config.DB.Preload("Points", orderIdleZonePoints).Where("is_active = ?", true).
+		Order("name asc").Find(&zones).Error
+	if err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
@@ -9,6 +9,8 @@ import (
 
 type RouteDeviationRepository interface {
 	Create(deviation *model.TruckRouteDeviation) error
+	Update(deviation *model.TruckRouteDeviation) error
+	FindOpenByTruckID(truckID uint) (*model.TruckRouteDeviation, error)
 	FindByID(id uint) (*model.TruckRouteDeviation, error)
 	FindByTruckID(truckID uint, limit int) ([]*model.TruckRouteDeviation, error)
 	FindByRoutePlanID(routePlanID uint, limit int) ([]*model.TruckRouteDeviation, error)
@@ -41,6 +43,21 @@ func (r *routeDeviationRepository) Create(deviation *model.TruckRouteDeviation)
 	return config.DB.Create(deviation).Error
 }
 
+// Update saves changes to a deviation episode
+func (r *routeDeviationRepository) Update(deviation *model.TruckRouteDeviation) error {
+	return config.DB.Save(deviation).Error
+}
+
+// FindOpenByTruckID finds the deviation episode a truck is currently in
+func (r *routeDeviationRepository) FindOpenByTruckID(truckID uint) (*model.TruckRouteDeviation, error) {
+	var deviation model.TruckRouteDeviation
+	err := config.DB.Where("truck_id = ? AND is_open = ?", truckID, true).Order("timestamp DESC").First(&deviation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &deviation, nil
+}
+
 // FindByID finds a route deviation by ID
 func (r *routeDeviationRepository) FindByID(id uint) (*model.TruckRouteDeviation, error) {
 	var deviation model.TruckRouteDeviation
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"gorm.io/gorm"
+)
+
+// RouteGeometryRevisionRepository provides access to the geometry revisions of route plans
+type RouteGeometryRevisionRepository interface {
+	Create(revision *model.RouteGeometryRevision) error
+	FindByRoutePlanID(routePlanID uint) ([]*model.RouteGeometryRevision, error)
+	FindLatestByRoutePlanID(routePlanID uint) (*model.RouteGeometryRevision, error)
+}
+
+type routeGeometryRevisionRepository struct{}
+
+// NewRouteGeometryRevisionRepository creates a new instance of RouteGeometryRevisionRepository
+func NewRouteGeometryRevisionRepository() RouteGeometryRevisionRepository {
+	return &routeGeometryRevisionRepository{}
+}
+
+// Create records a geometry revision
+func (r *routeGeometryRevisionRepository) Create(revision *model.RouteGeometryRevision) error {
+	return config.DB.Create(revision).Error
+}
+
+// FindByRoutePlanID returns the geometry revisions of a route plan, oldest first
+func (r *routeGeometryRevisionRepository) FindByRoutePlanID(routePlanID uint) ([]*model.RouteGeometryRevision, error) {
+	var revisions []*model.RouteGeometryRevision
+	err := config.DB.Where("route_plan_id = ?", routePlanID).Order("revision asc").Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// FindLatestByRoutePlanID returns the newest geometry revision of a route plan, or an error
+// wrapping ErrNotFound when it has none
+func (r *routeGeometryRevisionRepository) FindLatestByRoutePlanID(routePlanID uint) (*model.RouteGeometryRevision, error) {
+	var revision model.RouteGeometryRevision
+	err := config.DB.Where("route_plan_id = ?", routePlanID).Order("revision desc").First(&revision).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("geometry revision %w", ErrNotFound)
+		}
+		return nil, err
+	}
+	return &revision, nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
 	"gorm.io/gorm"
@@ -18,10 +20,20 @@ type RoutePlanRepository interface {
 	FindAvoidancePointsByAreaID(areaID uint) ([]*model.RouteAvoidancePoint, error)
 	FindAvoidanceAreaByID(id uint) (*model.RouteAvoidanceArea, error)
 	FindAvoidanceAreasByPermanentStatus(isPermanent bool) ([]*model.RouteAvoidanceArea, error)
+	FindApprovedAvoidanceAreas(routePlanID uint) ([]*model.RouteAvoidanceArea, error)
+	FindAvoidancePointsByAreaIDs(areaIDs []uint) ([]*model.RouteAvoidancePoint, error)
 	FindAll() ([]*model.RoutePlan, error)
 	FindAllActiveRoutePlans() ([]*model.RoutePlan, error)
 	FindActiveRoutePlansByTruckID(truckID uint) (*model.RoutePlan, error)
+	FindPlannedRoutePlansByTruckID(truckID uint) ([]*model.RoutePlan, error)
+	FindUnstartedRoutePlans(dueBefore time.Time) ([]*model.RoutePlan, error)
+	FindActiveByTruckOrDriver(truckID, driverID, excludeID uint) ([]*model.RoutePlan, error)
+	FindScheduleOverlaps(truckID, driverID, excludeID uint, start, end time.Time) ([]*model.RoutePlan, error)
+	FindByScheduleRange(start, end time.Time, driverID *uint) ([]*model.RoutePlan, error)
+	UpdateWaypoint(waypoint *model.RouteWaypoint) error
 	Update(routePlan *model.RoutePlan) error
+	UpdateActiveRouteGeometry(routePlan *model.RoutePlan) (bool, error)
+	FlagNotStarted(id uint, flaggedAt time.Time) (bool, error)
 	UpdateAvoidanceArea(area *model.RouteAvoidanceArea) error
 	UpdateAvoidanceAreaStatus(id uint, status string) error
 	DeleteAvoidanceArea(id uint) error
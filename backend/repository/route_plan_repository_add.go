@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
 )
@@ -24,3 +26,129 @@ func (r *routePlanRepository) FindActiveRoutePlansByTruckID(truckID uint) (*mode
 	}
 	return &routePlan, nil
 }
+
+// FindPlannedRoutePlansByTruckID returns the planned route plans of a truck, oldest first
+func (r *routePlanRepository) FindPlannedRoutePlansByTruckID(truckID uint) ([]*model.RoutePlan, error) {
+	var routePlans []*model.RoutePlan
+	err := config.DB.Where("truck_id = ? AND status = ?", truckID, "planned").Order("created_at asc").Find(&routePlans).Error
+	if err != nil {
+		return nil, err
+	}
+	return routePlans, nil
+}
+
+// FindUnstartedRoutePlans returns planned route plans that were due to start before a time, or created
+// before it when they have no planned start, and have not been flagged yet
+func (r *routePlanRepository) FindUnstartedRoutePlans(dueBefore time.Time) ([]*model.RoutePlan, error) {
+	var routePlans []*model.RoutePlan
+	err := config.DB.Where("status = ? AND COALESCE(planned_start_at, created_at) < ? AND not_started_flagged_at IS NULL", "planned", dueBefore).
+		Find(&routePlans).Error
+	if err != nil {
+		return nil, err
+	}
+	return routePlans, nil
+}
+
+// FindActiveByTruckOrDriver returns the active route plans of a truck or a driver, except excludeID
+func (r *routePlanRepository) FindActiveByTruckOrDriver(truckID, driverID, excludeID uint) ([]*model.RoutePlan, error) {
+	var routePlans []*model.RoutePlan
+	err := config.DB.Where("status = ? AND id <> ? AND (truck_id = ? OR driver_id = ?)", "active", excludeID, truckID, driverID).
+		Find(&routePlans).Error
+	if err != nil {
+		return nil, err
+	}
+	return routePlans, nil
+}
+
+// FindScheduleOverlaps returns open route plans of a truck or a driver whose planned window overlaps [start, end)
+func (r *routePlanRepository) FindScheduleOverlaps(truckID, driverID, excludeID uint, start, end time.Time) ([]*model.RoutePlan, error) {
+	var routePlans []*model.RoutePlan
+	err := config.DB.
+		Where("status IN ? AND id <> ? AND (truck_id = ? OR driver_id = ?)", []string{"planned", "active", "on confirmation"}, excludeID, truckID, driverID).
+		Where("planned_start_at < ? AND planned_end_at > ?", end, start).
+		Order("planned_start_at asc").
+		Find(&routePlans).Error
+	if err != nil {
+		return nil, err
+	}
+	return routePlans, nil
+}
+
+// FindByScheduleRange returns route plans planned to start in a range, or created in it when
+// they have no planned start, optionally for one driver
+func (r *routePlanRepository) FindByScheduleRange(start, end time.Time, driverID *uint) ([]*model.RoutePlan, error) {
+	var routePlans []*model.RoutePlan
+	query := config.DB.Where("COALESCE(planned_start_at, created_at) BETWEEN ? AND ?", start, end)
+	if driverID != nil {
+		query = query.Where("driver_id = ?", *driverID)
+	}
+
+	err := query.Order("driver_id asc, COALESCE(planned_start_at, created_at) asc").Find(&routePlans).Error
+	if err != nil {
+		return nil, err
+	}
+	return routePlans, nil
+}
+
+// UpdateWaypoint updates an existing waypoint
+func (r *routePlanRepository) UpdateWaypoint(waypoint *model.RouteWaypoint) error {
+	return config.DB.Save(waypoint).Error
+}
+
+// FindApprovedAvoidanceAreas returns the approved permanent avoidance areas and the approved
+// areas of one route plan. routePlanID 0 returns only the permanent areas.
+func (r *routePlanRepository) FindApprovedAvoidanceAreas(routePlanID uint) ([]*model.RouteAvoidanceArea, error) {
+	var areas []*model.RouteAvoidanceArea
+	err := config.DB.Where("status = ? AND (is_permanent = ? OR route_plan_id = ?)", "approved", true, routePlanID).
+		Order("id asc").
+		Find(&areas).Error
+	if err != nil {
+		return nil, err
+	}
+	return areas, nil
+}
+
+// FindAvoidancePointsByAreaIDs returns the points of several avoidance areas, ordered per area
+func (r *routePlanRepository) FindAvoidancePointsByAreaIDs(areaIDs []uint) ([]*model.RouteAvoidancePoint, error) {
+	var points []*model.RouteAvoidancePoint
+	if len(areaIDs) == 0 {
+		return points, nil
+	}
+	err := config.DB.Where("route_avoidance_area_id IN ?", areaIDs).
+		Order("route_avoidance_area_id asc, \"order\" asc").
+		Find(&points).Error
+	if err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+// UpdateActiveRouteGeometry writes only the geometry columns of a route plan, and only while it is
+// still active. It reports false when the route plan was completed, cancelled or deleted meanwhile.
+func (r *routePlanRepository) UpdateActiveRouteGeometry(routePlan *model.RoutePlan) (bool, error) {
+	result := config.DB.Model(&model.RoutePlan{}).
+		Where("id = ? AND status = ?", routePlan.ID, model.RoutePlanStatusActive).
+		Updates(map[string]interface{}{
+			"route_geometry":   routePlan.RouteGeometry,
+			"extras_data":      routePlan.ExtrasData,
+			"distance_meters":  routePlan.DistanceMeters,
+			"duration_seconds": routePlan.DurationSeconds,
+			"updated_at":       routePlan.UpdatedAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FlagNotStarted marks a route plan as not started, only while it is still planned and not flagged yet.
+// It reports false when the route plan was started, cancelled, deleted or flagged meanwhile.
+func (r *routePlanRepository) FlagNotStarted(id uint, flaggedAt time.Time) (bool, error) {
+	result := config.DB.Model(&model.RoutePlan{}).
+		Where("id = ? AND status = ? AND not_started_flagged_at IS NULL", id, model.RoutePlanStatusPlanned).
+		Update("not_started_flagged_at", flaggedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
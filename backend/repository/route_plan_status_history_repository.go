@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+// RoutePlanStatusHistoryRepository provides access to the status history of route plans
+type RoutePlanStatusHistoryRepository interface {
+	Create(history *model.RoutePlanStatusHistory) error
+	FindByRoutePlanID(routePlanID uint) ([]*model.RoutePlanStatusHistory, error)
+}
+
+type routePlanStatusHistoryRepository struct{}
+
+// NewRoutePlanStatusHistoryRepository creates a new instance of RoutePlanStatusHistoryRepository
+func NewRoutePlanStatusHistoryRepository() RoutePlanStatusHistoryRepository {
+	return &routePlanStatusHistoryRepository{}
+}
+
+// Create records a status change
+func (r *routePlanStatusHistoryRepository) Create(history *model.RoutePlanStatusHistory) error {
+	return config.DB.Create(history).Error
+}
+
+// FindByRoutePlanID returns the status changes of a route plan, oldest first
+func (r *routePlanStatusHistoryRepository) FindByRoutePlanID(routePlanID uint) ([]*model.RoutePlanStatusHistory, error) {
+	var histories []*model.RoutePlanStatusHistory
+	err := config.DB.Where("route_plan_id = ?", routePlanID).Order("created_at asc, id asc").Find(&histories).Error
+	if err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RouteProgressRepository provides access to the latest progress of route plans
+type RouteProgressRepository interface {
+	Save(progress *model.RouteProgress) error
+	FindByRoutePlanID(routePlanID uint) (*model.RouteProgress, error)
+}
+
+type routeProgressRepository struct{}
+
+// NewRouteProgressRepository creates a new instance of RouteProgressRepository
+func NewRouteProgressRepository() RouteProgressRepository {
+	return &routeProgressRepository{}
+}
+
+// Save creates or replaces the progress row of a route plan
+func (r *routeProgressRepository) Save(progress *model.RouteProgress) error {
+	return config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "route_plan_id"}},
+		UpdateAll: true,
+	}).Create(progress).Error
+}
+
+// FindByRoutePlanID returns the progress of a route plan
+func (r *routeProgressRepository) FindByRoutePlanID(routePlanID uint) (*model.RouteProgress, error) {
+	var progress model.RouteProgress
+	err := config.DB.Where("route_plan_id = ?", routePlanID).First(&progress).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("route progress not found")
+		}
+		return nil, err
+	}
+	return &progress, nil
+}
@@ -16,6 +16,7 @@ type TruckHistoryRepository interface {
 	GetFuelHistoryByTruckIDWithDateRange(truckID uint, days int) ([]*model.TruckFuelHistory, error)
 	GetFuelHistoryByTruckIDWithCustomDateRange(truckID uint, startDate, endDate time.Time) ([]*model.TruckFuelHistory, error)
 	GetPositionHistoryByTruckIDWithCustomDateRange(truckID uint, startDate, endDate time.Time) ([]*model.TruckPositionHistory, error)
+	GetPositionHistorySince(since time.Time) ([]*model.TruckPositionHistory, error)
 }
 
 type truckHistoryRepository struct{}
@@ -108,4 +109,11 @@ func (r *truckHistoryRepository) GetPositionHistoryByTruckIDWithCustomDateRange(
 	
 	err := query.Find(&histories).Error
 	return histories, err
-}
\ No newline at end of file
+}
+
+// GetPositionHistorySince mendapatkan riwayat posisi semua truck sejak waktu tertentu, urut per truck dan waktu
+func (r *truckHistoryRepository) GetPositionHistorySince(since time.Time) ([]*model.TruckPositionHistory, error) {
+	var histories []*model.TruckPositionHistory
+	err := config.DB.Where("timestamp >= ?", since).Order("mac_id ASC, timestamp ASC").Find(&histories).Error
+	return histories, err
+}
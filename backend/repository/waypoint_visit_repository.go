@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/config"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"gorm.io/gorm"
+)
+
+// WaypointVisitRepository provides access to waypoint visits
+type WaypointVisitRepository interface {
+	Create(visit *model.WaypointVisit) error
+	Update(visit *model.WaypointVisit) error
+	FindOpenByRoutePlanID(routePlanID uint) (*model.WaypointVisit, error)
+	FindByRoutePlanID(routePlanID uint) ([]*model.WaypointVisit, error)
+}
+
+type waypointVisitRepository struct{}
+
+// NewWaypointVisitRepository creates a new instance of WaypointVisitRepository
+func NewWaypointVisitRepository() WaypointVisitRepository {
+	return &waypointVisitRepository{}
+}
+
+// Create saves a new waypoint visit
+func (r *waypointVisitRepository) Create(visit *model.WaypointVisit) error {
+	return config.DB.Create(visit).Error
+}
+
+// Update saves changes to a waypoint visit
+func (r *waypointVisitRepository) Update(visit *model.WaypointVisit) error {
+	return config.DB.Save(visit).Error
+}
+
+// FindOpenByRoutePlanID returns the visit the truck of a route plan has not departed from yet
+func (r *waypointVisitRepository) FindOpenByRoutePlanID(routePlanID uint) (*model.WaypointVisit, error) {
+	var visit model.WaypointVisit
+	err := config.DB.Where("route_plan_id = ? AND departure_time IS NULL", routePlanID).
+		Order("arrival_time desc").First(&visit).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("waypoint visit not found")
+		}
+		return nil, err
+	}
+	return &visit, nil
+}
+
+// FindByRoutePlanID returns all visits of a route plan in arrival order
+func (r *waypointVisitRepository) FindByRoutePlanID(routePlanID uint) ([]*model.WaypointVisit, error) {
+	var visits []*model.WaypointVisit
+	err := config.DB.Where("route_plan_id = ?", routePlanID).Order("arrival_time asc").Find(&visits).Error
+	if err != nil {
+		return nil, err
+	}
+	return visits, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// BackgroundDrainTimeout is how long shutdown waits for running background tasks before cancelling them
+const BackgroundDrainTimeout = 30 * time.Second
+
+// BackgroundWorker runs tasks outside the request that started them. A panicking task is logged
+// instead of crashing the process, and main waits for running tasks on shutdown.
+type BackgroundWorker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewBackgroundWorker creates a background worker that accepts tasks until Shutdown
+func NewBackgroundWorker() *BackgroundWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BackgroundWorker{ctx: ctx, cancel: cancel}
+}
+
+// Go runs task in the background with a context that is cancelled when shutdown times out.
+// It returns false without running the task once shutdown has started.
+func (w *BackgroundWorker) Go(name string, task func(ctx context.Context)) bool {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return false
+	}
+	w.wg.Add(1)
+	w.mu.Unlock()
+
+	go func() {
+		defer w.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Background task %s panicked: %v\n%s", name, r, debug.Stack())
+			}
+		}()
+		task(w.ctx)
+	}()
+	return true
+}
+
+// Shutdown stops accepting tasks and waits for the running ones. When ctx ends first the running
+// tasks are cancelled and ctx's error is returned.
+func (w *BackgroundWorker) Shutdown(ctx context.Context) error {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		w.cancel()
+		return nil
+	case <-ctx.Done():
+		w.cancel()
+		return ctx.Err()
+	}
+}
+
+// recoverTask turns a panic in the calling function into an error, so one failing item of a
+// background task does not stop the rest
+func recoverTask(err *error) {
+	if r := recover(); r != nil {
+		log.Printf("Recovered from panic: %v\n%s", r, debug.Stack())
+		*err = fmt.Errorf("internal error: %v", r)
+	}
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Tempat penyimpanan cache directions untuk DIRECTIONS_CACHE
+const (
+	DirectionsCacheMemory   = "memory"   // Per instance backend
+	DirectionsCachePostgres = "postgres" // Dibagi antar instance backend
+	DirectionsCacheOff      = "off"
+)
+
+const (
+	// DirectionsCacheTTL is how long a cached directions response is used
+	DirectionsCacheTTL = time.Hour
+
+	// DirectionsCacheMaxEntries bounds the number of cached responses
+	DirectionsCacheMaxEntries = 1000
+
+	// directionsKeyPrecision is the number of decimals numbers are rounded to in the cache key, about 1 m for coordinates
+	directionsKeyPrecision = 5
+
+	// directionsPruneEvery is how many Postgres writes happen between removals of old entries
+	directionsPruneEvery = 100
+)
+
+// Hasil pencarian cache untuk metrik
+const (
+	directionsCacheHit    = "hit"
+	directionsCacheMiss   = "miss"
+	directionsCacheBypass = "bypass"
+)
+
+var (
+	directionsCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "routing_directions_cache_requests_total",
+			Help: "Directions requests by cache result (hit, miss, bypass)",
+		},
+		[]string{"result"},
+	)
+	directionsCacheErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "routing_directions_cache_errors_total",
+			Help: "Directions cache store errors",
+		},
+	)
+)
+
+// RegisterDirectionsCacheMetrics registers the directions cache metrics
+func RegisterDirectionsCacheMetrics(reg prometheus.Registerer) {
+	for _, collector := range []prometheus.Collector{directionsCacheRequests, directionsCacheErrors} {
+		if err := reg.Register(collector); err != nil {
+			log.Printf("Failed to register directions cache metric: %v", err)
+		}
+	}
+}
+
+// DirectionsCacheConfig holds the settings of the directions cache
+type DirectionsCacheConfig struct {
+	Store      string
+	TTL        time.Duration
+	MaxEntries int
+}
+
+// LoadDirectionsCacheConfig reads the directions cache settings from the environment
+func LoadDirectionsCacheConfig() DirectionsCacheConfig {
+	config := DirectionsCacheConfig{
+		Store:      strings.ToLower(envString("DIRECTIONS_CACHE", DirectionsCacheMemory)),
+		TTL:        time.Duration(envInt("DIRECTIONS_CACHE_TTL_MINUTES", int(DirectionsCacheTTL/time.Minute))) * time.Minute,
+		MaxEntries: envInt("DIRECTIONS_CACHE_MAX_ENTRIES", DirectionsCacheMaxEntries),
+	}
+	if config.TTL <= 0 {
+		config.TTL = DirectionsCacheTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DirectionsCacheMaxEntries
+	}
+	return config
+}
+
+// DirectionsCacheStore stores directions responses by key
+type DirectionsCacheStore interface {
+	Get(key string, now time.Time) ([]byte, bool, error)
+	Set(key string, value []byte, now time.Time) error
+}
+
+// DirectionsCache caches directions responses by a hash of the normalized request
+type DirectionsCache struct {
+	store DirectionsCacheStore
+}
+
+// NewDirectionsCache creates the configured directions cache, nil when caching is off
+func NewDirectionsCache(config DirectionsCacheConfig, repo repository.DirectionsCacheRepository) *DirectionsCache {
+	switch config.Store {
+	case DirectionsCacheOff:
+		return nil
+	case DirectionsCachePostgres:
+		return &DirectionsCache{store: &postgresDirectionsStore{
+			repo:       repo,
+			ttl:        config.TTL,
+			maxEntries: config.MaxEntries,
+		}}
+	case DirectionsCacheMemory:
+	default:
+		log.Printf("Unknown directions cache %q, using %s", config.Store, DirectionsCacheMemory)
+	}
+	return &DirectionsCache{store: newMemoryDirectionsStore(config.TTL, config.MaxEntries)}
+}
+
+// Get returns the cached response of a key. Store errors count as a miss.
+func (c *DirectionsCache) Get(key string) ([]byte, bool) {
+	value, ok, err := c.store.Get(key, time.Now())
+	if err != nil {
+		directionsCacheErrors.Inc()
+		log.Printf("Failed to read directions cache: %v", err)
+		return nil, false
+	}
+	return value, ok
+}
+
+// Set caches the response of a key
+func (c *DirectionsCache) Set(key string, value []byte) {
+	if err := c.store.Set(key, value, time.Now()); err != nil {
+		directionsCacheErrors.Inc()
+		log.Printf("Failed to write directions cache: %v", err)
+	}
+}
+
+// DirectionsCacheKey hashes a directions request after normalizing it: numbers such as
+// coordinates are rounded, object keys are sorted and extra_info is sorted without duplicates.
+// Requests that only differ in field order or GPS noise share a key.
+func DirectionsCacheKey(requestBody []byte) (string, error) {
+	var request map[string]interface{}
+	if err := json.Unmarshal(requestBody, &request); err != nil {
+		return "", err
+	}
+
+	if extras, ok := request["extra_info"].([]interface{}); ok {
+		request["extra_info"] = sortedUniqueStrings(extras)
+	}
+
+	// encoding/json menulis key map secara berurutan
+	normalized, err := json.Marshal(roundNumbers(request))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(normalized)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// roundNumbers rounds every number in a decoded JSON value to directionsKeyPrecision decimals
+func roundNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		factor := math.Pow10(directionsKeyPrecision)
+		return math.Round(v*factor) / factor
+	case []interface{}:
+		for i := range v {
+			v[i] = roundNumbers(v[i])
+		}
+		return v
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = roundNumbers(v[key])
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// sortedUniqueStrings sorts a decoded JSON array of strings and drops duplicates
+func sortedUniqueStrings(values []interface{}) []interface{} {
+	seen := make(map[string]bool)
+	var strs []string
+	for _, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			// Bukan daftar string, biarkan apa adanya
+			return values
+		}
+		if !seen[str] {
+			seen[str] = true
+			strs = append(strs, str)
+		}
+	}
+	sort.Strings(strs)
+
+	result := make([]interface{}, len(strs))
+	for i, str := range strs {
+		result[i] = str
+	}
+	return result
+}
+
+// memoryDirectionsStore is a least recently used cache in process memory
+type memoryDirectionsStore struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List // Depan = paling baru dipakai
+}
+
+type memoryDirectionsEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func newMemoryDirectionsStore(ttl time.Duration, maxEntries int) *memoryDirectionsStore {
+	return &memoryDirectionsStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Get returns an unexpired entry and marks it as recently used
+func (s *memoryDirectionsStore) Get(key string, now time.Time) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryDirectionsEntry)
+	if now.After(entry.expiresAt) {
+		s.order.Remove(element)
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+
+	s.order.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+// Set stores an entry and evicts the least recently used entries beyond the size bound
+func (s *memoryDirectionsStore) Set(key string, value []byte, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		entry := element.Value.(*memoryDirectionsEntry)
+		entry.value = value
+		entry.expiresAt = now.Add(s.ttl)
+		s.order.MoveToFront(element)
+		return nil
+	}
+
+	s.entries[key] = s.order.PushFront(&memoryDirectionsEntry{
+		key:       key,
+		value:     value,
+		expiresAt: now.Add(s.ttl),
+	})
+
+	for s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryDirectionsEntry).key)
+	}
+	return nil
+}
+
+// postgresDirectionsStore keeps the cache in Postgres so every backend instance shares it
+type postgresDirectionsStore struct {
+	repo       repository.DirectionsCacheRepository
+	ttl        time.Duration
+	maxEntries int
+
+	mu     sync.Mutex
+	writes int
+}
+
+// Get returns an unexpired entry and records its use
+func (s *postgresDirectionsStore) Get(key string, now time.Time) ([]byte, bool, error) {
+	entry, err := s.repo.FindByKey(key)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	if now.After(entry.ExpiresAt) {
+		return nil, false, nil
+	}
+
+	if err := s.repo.Touch(key, now); err != nil {
+		log.Printf("Failed to update directions cache entry: %v", err)
+	}
+	return []byte(entry.Response), true, nil
+}
+
+// Set stores an entry. Expired and least recently used entries are removed every directionsPruneEvery writes.
+func (s *postgresDirectionsStore) Set(key string, value []byte, now time.Time) error {
+	err := s.repo.Save(&model.DirectionsCacheEntry{
+		Key:        key,
+		Response:   string(value),
+		ExpiresAt:  now.Add(s.ttl),
+		LastUsedAt: now,
+		CreatedAt:  now,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.writes++
+	prune := s.writes%directionsPruneEvery == 0
+	s.mu.Unlock()
+
+	if prune {
+		if _, err := s.repo.DeleteExpired(now); err != nil {
+			return err
+		}
+		if _, err := s.repo.TrimToSize(s.maxEntries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
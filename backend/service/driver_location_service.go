@@ -1,12 +1,15 @@
 package service
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
 )
 
 type DriverLocationService interface {
@@ -22,6 +25,7 @@ type driverLocationService struct {
 	routePlanRepo repository.RoutePlanRepository
 	userRepo      repository.UserRepository
 	routePlanSvc  RoutePlanService
+	progressSvc   RouteProgressService
 }
 
 func NewDriverLocationService(
@@ -29,12 +33,14 @@ func NewDriverLocationService(
 	routePlanRepo repository.RoutePlanRepository,
 	userRepo repository.UserRepository,
 	routePlanSvc RoutePlanService,
+	progressSvc RouteProgressService,
 ) DriverLocationService {
 	return &driverLocationService{
 		locationRepo:  locationRepo,
 		routePlanRepo: routePlanRepo,
 		userRepo:      userRepo,
 		routePlanSvc:  routePlanSvc,
+		progressSvc:   progressSvc,
 	}
 }
 
@@ -98,9 +104,31 @@ func (s *driverLocationService) UpdateDriverLocation(routePlanID uint, driverID
 		CreatedAt:  location.CreatedAt,
 	}
 
+	// Push the new location to clients following this route
+	s.publishDriverLocation(response)
+
 	return response, nil
 }
 
+// publishDriverLocation sends a driver location update to the route's WebSocket subscribers
+func (s *driverLocationService) publishDriverLocation(location *model.DriverLocationResponse) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"type":     "driver_location",
+		"location": location,
+	})
+	if err != nil {
+		log.Printf("Error marshaling driver location update: %v", err)
+		return
+	}
+
+	wsHub.Publish(jsonData, websocket.RouteChannel(location.RoutePlanID))
+}
+
 // GetLatestDriverLocation retrieves the latest location of a driver for a specific route
 func (s *driverLocationService) GetLatestDriverLocation(routePlanID uint) (*model.DriverLocationResponse, error) {
 	// Check if the route plan exists
@@ -251,10 +279,14 @@ func (s *driverLocationService) GetActiveRoute(driverID uint) (*model.ActiveRout
 		}
 	}
 
+	// Progress is only known once the truck has reported a position on the route
+	progressResponse, _ := s.progressSvc.GetProgress(activeRoutePlan.ID)
+
 	// Create response
 	response := &model.ActiveRouteResponse{
 		RoutePlan: routePlanResponse,
 		Location:  locationResponse,
+		Progress:  progressResponse,
 	}
 
 	return response, nil
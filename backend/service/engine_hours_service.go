@@ -0,0 +1,126 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+)
+
+// EngineHoursService mengakumulasi dan melaporkan jam mesin truk
+type EngineHoursService interface {
+	AddEngineTime(truckID uint, macID string, at time.Time, engineOnSeconds, idleEngineOnSeconds int) error
+	GetReport(start, end time.Time) (*model.EngineHoursReportResponse, error)
+	GetTruckReport(truckID uint, start, end time.Time) (*model.EngineHoursTruckReport, error)
+}
+
+type engineHoursService struct {
+	engineHoursRepo repository.EngineHoursRepository
+	truckRepo       repository.TruckRepository
+}
+
+// NewEngineHoursService creates a new instance of EngineHoursService
+func NewEngineHoursService(engineHoursRepo repository.EngineHoursRepository, truckRepo repository.TruckRepository) EngineHoursService {
+	return &engineHoursService{
+		engineHoursRepo: engineHoursRepo,
+		truckRepo:       truckRepo,
+	}
+}
+
+// AddEngineTime adds engine-on time to the day of the given timestamp
+func (s *engineHoursService) AddEngineTime(truckID uint, macID string, at time.Time, engineOnSeconds, idleEngineOnSeconds int) error {
+	if engineOnSeconds <= 0 {
+		return nil
+	}
+	date := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	return s.engineHoursRepo.AddSeconds(truckID, macID, date, engineOnSeconds, idleEngineOnSeconds)
+}
+
+// GetReport returns the engine hours of every truck between two dates
+func (s *engineHoursService) GetReport(start, end time.Time) (*model.EngineHoursReportResponse, error) {
+	rows, err := s.engineHoursRepo.FindByDateRange(start, end, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.EngineHoursReportResponse{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Trucks:    s.groupByTruck(rows),
+	}
+	return report, nil
+}
+
+// GetTruckReport returns the engine hours of one truck between two dates
+func (s *engineHoursService) GetTruckReport(truckID uint, start, end time.Time) (*model.EngineHoursTruckReport, error) {
+	truck, err := s.truckRepo.FindByID(truckID)
+	if err != nil {
+		return nil, notFoundError("truck", err)
+	}
+
+	rows, err := s.engineHoursRepo.FindByDateRange(start, end, &truckID)
+	if err != nil {
+		return nil, err
+	}
+
+	if reports := s.groupByTruck(rows); len(reports) > 0 {
+		return reports[0], nil
+	}
+	return &model.EngineHoursTruckReport{
+		TruckID:     truck.ID,
+		MacID:       truck.MacID,
+		PlateNumber: truck.PlateNumber,
+		Days:        []model.EngineHoursDayResponse{},
+	}, nil
+}
+
+// groupByTruck folds daily rows, ordered by truck, into one report per truck
+func (s *engineHoursService) groupByTruck(rows []*model.EngineHoursDaily) []*model.EngineHoursTruckReport {
+	reports := []*model.EngineHoursTruckReport{}
+	var current *model.EngineHoursTruckReport
+	engineOn, idleEngineOn := 0, 0
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.EngineOnHours = secondsToHours(engineOn)
+		current.IdleEngineOnHours = secondsToHours(idleEngineOn)
+		if engineOn > 0 {
+			current.IdlePercentage = math.Round(float64(idleEngineOn)/float64(engineOn)*10000) / 100
+		}
+		reports = append(reports, current)
+	}
+
+	for _, row := range rows {
+		if current == nil || current.TruckID != row.TruckID {
+			flush()
+			current = &model.EngineHoursTruckReport{
+				TruckID: row.TruckID,
+				MacID:   row.MacID,
+				Days:    []model.EngineHoursDayResponse{},
+			}
+			if truck, err := s.truckRepo.FindByID(row.TruckID); err == nil {
+				current.PlateNumber = truck.PlateNumber
+			}
+			engineOn, idleEngineOn = 0, 0
+		}
+
+		engineOn += row.EngineOnSeconds
+		idleEngineOn += row.IdleEngineOnSeconds
+		current.Days = append(current.Days, model.EngineHoursDayResponse{
+			Date:              row.Date.Format("2006-01-02"),
+			EngineOnHours:     secondsToHours(row.EngineOnSeconds),
+			IdleEngineOnHours: secondsToHours(row.IdleEngineOnSeconds),
+		})
+	}
+	flush()
+
+	return reports
+}
+
+// secondsToHours converts seconds to hours rounded to two decimals
+func secondsToHours(seconds int) float64 {
+	return math.Round(float64(seconds)/3600*100) / 100
+}
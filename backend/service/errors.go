@@ -0,0 +1,30 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"gorm.io/gorm"
+)
+
+// Errors the controllers map to HTTP status codes. Services wrap them so the message still names
+// the record or action, e.g. "route plan not found".
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = repository.ErrNotFound
+	// ErrNotAllowed is returned when the user may not perform the action on the record
+	ErrNotAllowed = errors.New("not allowed")
+	// ErrRoutePlanConflict is returned when a route plan cannot become active because its
+	// truck or driver already has an active route plan
+	ErrRoutePlanConflict = errors.New("route plan conflict")
+)
+
+// notFoundError turns a failed lookup of what into "<what> not found" wrapping ErrNotFound.
+// Other errors, such as a lost database connection, are returned unchanged.
+func notFoundError(what string, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%s %w", what, ErrNotFound)
+	}
+	return err
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+)
+
+const (
+	// DefaultIdleRadius is the radius in meters used when no idle rule applies
+	DefaultIdleRadius = 15.0
+
+	// DefaultIdleMinDurationMinutes is the idle duration used when no idle rule applies
+	DefaultIdleMinDurationMinutes = 1
+
+	// DefaultEngineOnAlertMinutes is the engine-on idle time that raises an alert when a rule does not set one
+	DefaultEngineOnAlertMinutes = 10
+
+	// DefaultIdleRuleName is recorded on detections fired by the fallback rule
+	DefaultIdleRuleName = "default"
+
+	// idleRuleCacheTTL bounds how long rules edited on another replica take to apply
+	idleRuleCacheTTL = time.Minute
+)
+
+// IdleRuleService mengelola aturan deteksi idle
+type IdleRuleService interface {
+	CreateRule(req model.IdleRuleRequest, userID uint) (*model.IdleRuleResponse, error)
+	UpdateRule(id uint, req model.IdleRuleRequest) (*model.IdleRuleResponse, error)
+	DeleteRule(id uint) error
+	GetRuleByID(id uint) (*model.IdleRuleResponse, error)
+	GetAllRules() ([]*model.IdleRuleResponse, error)
+	// MatchRule returns the rule that applies to a truck type at the given time,
+	// or the default rule (ID 0) when none does
+	MatchRule(truckType string, at time.Time) *model.IdleRule
+}
+
+type idleRuleService struct {
+	ruleRepo repository.IdleRuleRepository
+
+	cacheMutex sync.RWMutex
+	rules      []*model.IdleRule
+	loadedAt   time.Time
+}
+
+// NewIdleRuleService creates a new instance of IdleRuleService
+func NewIdleRuleService(ruleRepo repository.IdleRuleRepository) IdleRuleService {
+	return &idleRuleService{
+		ruleRepo: ruleRepo,
+	}
+}
+
+// defaultIdleRule is used when no configured rule applies
+func defaultIdleRule() *model.IdleRule {
+	return &model.IdleRule{
+		Name:                 DefaultIdleRuleName,
+		Radius:               DefaultIdleRadius,
+		MinDurationMinutes:   DefaultIdleMinDurationMinutes,
+		IsActive:             true,
+		EngineOnAlertMinutes: DefaultEngineOnAlertMinutes,
+	}
+}
+
+// CreateRule creates a new idle rule
+func (s *idleRuleService) CreateRule(req model.IdleRuleRequest, userID uint) (*model.IdleRuleResponse, error) {
+	if err := validateIdleRuleRequest(req); err != nil {
+		return nil, err
+	}
+
+	rule := &model.IdleRule{
+		CreatedByID: userID,
+		IsActive:    true,
+		CreatedAt:   time.Now(),
+	}
+	applyIdleRuleRequest(rule, req)
+
+	if err := s.ruleRepo.Create(rule); err != nil {
+		return nil, errors.New("failed to create idle rule: " + err.Error())
+	}
+	s.invalidateCache()
+
+	response := rule.ToIdleRuleResponse()
+	return &response, nil
+}
+
+// UpdateRule updates an existing idle rule
+func (s *idleRuleService) UpdateRule(id uint, req model.IdleRuleRequest) (*model.IdleRuleResponse, error) {
+	rule, err := s.ruleRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateIdleRuleRequest(req); err != nil {
+		return nil, err
+	}
+
+	applyIdleRuleRequest(rule, req)
+	if err := s.ruleRepo.Update(rule); err != nil {
+		return nil, errors.New("failed to update idle rule: " + err.Error())
+	}
+	s.invalidateCache()
+
+	response := rule.ToIdleRuleResponse()
+	return &response, nil
+}
+
+// DeleteRule deletes an idle rule
+func (s *idleRuleService) DeleteRule(id uint) error {
+	if _, err := s.ruleRepo.FindByID(id); err != nil {
+		return err
+	}
+	if err := s.ruleRepo.Delete(id); err != nil {
+		return errors.New("failed to delete idle rule: " + err.Error())
+	}
+	s.invalidateCache()
+	return nil
+}
+
+// GetRuleByID returns an idle rule by ID
+func (s *idleRuleService) GetRuleByID(id uint) (*model.IdleRuleResponse, error) {
+	rule, err := s.ruleRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	response := rule.ToIdleRuleResponse()
+	return &response, nil
+}
+
+// GetAllRules returns all idle rules
+func (s *idleRuleService) GetAllRules() ([]*model.IdleRuleResponse, error) {
+	rules, err := s.ruleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*model.IdleRuleResponse, len(rules))
+	for i, rule := range rules {
+		response := rule.ToIdleRuleResponse()
+		responses[i] = &response
+	}
+	return responses, nil
+}
+
+// MatchRule returns the highest priority active rule for the truck type and time of day.
+// Rules limited to specific truck types win over generic rules of the same priority.
+func (s *idleRuleService) MatchRule(truckType string, at time.Time) *model.IdleRule {
+	var match *model.IdleRule
+	for _, rule := range s.activeRules() {
+		types := rule.TruckTypeList()
+		if len(types) > 0 && !containsFold(types, truckType) {
+			continue
+		}
+		if !inSchedule(rule.ScheduleStart, rule.ScheduleEnd, at) {
+			continue
+		}
+		if match == nil || rule.Priority > match.Priority ||
+			(rule.Priority == match.Priority && len(types) > 0 && match.TruckTypes == "") {
+			match = rule
+		}
+	}
+
+	if match == nil {
+		return defaultIdleRule()
+	}
+	return match
+}
+
+// activeRules returns the cached active rules, reloading them when stale
+func (s *idleRuleService) activeRules() []*model.IdleRule {
+	s.cacheMutex.RLock()
+	rules, loadedAt := s.rules, s.loadedAt
+	s.cacheMutex.RUnlock()
+
+	if time.Since(loadedAt) < idleRuleCacheTTL {
+		return rules
+	}
+
+	fresh, err := s.ruleRepo.FindAllActive()
+	if err != nil {
+		// Keep evaluating with the last known rules
+		log.Printf("Error loading idle rules: %v", err)
+		return rules
+	}
+
+	s.cacheMutex.Lock()
+	s.rules = fresh
+	s.loadedAt = time.Now()
+	s.cacheMutex.Unlock()
+	return fresh
+}
+
+// invalidateCache makes the next evaluation reload the rules
+func (s *idleRuleService) invalidateCache() {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+// validateIdleRuleRequest checks the fields of an idle rule request
+func validateIdleRuleRequest(req model.IdleRuleRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return errors.New("name is required")
+	}
+	if req.Radius <= 0 {
+		return errors.New("radius must be greater than 0")
+	}
+	if req.MinDurationMinutes <= 0 {
+		return errors.New("min_duration_minutes must be greater than 0")
+	}
+	if req.EngineOnAlertMinutes < 0 {
+		return errors.New("engine_on_alert_minutes must not be negative")
+	}
+	if (req.ScheduleStart == "") != (req.ScheduleEnd == "") {
+		return errors.New("schedule_start and schedule_end must be set together")
+	}
+	if req.ScheduleStart != "" {
+		if _, err := time.Parse("15:04", req.ScheduleStart); err != nil {
+			return errors.New("schedule_start must use the HH:MM format")
+		}
+		if _, err := time.Parse("15:04", req.ScheduleEnd); err != nil {
+			return errors.New("schedule_end must use the HH:MM format")
+		}
+	}
+	return nil
+}
+
+// applyIdleRuleRequest copies the request fields onto the rule
+func applyIdleRuleRequest(rule *model.IdleRule, req model.IdleRuleRequest) {
+	types := make([]string, 0, len(req.TruckTypes))
+	for _, truckType := range req.TruckTypes {
+		if truckType = strings.TrimSpace(truckType); truckType != "" {
+			types = append(types, truckType)
+		}
+	}
+
+	rule.Name = strings.TrimSpace(req.Name)
+	rule.Radius = req.Radius
+	rule.MinDurationMinutes = req.MinDurationMinutes
+	rule.TruckTypes = strings.Join(types, ",")
+	rule.ScheduleStart = req.ScheduleStart
+	rule.ScheduleEnd = req.ScheduleEnd
+	rule.Priority = req.Priority
+	rule.EngineOnAlertMinutes = req.EngineOnAlertMinutes
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+	rule.UpdatedAt = time.Now()
+}
+
+// inSchedule reports whether the time of day falls in the HH:MM window.
+// An empty window covers the whole day; a window ending before it starts wraps past midnight.
+func inSchedule(start, end string, at time.Time) bool {
+	if start == "" || end == "" {
+		return true
+	}
+	startTime, err := time.Parse("15:04", start)
+	if err != nil {
+		return false
+	}
+	endTime, err := time.Parse("15:04", end)
+	if err != nil {
+		return false
+	}
+
+	minute := at.Hour()*60 + at.Minute()
+	from := startTime.Hour()*60 + startTime.Minute()
+	to := endTime.Hour()*60 + endTime.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	return minute >= from || minute < to
+}
+
+// containsFold reports whether the list contains the value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
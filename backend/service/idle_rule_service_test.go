@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+func TestInSchedule(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		start, end string
+		at         time.Time
+		want       bool
+	}{
+		{"no window", "", "", at(3, 0), true},
+		{"only start", "08:00", "", at(3, 0), true},
+		{"inside day window", "08:00", "17:00", at(12, 30), true},
+		{"at window start", "08:00", "17:00", at(8, 0), true},
+		{"at window end", "08:00", "17:00", at(17, 0), false},
+		{"before day window", "08:00", "17:00", at(7, 59), false},
+		{"after day window", "08:00", "17:00", at(22, 0), false},
+		{"overnight before midnight", "22:00", "06:00", at(23, 15), true},
+		{"overnight after midnight", "22:00", "06:00", at(2, 0), true},
+		{"overnight at end", "22:00", "06:00", at(6, 0), false},
+		{"outside overnight window", "22:00", "06:00", at(12, 0), false},
+		{"empty window", "08:00", "08:00", at(8, 0), false},
+		{"invalid start", "8am", "17:00", at(12, 0), false},
+		{"invalid end", "08:00", "25:00", at(12, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inSchedule(tt.start, tt.end, tt.at); got != tt.want {
+				t.Errorf("inSchedule(%q, %q, %s) = %v, want %v", tt.start, tt.end, tt.at.Format("15:04"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateIdleRuleRequest(t *testing.T) {
+	valid := model.IdleRuleRequest{Name: "Depot", Radius: 50, MinDurationMinutes: 10}
+
+	tests := []struct {
+		name   string
+		modify func(req *model.IdleRuleRequest)
+		valid  bool
+	}{
+		{"valid", func(req *model.IdleRuleRequest) {}, true},
+		{"valid schedule", func(req *model.IdleRuleRequest) { req.ScheduleStart, req.ScheduleEnd = "22:00", "06:00" }, true},
+		{"blank name", func(req *model.IdleRuleRequest) { req.Name = "  " }, false},
+		{"zero radius", func(req *model.IdleRuleRequest) { req.Radius = 0 }, false},
+		{"zero duration", func(req *model.IdleRuleRequest) { req.MinDurationMinutes = 0 }, false},
+		{"negative engine on alert", func(req *model.IdleRuleRequest) { req.EngineOnAlertMinutes = -1 }, false},
+		{"schedule start only", func(req *model.IdleRuleRequest) { req.ScheduleStart = "08:00" }, false},
+		{"schedule end only", func(req *model.IdleRuleRequest) { req.ScheduleEnd = "08:00" }, false},
+		{"invalid schedule start", func(req *model.IdleRuleRequest) { req.ScheduleStart, req.ScheduleEnd = "8:00pm", "06:00" }, false},
+		{"invalid schedule end", func(req *model.IdleRuleRequest) { req.ScheduleStart, req.ScheduleEnd = "22:00", "24:30" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			if err := validateIdleRuleRequest(req); (err == nil) != tt.valid {
+				t.Errorf("validateIdleRuleRequest() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
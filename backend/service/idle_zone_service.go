@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+// idleZoneCacheTTL bounds how long zones edited on another replica take to apply
+const idleZoneCacheTTL = time.Minute
+
+// IdleZoneService mengelola zona pengecualian idle
+type IdleZoneService interface {
+	CreateZone(req model.IdleZoneRequest, userID uint) (*model.IdleZoneResponse, error)
+	UpdateZone(id uint, req model.IdleZoneRequest) (*model.IdleZoneResponse, error)
+	DeleteZone(id uint) error
+	GetZoneByID(id uint) (*model.IdleZoneResponse, error)
+	GetAllZones() ([]*model.IdleZoneResponse, error)
+	// FindZone returns the active zone containing the position, or nil
+	FindZone(latitude, longitude float64) *model.IdleZone
+}
+
+type idleZoneService struct {
+	zoneRepo repository.IdleZoneRepository
+
+	cacheMutex sync.RWMutex
+	zones      []*model.IdleZone
+	loadedAt   time.Time
+}
+
+// NewIdleZoneService creates a new instance of IdleZoneService
+func NewIdleZoneService(zoneRepo repository.IdleZoneRepository) IdleZoneService {
+	return &idleZoneService{
+		zoneRepo: zoneRepo,
+	}
+}
+
+// CreateZone creates a new idle zone
+func (s *idleZoneService) CreateZone(req model.IdleZoneRequest, userID uint) (*model.IdleZoneResponse, error) {
+	if err := validateIdleZoneRequest(req); err != nil {
+		return nil, err
+	}
+
+	zone := &model.IdleZone{
+		CreatedByID: userID,
+		IsActive:    true,
+		CreatedAt:   time.Now(),
+	}
+	applyIdleZoneRequest(zone, req)
+
+	if err := s.zoneRepo.Create(zone); err != nil {
+		return nil, errors.New("failed to create idle zone: " + err.Error())
+	}
+	s.invalidateCache()
+
+	response := zone.ToIdleZoneResponse()
+	return &response, nil
+}
+
+// UpdateZone updates an existing idle zone
+func (s *idleZoneService) UpdateZone(id uint, req model.IdleZoneRequest) (*model.IdleZoneResponse, error) {
+	zone, err := s.zoneRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateIdleZoneRequest(req); err != nil {
+		return nil, err
+	}
+
+	applyIdleZoneRequest(zone, req)
+	if err := s.zoneRepo.Update(zone); err != nil {
+		return nil, errors.New("failed to update idle zone: " + err.Error())
+	}
+	s.invalidateCache()
+
+	response := zone.ToIdleZoneResponse()
+	return &response, nil
+}
+
+// DeleteZone deletes an idle zone
+func (s *idleZoneService) DeleteZone(id uint) error {
+	if _, err := s.zoneRepo.FindByID(id); err != nil {
+		return err
+	}
+	if err := s.zoneRepo.Delete(id); err != nil {
+		return errors.New("failed to delete idle zone: " + err.Error())
+	}
+	s.invalidateCache()
+	return nil
+}
+
+// GetZoneByID returns an idle zone by ID
+func (s *idleZoneService) GetZoneByID(id uint) (*model.IdleZoneResponse, error) {
+	zone, err := s.zoneRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	response := zone.ToIdleZoneResponse()
+	return &response, nil
+}
+
+// GetAllZones returns all idle zones
+func (s *idleZoneService) GetAllZones() ([]*model.IdleZoneResponse, error) {
+	zones, err := s.zoneRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*model.IdleZoneResponse, len(zones))
+	for i, zone := range zones {
+		response := zone.ToIdleZoneResponse()
+		responses[i] = &response
+	}
+	return responses, nil
+}
+
+// FindZone returns the first active zone whose polygon contains the position
+func (s *idleZoneService) FindZone(latitude, longitude float64) *model.IdleZone {
+	point := utils.LatLng{Lat: latitude, Lng: longitude}
+	for _, zone := range s.activeZones() {
+		polygon := make([]utils.LatLng, len(zone.Points))
+		for i, p := range zone.Points {
+			polygon[i] = utils.LatLng{Lat: p.Latitude, Lng: p.Longitude}
+		}
+		if utils.PointInPolygon(point, polygon) {
+			return zone
+		}
+	}
+	return nil
+}
+
+// activeZones returns the cached active zones, reloading them when stale
+func (s *idleZoneService) activeZones() []*model.IdleZone {
+	s.cacheMutex.RLock()
+	zones, loadedAt := s.zones, s.loadedAt
+	s.cacheMutex.RUnlock()
+
+	if time.Since(loadedAt) < idleZoneCacheTTL {
+		return zones
+	}
+
+	fresh, err := s.zoneRepo.FindAllActive()
+	if err != nil {
+		// Keep evaluating with the last known zones
+		log.Printf("Error loading idle zones: %v", err)
+		return zones
+	}
+
+	s.cacheMutex.Lock()
+	s.zones = fresh
+	s.loadedAt = time.Now()
+	s.cacheMutex.Unlock()
+	return fresh
+}
+
+// invalidateCache makes the next lookup reload the zones
+func (s *idleZoneService) invalidateCache() {
+	s.cacheMutex.Lock()
+	defer s.cacheMutex.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+// validateIdleZoneRequest checks the fields of an idle zone request
+func validateIdleZoneRequest(req model.IdleZoneRequest) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(req.Points) < 3 {
+		return errors.New("a zone needs at least 3 points")
+	}
+	switch req.Policy {
+	case model.IdleZonePolicySuppress:
+	case model.IdleZonePolicyExtended:
+		if req.ExtendedMinutes <= 0 {
+			return errors.New("extended_minutes must be greater than 0 for the extended policy")
+		}
+	default:
+		return errors.New("policy must be suppress or extended")
+	}
+	return nil
+}
+
+// applyIdleZoneRequest copies the request fields onto the zone
+func applyIdleZoneRequest(zone *model.IdleZone, req model.IdleZoneRequest) {
+	zone.Name = strings.TrimSpace(req.Name)
+	zone.Category = req.Category
+	zone.Policy = req.Policy
+	zone.ExtendedMinutes = req.ExtendedMinutes
+	if req.Policy != model.IdleZonePolicyExtended {
+		zone.ExtendedMinutes = 0
+	}
+	if req.IsActive != nil {
+		zone.IsActive = *req.IsActive
+	}
+	zone.UpdatedAt = time.Now()
+
+	zone.Points = make([]model.IdleZonePoint, len(req.Points))
+	for i, point := range req.Points {
+		zone.Points[i] = model.IdleZonePoint{
+			Latitude:  point.Latitude,
+			Longitude: point.Longitude,
+			Order:     i,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+	}
+}
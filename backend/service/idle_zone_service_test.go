@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+func TestValidateIdleZoneRequest(t *testing.T) {
+	points := []model.IdleZonePointRequest{
+		{Latitude: -6.20, Longitude: 106.80},
+		{Latitude: -6.20, Longitude: 106.81},
+		{Latitude: -6.21, Longitude: 106.81},
+	}
+
+	tests := []struct {
+		name  string
+		req   model.IdleZoneRequest
+		valid bool
+	}{
+		{"suppress", model.IdleZoneRequest{Name: "Depot", Policy: model.IdleZonePolicySuppress, Points: points}, true},
+		{"extended", model.IdleZoneRequest{Name: "Customer", Policy: model.IdleZonePolicyExtended, ExtendedMinutes: 30, Points: points}, true},
+		{"extended without minutes", model.IdleZoneRequest{Name: "Customer", Policy: model.IdleZonePolicyExtended, Points: points}, false},
+		{"blank name", model.IdleZoneRequest{Name: " ", Policy: model.IdleZonePolicySuppress, Points: points}, false},
+		{"two points", model.IdleZoneRequest{Name: "Depot", Policy: model.IdleZonePolicySuppress, Points: points[:2]}, false},
+		{"unknown policy", model.IdleZoneRequest{Name: "Depot", Policy: "ignore", Points: points}, false},
+		{"missing policy", model.IdleZoneRequest{Name: "Depot", Points: points}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateIdleZoneRequest(tt.req); (err == nil) != tt.valid {
+				t.Errorf("validateIdleZoneRequest() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+const (
+	// DeviationExitThreshold is the distance in meters below which a deviating truck is back on the route
+	DeviationExitThreshold = 25.0
+
+	// DeviationHighwayThreshold and DeviationHighwayExitThreshold apply on highway segments,
+	// where lanes are wide and trucks legitimately drive further from the route line
+	DeviationHighwayThreshold     = 60.0
+	DeviationHighwayExitThreshold = 45.0
+
+	// DeviationMinPoints is how many consecutive off-route samples confirm a deviation
+	DeviationMinPoints = 3
+
+	// DeviationAccuracyFactor scales the reported GPS accuracy added to the enter threshold
+	DeviationAccuracyFactor = 1.0
+
+	// maxAccuracyMargin caps the accuracy margin so a broken fix cannot hide a real deviation
+	maxAccuracyMargin = 50.0
+
+	// hdopToMeters converts HDOP to an accuracy estimate for receivers that do not report meters
+	hdopToMeters = 5.0
+
+	// waycategoryHighway is the ORS waycategory bit for highways
+	waycategoryHighway = 1
+)
+
+// DeviationConfig holds the settings of the route deviation detector
+type DeviationConfig struct {
+	EnterMeters        float64
+	ExitMeters         float64
+	HighwayEnterMeters float64
+	HighwayExitMeters  float64
+	MinPoints          int
+	MinDurationSeconds int // 0 disables confirmation by duration
+	AccuracyFactor     float64
+}
+
+// LoadDeviationConfig reads the global deviation detector settings from the environment
+func LoadDeviationConfig() DeviationConfig {
+	config := DeviationConfig{
+		EnterMeters:        envFloat("DEVIATION_ENTER_METERS", DeviationThreshold),
+		ExitMeters:         envFloat("DEVIATION_EXIT_METERS", DeviationExitThreshold),
+		HighwayEnterMeters: envFloat("DEVIATION_HIGHWAY_ENTER_METERS", DeviationHighwayThreshold),
+		HighwayExitMeters:  envFloat("DEVIATION_HIGHWAY_EXIT_METERS", DeviationHighwayExitThreshold),
+		MinPoints:          envInt("DEVIATION_MIN_POINTS", DeviationMinPoints),
+		MinDurationSeconds: envInt("DEVIATION_MIN_DURATION_SECONDS", 0),
+		AccuracyFactor:     envFloat("DEVIATION_ACCURACY_FACTOR", DeviationAccuracyFactor),
+	}
+
+	if err := config.validate(); err != nil {
+		log.Printf("Invalid route deviation settings in environment, using defaults: %v", err)
+		return DeviationConfig{
+			EnterMeters:        DeviationThreshold,
+			ExitMeters:         DeviationExitThreshold,
+			HighwayEnterMeters: DeviationHighwayThreshold,
+			HighwayExitMeters:  DeviationHighwayExitThreshold,
+			MinPoints:          DeviationMinPoints,
+			AccuracyFactor:     DeviationAccuracyFactor,
+		}
+	}
+	return config
+}
+
+// withOverrides applies the route plan overrides on top of the global settings
+func (c DeviationConfig) withOverrides(settings *model.RouteDeviationSettings) DeviationConfig {
+	if settings == nil {
+		return c
+	}
+	if settings.EnterMeters != nil {
+		c.EnterMeters = *settings.EnterMeters
+	}
+	if settings.ExitMeters != nil {
+		c.ExitMeters = *settings.ExitMeters
+	}
+	if settings.HighwayEnterMeters != nil {
+		c.HighwayEnterMeters = *settings.HighwayEnterMeters
+	}
+	if settings.HighwayExitMeters != nil {
+		c.HighwayExitMeters = *settings.HighwayExitMeters
+	}
+	if settings.MinPoints != nil {
+		c.MinPoints = *settings.MinPoints
+	}
+	if settings.MinDurationSeconds != nil {
+		c.MinDurationSeconds = *settings.MinDurationSeconds
+	}
+	if settings.AccuracyFactor != nil {
+		c.AccuracyFactor = *settings.AccuracyFactor
+	}
+	return c
+}
+
+// thresholds returns the enter and exit distance for a sample. The enter distance
+// grows with the reported GPS accuracy so a single noisy fix does not count as off-route.
+func (c DeviationConfig) thresholds(highway bool, accuracy float64) (float64, float64) {
+	enter, exit := c.EnterMeters, c.ExitMeters
+	if highway {
+		enter, exit = c.HighwayEnterMeters, c.HighwayExitMeters
+	}
+
+	margin := accuracy * c.AccuracyFactor
+	if margin > maxAccuracyMargin {
+		margin = maxAccuracyMargin
+	}
+	if margin > 0 {
+		enter += margin
+	}
+	return enter, exit
+}
+
+// validate checks that the settings form a usable hysteresis band
+func (c DeviationConfig) validate() error {
+	if c.EnterMeters <= 0 || c.HighwayEnterMeters <= 0 {
+		return errors.New("enter thresholds must be greater than 0")
+	}
+	if c.ExitMeters <= 0 || c.HighwayExitMeters <= 0 {
+		return errors.New("exit thresholds must be greater than 0")
+	}
+	if c.ExitMeters > c.EnterMeters || c.HighwayExitMeters > c.HighwayEnterMeters {
+		return errors.New("exit thresholds must not be greater than enter thresholds")
+	}
+	if c.MinPoints < 1 {
+		return errors.New("min_points must be at least 1")
+	}
+	if c.MinDurationSeconds < 0 {
+		return errors.New("min_duration_seconds must not be negative")
+	}
+	if c.AccuracyFactor < 0 {
+		return errors.New("accuracy_factor must not be negative")
+	}
+	return nil
+}
+
+// ValidateDeviationSettings checks route plan overrides against the global settings
+func ValidateDeviationSettings(settings *model.RouteDeviationSettings) error {
+	return LoadDeviationConfig().withOverrides(settings).validate()
+}
+
+// AccuracyFromHDOP estimates the GPS accuracy in meters from the horizontal dilution of precision
+func AccuracyFromHDOP(hdop float64) float64 {
+	return hdop * hdopToMeters
+}
+
+// isHighwaySegment reports whether the route segment is a highway according to the ORS extras
+func isHighwaySegment(extras *model.RouteExtras, segmentIndex int) bool {
+	if extras == nil || segmentIndex < 0 {
+		return false
+	}
+	if extras.Waycategory != nil {
+		if value, ok := extraValueAt(extras.Waycategory, segmentIndex); ok && value&waycategoryHighway != 0 {
+			return true
+		}
+	}
+	if extras.Waytype != nil {
+		// Waytype 1 is "State Road"
+		if value, ok := extraValueAt(extras.Waytype, segmentIndex); ok && value == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// extraValueAt finds the extras value of the [from, to, value] range containing the point index
+func extraValueAt(extra *model.ExtraValues, index int) (int, bool) {
+	for _, value := range extra.Values {
+		if len(value) >= 3 && index >= value[0] && index < value[1] {
+			return value[2], true
+		}
+	}
+	return 0, false
+}
+
+// envFloat reads a float from the environment, or returns fallback
+func envFloat(key string, fallback float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// envInt reads an integer from the environment, or returns fallback
+func envInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+// testDeviationConfig is the default detector configuration
+func testDeviationConfig() DeviationConfig {
+	return DeviationConfig{
+		EnterMeters:        DeviationThreshold,
+		ExitMeters:         DeviationExitThreshold,
+		HighwayEnterMeters: DeviationHighwayThreshold,
+		HighwayExitMeters:  DeviationHighwayExitThreshold,
+		MinPoints:          DeviationMinPoints,
+		AccuracyFactor:     DeviationAccuracyFactor,
+	}
+}
+
+func TestDeviationConfigThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		factor    float64
+		highway   bool
+		accuracy  float64
+		wantEnter float64
+		wantExit  float64
+	}{
+		{"city without accuracy", 1, false, 0, 35, 25},
+		{"highway without accuracy", 1, true, 0, 60, 45},
+		{"accuracy widens enter only", 1, false, 10, 45, 25},
+		{"accuracy on highway", 1, true, 10, 70, 45},
+		{"accuracy factor scales margin", 0.5, false, 10, 40, 25},
+		{"margin is capped", 1, false, 500, 35 + maxAccuracyMargin, 25},
+		{"factor zero ignores accuracy", 0, false, 30, 35, 25},
+		{"unknown accuracy", 1, false, -1, 35, 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testDeviationConfig()
+			config.AccuracyFactor = tt.factor
+			enter, exit := config.thresholds(tt.highway, tt.accuracy)
+			if enter != tt.wantEnter || exit != tt.wantExit {
+				t.Errorf("thresholds(%v, %v) = %v, %v, want %v, %v", tt.highway, tt.accuracy, enter, exit, tt.wantEnter, tt.wantExit)
+			}
+		})
+	}
+}
+
+func TestDeviationConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *DeviationConfig)
+		valid  bool
+	}{
+		{"defaults", func(c *DeviationConfig) {}, true},
+		{"exit equal to enter", func(c *DeviationConfig) { c.ExitMeters = c.EnterMeters }, true},
+		{"duration confirmation", func(c *DeviationConfig) { c.MinDurationSeconds = 60 }, true},
+		{"zero enter", func(c *DeviationConfig) { c.EnterMeters = 0 }, false},
+		{"zero highway enter", func(c *DeviationConfig) { c.HighwayEnterMeters = 0 }, false},
+		{"zero exit", func(c *DeviationConfig) { c.ExitMeters = 0 }, false},
+		{"exit above enter", func(c *DeviationConfig) { c.ExitMeters = c.EnterMeters + 1 }, false},
+		{"highway exit above enter", func(c *DeviationConfig) { c.HighwayExitMeters = c.HighwayEnterMeters + 1 }, false},
+		{"no points", func(c *DeviationConfig) { c.MinPoints = 0 }, false},
+		{"negative duration", func(c *DeviationConfig) { c.MinDurationSeconds = -1 }, false},
+		{"negative accuracy factor", func(c *DeviationConfig) { c.AccuracyFactor = -0.5 }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testDeviationConfig()
+			tt.modify(&config)
+			if err := config.validate(); (err == nil) != tt.valid {
+				t.Errorf("validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestDeviationConfigWithOverrides(t *testing.T) {
+	enter, exit, points := 80.0, 90.0, 5
+
+	tests := []struct {
+		name     string
+		settings *model.RouteDeviationSettings
+		want     func(c *DeviationConfig)
+		valid    bool
+	}{
+		{"no overrides", nil, func(c *DeviationConfig) {}, true},
+		{"enter and points", &model.RouteDeviationSettings{EnterMeters: &enter, MinPoints: &points},
+			func(c *DeviationConfig) { c.EnterMeters, c.MinPoints = enter, points }, true},
+		{"exit above global enter", &model.RouteDeviationSettings{ExitMeters: &exit},
+			func(c *DeviationConfig) { c.ExitMeters = exit }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := testDeviationConfig()
+			tt.want(&want)
+			got := testDeviationConfig().withOverrides(tt.settings)
+			if got != want {
+				t.Errorf("withOverrides() = %+v, want %+v", got, want)
+			}
+			if err := got.validate(); (err == nil) != tt.valid {
+				t.Errorf("validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestLoadDeviationConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantEnter float64
+		wantExit  float64
+	}{
+		{"defaults", nil, DeviationThreshold, DeviationExitThreshold},
+		{"from environment", map[string]string{"DEVIATION_ENTER_METERS": "50", "DEVIATION_EXIT_METERS": "30"}, 50, 30},
+		{"unparsable value keeps default", map[string]string{"DEVIATION_ENTER_METERS": "far"}, DeviationThreshold, DeviationExitThreshold},
+		{"invalid band falls back to defaults", map[string]string{"DEVIATION_EXIT_METERS": "100"}, DeviationThreshold, DeviationExitThreshold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DEVIATION_ENTER_METERS", "DEVIATION_EXIT_METERS"} {
+				t.Setenv(key, tt.env[key])
+			}
+			config := LoadDeviationConfig()
+			if config.EnterMeters != tt.wantEnter || config.ExitMeters != tt.wantExit {
+				t.Errorf("enter, exit = %v, %v, want %v, %v", config.EnterMeters, config.ExitMeters, tt.wantEnter, tt.wantExit)
+			}
+		})
+	}
+}
+
+func TestIsHighwaySegment(t *testing.T) {
+	extras := &model.RouteExtras{
+		Waycategory: &model.ExtraValues{Values: [][]int{{0, 10, 0}, {10, 20, 1}, {20, 30, 4}}},
+		Waytype:     &model.ExtraValues{Values: [][]int{{0, 25, 3}, {25, 30, 1}}},
+	}
+
+	tests := []struct {
+		name    string
+		extras  *model.RouteExtras
+		segment int
+		want    bool
+	}{
+		{"no extras", nil, 5, false},
+		{"negative segment", extras, -1, false},
+		{"ordinary road", extras, 5, false},
+		{"highway waycategory", extras, 15, true},
+		{"range end is exclusive", extras, 20, false},
+		{"state road waytype", extras, 27, true},
+		{"past the extras", extras, 40, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isHighwaySegment(tt.extras, tt.segment); got != tt.want {
+				t.Errorf("isHighwaySegment(%d) = %v, want %v", tt.segment, got, tt.want)
+			}
+		})
+	}
+}
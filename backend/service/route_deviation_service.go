@@ -7,16 +7,24 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
 )
 
 const (
 	// Deviation threshold in meters
 	DeviationThreshold = 35.0
+
+	// maxDeviationPathPoints caps the off-route samples stored per episode
+	maxDeviationPathPoints = 500
 )
 
 // NotificationRequest represents the payload for sending notifications
@@ -29,8 +37,8 @@ type NotificationRequest struct {
 }
 
 type RouteDeviationService interface {
-	DetectAndSaveDeviation(macID string, latitude, longitude float64, timestamp time.Time) error
-	GetRouteDeviationsByTruckIDAndDateRange(truckID uint, startDate, endDate time.Time) ([]*model.TruckRouteDeviation, error)
+	DetectAndSaveDeviation(macID string, latitude, longitude, accuracy float64, timestamp time.Time) error
+	GetRouteDeviationsByTruckIDAndDateRange(truckID uint, startDate, endDate time.Time) ([]model.RouteDeviationResponse, error)
 }
 
 type routeDeviationService struct {
@@ -38,6 +46,19 @@ type routeDeviationService struct {
 	routePlanRepo     repository.RoutePlanRepository
 	deviationRepo     repository.RouteDeviationRepository
 	userRepo          repository.UserRepository
+	routeCache        *RouteGeometryCache
+	escalations       []time.Duration
+	config            DeviationConfig
+	candidates        map[uint]*deviationCandidate // Sampel di luar rute yang belum dikonfirmasi, per truk
+	candidateMutex    sync.Mutex
+}
+
+// deviationCandidate collects off-route samples until they confirm a deviation
+type deviationCandidate struct {
+	RoutePlanID  uint
+	Reference    utils.LatLng
+	SegmentIndex int
+	Samples      []model.DeviationPathPoint
 }
 
 func NewRouteDeviationService(
@@ -45,129 +66,334 @@ func NewRouteDeviationService(
 	routePlanRepo repository.RoutePlanRepository,
 	deviationRepo repository.RouteDeviationRepository,
 	userRepo repository.UserRepository,
+	routeCache *RouteGeometryCache,
 ) RouteDeviationService {
 	return &routeDeviationService{
 		truckRepo:         truckRepo,
 		routePlanRepo:     routePlanRepo,
 		deviationRepo:     deviationRepo,
 		userRepo:          userRepo,
+		routeCache:        routeCache,
+		escalations:       parseEscalationSteps(os.Getenv("DEVIATION_ESCALATION_MINUTES")),
+		config:            LoadDeviationConfig(),
+		candidates:        make(map[uint]*deviationCandidate),
+	}
+}
+
+// addCandidate records an off-route sample that is not yet part of an episode
+func (s *routeDeviationService) addCandidate(truckID, routePlanID uint, sample model.DeviationPathPoint, reference utils.LatLng, segmentIndex int) *deviationCandidate {
+	s.candidateMutex.Lock()
+	defer s.candidateMutex.Unlock()
+
+	candidate, ok := s.candidates[truckID]
+	if !ok || candidate.RoutePlanID != routePlanID {
+		candidate = &deviationCandidate{
+			RoutePlanID:  routePlanID,
+			Reference:    reference,
+			SegmentIndex: segmentIndex,
+		}
+		s.candidates[truckID] = candidate
+	}
+	candidate.Samples = append(candidate.Samples, sample)
+	return candidate
+}
+
+// clearCandidate forgets unconfirmed off-route samples of a truck
+func (s *routeDeviationService) clearCandidate(truckID uint) {
+	s.candidateMutex.Lock()
+	delete(s.candidates, truckID)
+	s.candidateMutex.Unlock()
+}
+
+// confirms reports whether the candidate has enough consecutive samples or has lasted long enough
+func (c DeviationConfig) confirms(candidate *deviationCandidate) bool {
+	if len(candidate.Samples) >= c.MinPoints {
+		return true
+	}
+	if c.MinDurationSeconds > 0 {
+		first, last := candidate.Samples[0], candidate.Samples[len(candidate.Samples)-1]
+		return last.Timestamp.Sub(first.Timestamp) >= time.Duration(c.MinDurationSeconds)*time.Second
+	}
+	return false
+}
+
+// parseEscalationSteps reads a comma separated list of minutes, e.g. "10,30".
+// Each step sends one more notification while a deviation episode stays open.
+func parseEscalationSteps(value string) []time.Duration {
+	steps := []time.Duration{}
+	for _, part := range strings.Split(value, ",") {
+		minutes, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || minutes <= 0 {
+			continue
+		}
+		steps = append(steps, time.Duration(minutes)*time.Minute)
 	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i] < steps[j] })
+	return steps
 }
 
-func (s *routeDeviationService) GetRouteDeviationsByTruckIDAndDateRange(truckID uint, startDate, endDate time.Time) ([]*model.TruckRouteDeviation, error) {
-    return s.deviationRepo.GetRouteDeviationsByTruckIDAndDateRange(truckID, startDate, endDate)
+func (s *routeDeviationService) GetRouteDeviationsByTruckIDAndDateRange(truckID uint, startDate, endDate time.Time) ([]model.RouteDeviationResponse, error) {
+	deviations, err := s.deviationRepo.GetRouteDeviationsByTruckIDAndDateRange(truckID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.RouteDeviationResponse, len(deviations))
+	for i, deviation := range deviations {
+		responses[i] = deviation.ToRouteDeviationResponse()
+	}
+	return responses, nil
 }
 
-// DetectAndSaveDeviation checks if a truck position deviates from its route plan
-// and saves the deviation if it exceeds the threshold
-func (s *routeDeviationService) DetectAndSaveDeviation(macID string, latitude, longitude float64, timestamp time.Time) error {
+// DetectAndSaveDeviation tracks deviation episodes of a truck against its route plan.
+// Enough consecutive samples beyond the enter threshold open an episode, later samples
+// extend it and the first sample within the exit threshold closes it. Accuracy is the
+// reported GPS accuracy in meters, 0 when unknown.
+func (s *routeDeviationService) DetectAndSaveDeviation(macID string, latitude, longitude, accuracy float64, timestamp time.Time) error {
 	// Find the truck by MAC ID
 	truck, err := s.truckRepo.FindByMacID(macID)
 	if err != nil {
 		return err
 	}
 
-	// Find the active route plan for this truck
-	routePlan, err := s.routePlanRepo.FindActiveRoutePlansByTruckID(truck.ID)
+	// Episode yang masih terbuka untuk truk ini
+	openEpisode, _ := s.deviationRepo.FindOpenByTruckID(truck.ID)
+
+	// Active route plan and its indexed geometry, cached per truck
+	route, err := s.routeCache.activeRoute(truck.ID)
 	if err != nil {
-		// No active route plan for this truck, skip deviation check
-		log.Printf("No active route plan found for truck %s (ID: %d)", macID, truck.ID)
+		return err
+	}
+	routePlan := route.Plan
+	if routePlan == nil {
+		// No active route plan for this truck, an open episode ends with the route
+		s.clearCandidate(truck.ID)
+		if openEpisode != nil {
+			return s.closeEpisode(truck, openEpisode, timestamp)
+		}
 		return nil
 	}
 
-	// Get route plan details including driver ID
-	driverID := routePlan.DriverID
-
-	// Decode the route geometry
-	points, err := utils.DecodeRouteGeometry(routePlan.RouteGeometry)
-	if err != nil {
-		log.Printf("Error decoding route geometry for route plan %d: %v", routePlan.ID, err)
-		return err
+	// An episode belongs to one route plan
+	if openEpisode != nil && openEpisode.RoutePlanID != routePlan.ID {
+		if err := s.closeEpisode(truck, openEpisode, timestamp); err != nil {
+			return err
+		}
+		openEpisode = nil
 	}
 
-	if len(points) == 0 {
+	if len(route.Index.Points()) == 0 {
 		log.Printf("Empty route geometry for route plan %d", routePlan.ID)
 		return nil
 	}
 
 	// Calculate the distance from the truck position to the route
 	currentPoint := utils.LatLng{Lat: latitude, Lng: longitude}
-	distance, referencePoint, segmentIndex := utils.CalculateDistanceToPolyline(currentPoint, points)
-
-	// Check if the distance exceeds the threshold
-	if distance >= DeviationThreshold {
-		// Create a new route deviation record
-		deviation := &model.TruckRouteDeviation{
-			TruckID:      truck.ID,
-			MacID:        macID,
-			RoutePlanID:  routePlan.ID,
-			DriverID:     driverID,
-			Latitude:     latitude,
-			Longitude:    longitude,
-			RefLatitude:  referencePoint.Lat,
-			RefLongitude: referencePoint.Lng,
-			Distance:     distance,
-			SegmentIndex: segmentIndex,
-			Timestamp:    timestamp,
-			CreatedAt:    time.Now(),
-		}
+	distance, referencePoint, segmentIndex := route.Nearest(currentPoint)
 
-		// Save the deviation
-		err = s.deviationRepo.Create(deviation)
-		if err != nil {
-			log.Printf("Error saving route deviation for truck %s: %v", macID, err)
-			return err
-		}
+	// Ambang masuk/keluar tergantung kelas jalan, akurasi GPS, dan pengaturan route plan
+	cfg := s.config.withOverrides(route.Settings)
+	enterThreshold, exitThreshold := cfg.thresholds(isHighwaySegment(route.Extras, segmentIndex), accuracy)
 
-		log.Printf("Route deviation detected and saved for truck %s (ID: %d): %.2f meters from route", 
-			macID, truck.ID, distance)
+	sample := model.DeviationPathPoint{
+		Latitude:  latitude,
+		Longitude: longitude,
+		Distance:  distance,
+		Timestamp: timestamp,
+	}
 
-		// Get vehicle plate number for notification
-		plateNumber := truck.PlateNumber
-		if plateNumber == "" {
-			plateNumber = macID
+	if openEpisode != nil {
+		// Hysteresis: the truck is back only once it is within the exit threshold
+		if distance < exitThreshold {
+			return s.closeEpisode(truck, openEpisode, timestamp)
 		}
+		return s.extendEpisode(truck, routePlan, openEpisode, sample)
+	}
 
-		// After saving to the database, send notification
-		// Get driver name if userRepo is available
-		var driverName string
-		if s.userRepo != nil {
-			driver, err := s.userRepo.FindByID(driverID)
-			if err == nil && driver != nil {
-				driverName = driver.Name
-			}
-		}
-		
-		if driverName == "" {
-			driverName = fmt.Sprintf("Driver #%d", driverID)
-		}
+	if distance < enterThreshold {
+		s.clearCandidate(truck.ID)
+		return nil
+	}
+
+	// Wait for enough consecutive off-route samples before opening an episode
+	candidate := s.addCandidate(truck.ID, routePlan.ID, sample, referencePoint, segmentIndex)
+	if !cfg.confirms(candidate) {
+		return nil
+	}
+	s.clearCandidate(truck.ID)
 
-		// Create notification
-		if err := s.sendDeviationNotification(truck, routePlan, distance, driverID, plateNumber, driverName); err != nil {
-			log.Printf("Error sending route deviation notification: %v", err)
-			// Don't return the error here to avoid disrupting the main flow
+	first := candidate.Samples[0]
+	maxDistance := 0.0
+	for _, candidateSample := range candidate.Samples {
+		if candidateSample.Distance > maxDistance {
+			maxDistance = candidateSample.Distance
 		}
 	}
 
+	// Open a new deviation episode from the first off-route sample
+	deviation := &model.TruckRouteDeviation{
+		TruckID:      truck.ID,
+		MacID:        macID,
+		RoutePlanID:  routePlan.ID,
+		DriverID:     routePlan.DriverID,
+		Latitude:     first.Latitude,
+		Longitude:    first.Longitude,
+		RefLatitude:  candidate.Reference.Lat,
+		RefLongitude: candidate.Reference.Lng,
+		Distance:     first.Distance,
+		SegmentIndex: candidate.SegmentIndex,
+		Timestamp:    first.Timestamp,
+		EndTime:      timestamp,
+		IsOpen:       true,
+		MaxDistance:  maxDistance,
+		SampleCount:  len(candidate.Samples),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if err := deviation.SetPath(candidate.Samples); err != nil {
+		return err
+	}
+
+	if err := s.deviationRepo.Create(deviation); err != nil {
+		log.Printf("Error saving route deviation for truck %s: %v", macID, err)
+		return err
+	}
+
+	log.Printf("Route deviation episode %d opened for truck %s (ID: %d): %.2f meters from route",
+		deviation.ID, macID, truck.ID, maxDistance)
+
+	s.publishEpisode("route_deviation_started", deviation)
+
+	// Notify once per episode
+	plateNumber, driverName := s.describeTruck(truck, routePlan.DriverID)
+	if err := s.sendDeviationNotification(truck, routePlan, maxDistance, routePlan.DriverID, plateNumber, driverName); err != nil {
+		log.Printf("Error sending route deviation notification: %v", err)
+		// Don't return the error here to avoid disrupting the main flow
+	}
+
 	return nil
 }
 
-// sendDeviationNotification sends a push notification about the route deviation
-func (s *routeDeviationService) sendDeviationNotification(truck *model.Truck, routePlan *model.RoutePlan, distance float64, driverID uint, plateNumber, driverName string) error {
-	// Get notification service URL from environment
-	notificationServiceURL := os.Getenv("NOTIFICATION_SERVICE_URL")
-	if notificationServiceURL == "" {
-		// Default URL for container environment
-		notificationServiceURL = "http://getstok-notification:8081/api/v1/push/send"
+// extendEpisode adds an off-route sample to an open episode and escalates if it has lasted long enough
+func (s *routeDeviationService) extendEpisode(truck *model.Truck, routePlan *model.RoutePlan, deviation *model.TruckRouteDeviation, sample model.DeviationPathPoint) error {
+	path := deviation.GetPath()
+	if len(path) < maxDeviationPathPoints {
+		path = append(path, sample)
 	} else {
-		notificationServiceURL = fmt.Sprintf("%s/api/v1/push/send", notificationServiceURL)
+		// Keep the latest sample so the path still ends where the truck is
+		path[len(path)-1] = sample
+	}
+	if err := deviation.SetPath(path); err != nil {
+		return err
+	}
+
+	deviation.EndTime = sample.Timestamp
+	deviation.SampleCount++
+	if sample.Distance > deviation.MaxDistance {
+		deviation.MaxDistance = sample.Distance
+	}
+
+	escalate := deviation.EscalationLevel < len(s.escalations) &&
+		sample.Timestamp.Sub(deviation.Timestamp) >= s.escalations[deviation.EscalationLevel]
+	if escalate {
+		deviation.EscalationLevel++
+	}
+	deviation.UpdatedAt = time.Now()
+
+	if err := s.deviationRepo.Update(deviation); err != nil {
+		return err
+	}
+
+	if escalate {
+		log.Printf("Route deviation episode %d for truck %s escalated to level %d", deviation.ID, truck.MacID, deviation.EscalationLevel)
+		s.publishEpisode("route_deviation_escalated", deviation)
+
+		plateNumber, driverName := s.describeTruck(truck, routePlan.DriverID)
+		if err := s.sendEscalationNotification(truck, deviation, plateNumber, driverName); err != nil {
+			log.Printf("Error sending route deviation escalation: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// closeEpisode ends an open episode at the time the truck rejoined the route
+func (s *routeDeviationService) closeEpisode(truck *model.Truck, deviation *model.TruckRouteDeviation, timestamp time.Time) error {
+	deviation.IsOpen = false
+	deviation.EndTime = timestamp
+	deviation.UpdatedAt = time.Now()
+
+	if err := s.deviationRepo.Update(deviation); err != nil {
+		return err
+	}
+
+	log.Printf("Route deviation episode %d closed for truck %s after %s, max %.2f meters",
+		deviation.ID, truck.MacID, timestamp.Sub(deviation.Timestamp).Round(time.Second), deviation.MaxDistance)
+	s.publishEpisode("route_deviation_ended", deviation)
+	return nil
+}
+
+// describeTruck returns the plate number and driver name used in notifications
+func (s *routeDeviationService) describeTruck(truck *model.Truck, driverID uint) (string, string) {
+	plateNumber := truck.PlateNumber
+	if plateNumber == "" {
+		plateNumber = truck.MacID
+	}
+
+	var driverName string
+	if s.userRepo != nil {
+		driver, err := s.userRepo.FindByID(driverID)
+		if err == nil && driver != nil {
+			driverName = driver.Name
+		}
+	}
+	if driverName == "" {
+		driverName = fmt.Sprintf("Driver #%d", driverID)
+	}
+
+	return plateNumber, driverName
+}
+
+// publishEpisode sends a deviation episode update to alert, truck and route subscribers
+func (s *routeDeviationService) publishEpisode(eventType string, deviation *model.TruckRouteDeviation) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"type":      eventType,
+		"mac_id":    deviation.MacID,
+		"deviation": deviation.ToRouteDeviationResponse(),
+	})
+	if err != nil {
+		log.Printf("Error marshaling route deviation update: %v", err)
+		return
 	}
 
+	wsHub.Publish(jsonData, websocket.ChannelAlerts, websocket.TruckChannel(deviation.MacID), websocket.RouteChannel(deviation.RoutePlanID))
+}
+
+// sendEscalationNotification sends a push notification when a deviation episode keeps going
+func (s *routeDeviationService) sendEscalationNotification(truck *model.Truck, deviation *model.TruckRouteDeviation, plateNumber, driverName string) error {
+	minutes := int(deviation.EndTime.Sub(deviation.Timestamp).Minutes())
+	message := fmt.Sprintf("Vehicle %s operated by %s has been off its planned route for %d minutes, up to %.0f meters away.",
+		plateNumber, driverName, minutes, deviation.MaxDistance)
+
+	return postPushNotification(truck, "Route Deviation Escalation", message, deviation.DriverID)
+}
+
+// sendDeviationNotification sends a push notification about the route deviation
+func (s *routeDeviationService) sendDeviationNotification(truck *model.Truck, routePlan *model.RoutePlan, distance float64, driverID uint, plateNumber, driverName string) error {
 	// Prepare notification message
-	title := "Route Deviation Alert"
 	message := fmt.Sprintf("Vehicle %s operated by %s is deviating from its planned route by %.0f meters.", 
 		plateNumber, driverName, distance)
 
+	return postPushNotification(truck, "Route Deviation Alert", message, driverID)
+}
+
+// postPushNotification sends a push notification about a truck to management and the driver
+func postPushNotification(truck *model.Truck, title, message string, driverID uint) error {
 	// Optional: Add URL to redirect to when notification is clicked
 	// This could be the dashboard page with the active truck selected
 	url := fmt.Sprintf("/management/dashboard?truck=%s", truck.MacID)
@@ -181,6 +407,25 @@ func (s *routeDeviationService) sendDeviationNotification(truck *model.Truck, ro
 		TargetUserIDs: []uint{driverID},       // Also notify the driver
 	}
 
+	if err := sendNotificationRequest(notificationPayload); err != nil {
+		return err
+	}
+
+	log.Printf("%s notification sent for truck %s (Driver ID: %d)", title, truck.MacID, driverID)
+	return nil
+}
+
+// sendNotificationRequest posts a notification to the notification service
+func sendNotificationRequest(notificationPayload NotificationRequest) error {
+	// Get notification service URL from environment
+	notificationServiceURL := os.Getenv("NOTIFICATION_SERVICE_URL")
+	if notificationServiceURL == "" {
+		// Default URL for container environment
+		notificationServiceURL = "http://getstok-notification:8081/api/v1/push/send"
+	} else {
+		notificationServiceURL = fmt.Sprintf("%s/api/v1/push/send", notificationServiceURL)
+	}
+
 	// Convert to JSON
 	jsonData, err := json.Marshal(notificationPayload)
 	if err != nil {
@@ -198,7 +443,5 @@ func (s *routeDeviationService) sendDeviationNotification(truck *model.Truck, ro
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("notification service returned non-OK status: %d", resp.StatusCode)
 	}
-
-	log.Printf("Route deviation notification sent for truck %s (Driver ID: %d)", truck.MacID, driverID)
 	return nil
 }
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+	"gorm.io/gorm"
+)
+
+// routeGeometryCacheTTL bounds how long route plans edited on another replica take to apply
+const routeGeometryCacheTTL = time.Minute
+
+// RouteGeometryCache keeps the active route plan of each truck with its decoded and
+// spatially indexed geometry, so position updates do not decode the polyline every time
+type RouteGeometryCache struct {
+	routePlanRepo repository.RoutePlanRepository
+	routes        map[uint]*cachedRoute // Per truck ID
+	mutex         sync.RWMutex
+}
+
+// cachedRoute is the active route plan of a truck, nil Plan when the truck has none
+type cachedRoute struct {
+	Plan        *model.RoutePlan
+	Index       *utils.RouteIndex
+	Extras      *model.RouteExtras
+	Settings    *model.RouteDeviationSettings
+	LoadedAt    time.Time
+	lastSegment int // Segmen terakhir yang cocok, titik awal pencarian berikutnya
+	mutex       sync.Mutex
+}
+
+// NewRouteGeometryCache creates an empty route geometry cache
+func NewRouteGeometryCache(routePlanRepo repository.RoutePlanRepository) *RouteGeometryCache {
+	return &RouteGeometryCache{
+		routePlanRepo: routePlanRepo,
+		routes:        make(map[uint]*cachedRoute),
+	}
+}
+
+// activeRoute returns the cached active route plan of a truck, reloading it when stale.
+// The returned route has a nil Plan when the truck has no active route plan. Lookup errors
+// are returned without caching anything.
+func (c *RouteGeometryCache) activeRoute(truckID uint) (*cachedRoute, error) {
+	c.mutex.RLock()
+	cached := c.routes[truckID]
+	c.mutex.RUnlock()
+
+	if cached != nil && time.Since(cached.LoadedAt) < routeGeometryCacheTTL {
+		return cached, nil
+	}
+
+	route := &cachedRoute{LoadedAt: time.Now(), lastSegment: -1}
+	routePlan, err := c.routePlanRepo.FindActiveRoutePlansByTruckID(truckID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Truk tanpa rute aktif juga di-cache, Plan tetap nil
+	case err != nil:
+		// A failed lookup says nothing about the truck's route, try again on the next position
+		return nil, err
+	default:
+		// Reuse the index when only the TTL expired and the geometry is unchanged
+		if cached != nil && cached.Plan != nil && cached.Plan.ID == routePlan.ID && cached.Plan.RouteGeometry == routePlan.RouteGeometry {
+			route.Index = cached.Index
+			route.lastSegment = cached.segment()
+		} else {
+			points, err := utils.DecodeRouteGeometry(routePlan.RouteGeometry)
+			if err != nil {
+				log.Printf("Error decoding route geometry for route plan %d: %v", routePlan.ID, err)
+				return nil, err
+			}
+			route.Index = utils.NewRouteIndex(points)
+		}
+
+		route.Plan = routePlan
+		route.Extras, _ = routePlan.GetExtras()
+		route.Settings, err = routePlan.GetDeviationSettings()
+		if err != nil {
+			log.Printf("Invalid deviation settings for route plan %d, using global settings: %v", routePlan.ID, err)
+		}
+	}
+
+	c.mutex.Lock()
+	c.routes[truckID] = route
+	c.mutex.Unlock()
+	return route, nil
+}
+
+// InvalidateTruck makes the next position of a truck reload its active route plan
+func (c *RouteGeometryCache) InvalidateTruck(truckID uint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.routes, truckID)
+}
+
+// InvalidateRoutePlan drops every cached entry of a route plan
+func (c *RouteGeometryCache) InvalidateRoutePlan(routePlan *model.RoutePlan) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.routes, routePlan.TruckID)
+	for truckID, route := range c.routes {
+		if route.Plan != nil && route.Plan.ID == routePlan.ID {
+			delete(c.routes, truckID)
+		}
+	}
+}
+
+// Nearest finds the closest route segment to a point, starting near the last match
+func (r *cachedRoute) Nearest(point utils.LatLng) (float64, utils.LatLng, int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	distance, reference, segmentIndex := r.Index.Nearest(point, r.lastSegment)
+	r.lastSegment = segmentIndex
+	return distance, reference, segmentIndex
+}
+
+// segment returns the last matched segment index
+func (r *cachedRoute) segment() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.lastSegment
+}
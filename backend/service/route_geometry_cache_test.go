@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+	"gorm.io/gorm"
+)
+
+// fakeActiveRouteRepo answers FindActiveRoutePlansByTruckID and counts the lookups
+type fakeActiveRouteRepo struct {
+	repository.RoutePlanRepository
+	plan    *model.RoutePlan
+	err     error
+	lookups int
+}
+
+func (r *fakeActiveRouteRepo) FindActiveRoutePlansByTruckID(truckID uint) (*model.RoutePlan, error) {
+	r.lookups++
+	return r.plan, r.err
+}
+
+func TestRouteGeometryCacheActiveRoute(t *testing.T) {
+	geometry := utils.EncodeRouteGeometry([]utils.LatLng{{Lat: -6.2, Lng: 106.8}, {Lat: -6.21, Lng: 106.81}})
+
+	tests := []struct {
+		name        string
+		plan        *model.RoutePlan
+		err         error
+		wantPlan    bool
+		wantErr     bool
+		wantLookups int // Setelah dua kali activeRoute
+	}{
+		{"active route plan", &model.RoutePlan{ID: 7, TruckID: 1, RouteGeometry: geometry}, nil, true, false, 1},
+		{"no active route plan is cached", nil, gorm.ErrRecordNotFound, false, false, 1},
+		{"database error is not cached", nil, errors.New("connection refused"), false, true, 2},
+		{"invalid geometry is not cached", &model.RoutePlan{ID: 8, TruckID: 1, RouteGeometry: ""}, nil, false, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeActiveRouteRepo{plan: tt.plan, err: tt.err}
+			cache := NewRouteGeometryCache(repo)
+
+			for i := 0; i < 2; i++ {
+				route, err := cache.activeRoute(1)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatalf("expected an error, got route %+v", route)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if (route.Plan != nil) != tt.wantPlan {
+					t.Fatalf("plan = %v, want plan %v", route.Plan, tt.wantPlan)
+				}
+			}
+			if repo.lookups != tt.wantLookups {
+				t.Errorf("lookups = %d, want %d", repo.lookups, tt.wantLookups)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
+)
+
+const (
+	// RouteLifecycleWatchdogInterval is how often planned route plans are checked for a missed start
+	RouteLifecycleWatchdogInterval = 10 * time.Minute
+
+	// RouteNotStartedAfter is how long a route plan may stay planned before it is flagged
+	RouteNotStartedAfter = 24 * time.Hour
+
+	// routeCompleteDwell is how long the truck must stay at the last waypoint to complete the route
+	routeCompleteDwell = 5 * time.Minute
+
+	// routeCompleteMinProgress is the share of the route that must be driven before arriving at
+	// the last waypoint completes it, so a round trip does not complete at its start
+	routeCompleteMinProgress = 0.8
+
+	// plannedStartCacheTTL bounds how long new planned route plans take to be picked up
+	plannedStartCacheTTL = time.Minute
+
+	// routeEarlyStartTolerance is how long before its planned start a route plan may start automatically
+	routeEarlyStartTolerance = time.Hour
+)
+
+// RouteLifecycleService memulai dan menyelesaikan route plan secara otomatis berdasarkan telemetri
+type RouteLifecycleService interface {
+	ProcessPosition(macID string, latitude, longitude float64, timestamp time.Time) error
+	FlagUnstartedRoutePlans(notStartedAfter time.Duration) (int, error)
+	StartWatchdog(interval, notStartedAfter time.Duration)
+}
+
+type routeLifecycleService struct {
+	truckRepo     repository.TruckRepository
+	routePlanRepo repository.RoutePlanRepository
+	visitRepo     repository.WaypointVisitRepository
+	progressRepo  repository.RouteProgressRepository
+	routeCache    *RouteGeometryCache
+	statusUpdater *routePlanStatusUpdater
+	plannedStarts map[uint]*plannedStarts // Per truck ID
+	atStart       map[uint]uint           // Route plan yang titik awalnya sedang didatangi truk, per truck ID
+	mutex         sync.Mutex
+}
+
+// plannedStarts caches the first waypoint of the planned route plans of a truck
+type plannedStarts struct {
+	Starts   []plannedStart
+	LoadedAt time.Time
+}
+
+// plannedStart is the first waypoint of a planned route plan
+type plannedStart struct {
+	RoutePlanID    uint
+	Latitude       float64
+	Longitude      float64
+	PlannedStartAt *time.Time
+}
+
+// due reports whether the route plan may start at a time, which is any time when it has no planned start
+func (p plannedStart) due(at time.Time) bool {
+	return p.PlannedStartAt == nil || !at.Before(p.PlannedStartAt.Add(-routeEarlyStartTolerance))
+}
+
+// NewRouteLifecycleService creates a new instance of RouteLifecycleService
+func NewRouteLifecycleService(
+	truckRepo repository.TruckRepository,
+	routePlanRepo repository.RoutePlanRepository,
+	visitRepo repository.WaypointVisitRepository,
+	progressRepo repository.RouteProgressRepository,
+	historyRepo repository.RoutePlanStatusHistoryRepository,
+	routeCache *RouteGeometryCache,
+) RouteLifecycleService {
+	return &routeLifecycleService{
+		truckRepo:     truckRepo,
+		routePlanRepo: routePlanRepo,
+		visitRepo:     visitRepo,
+		progressRepo:  progressRepo,
+		routeCache:    routeCache,
+		statusUpdater: &routePlanStatusUpdater{
+			routePlanRepo: routePlanRepo,
+			historyRepo:   historyRepo,
+			routeCache:    routeCache,
+		},
+		plannedStarts: make(map[uint]*plannedStarts),
+		atStart:       make(map[uint]uint),
+	}
+}
+
+// ProcessPosition starts a planned route plan once the truck departs its first waypoint and
+// completes the active route plan once the truck has stayed at its last waypoint long enough.
+// It must run after the waypoint visit and route progress services for the same position.
+func (s *routeLifecycleService) ProcessPosition(macID string, latitude, longitude float64, timestamp time.Time) error {
+	truck, err := s.truckRepo.FindByMacID(macID)
+	if err != nil {
+		return err
+	}
+
+	route, err := s.routeCache.activeRoute(truck.ID)
+	if err != nil {
+		return err
+	}
+	if route.Plan != nil {
+		return s.checkCompletion(route.Plan)
+	}
+	return s.checkStart(truck, latitude, longitude, timestamp)
+}
+
+// checkStart activates the planned route plan whose first waypoint the truck has just left.
+// Route plans scheduled for later are skipped, so leaving the depot does not start tomorrow's route.
+func (s *routeLifecycleService) checkStart(truck *model.Truck, latitude, longitude float64, timestamp time.Time) error {
+	starts, err := s.startsFor(truck.ID)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, start := range starts {
+		if !start.due(timestamp) {
+			continue
+		}
+		distance := calculateDistance(latitude, longitude, start.Latitude, start.Longitude)
+		if distance <= WaypointArrivalRadius {
+			s.atStart[truck.ID] = start.RoutePlanID
+			return nil
+		}
+	}
+
+	routePlanID, ok := s.atStart[truck.ID]
+	if !ok {
+		return nil
+	}
+	for _, start := range starts {
+		if start.RoutePlanID != routePlanID {
+			continue
+		}
+		if calculateDistance(latitude, longitude, start.Latitude, start.Longitude) <= WaypointDepartureRadius {
+			// Still around the first waypoint
+			return nil
+		}
+
+		delete(s.atStart, truck.ID)
+		delete(s.plannedStarts, truck.ID)
+
+		routePlan, err := s.routePlanRepo.FindByID(routePlanID)
+		if err != nil {
+			return err
+		}
+		if routePlan.Status != model.RoutePlanStatusPlanned {
+			return nil
+		}
+		return s.statusUpdater.apply(routePlan, model.RoutePlanStatusActive, model.StatusChangeSourceAutomatic,
+			fmt.Sprintf("Truck %s departed the first waypoint", truck.MacID), nil)
+	}
+
+	// The route plan is no longer planned
+	delete(s.atStart, truck.ID)
+	return nil
+}
+
+// startsFor returns the first waypoints of the planned route plans of a truck, oldest plan first
+func (s *routeLifecycleService) startsFor(truckID uint) ([]plannedStart, error) {
+	s.mutex.Lock()
+	cached := s.plannedStarts[truckID]
+	s.mutex.Unlock()
+
+	if cached != nil && time.Since(cached.LoadedAt) < plannedStartCacheTTL {
+		return cached.Starts, nil
+	}
+
+	routePlans, err := s.routePlanRepo.FindPlannedRoutePlansByTruckID(truckID)
+	if err != nil {
+		return nil, err
+	}
+
+	starts := make([]plannedStart, 0, len(routePlans))
+	for _, routePlan := range routePlans {
+		waypoints, err := s.routePlanRepo.FindWaypointsByRoutePlanID(routePlan.ID)
+		if err != nil || len(waypoints) == 0 {
+			continue
+		}
+		starts = append(starts, plannedStart{
+			RoutePlanID:    routePlan.ID,
+			Latitude:       waypoints[0].Latitude,
+			Longitude:      waypoints[0].Longitude,
+			PlannedStartAt: routePlan.PlannedStartAt,
+		})
+	}
+
+	s.mutex.Lock()
+	s.plannedStarts[truckID] = &plannedStarts{Starts: starts, LoadedAt: time.Now()}
+	s.mutex.Unlock()
+	return starts, nil
+}
+
+// checkCompletion completes the route plan when the truck has dwelled at its last waypoint
+func (s *routeLifecycleService) checkCompletion(routePlan *model.RoutePlan) error {
+	visit, err := s.visitRepo.FindOpenByRoutePlanID(routePlan.ID)
+	if err != nil {
+		// Not at a waypoint
+		return nil
+	}
+	if time.Duration(visit.DwellSeconds)*time.Second < routeCompleteDwell {
+		return nil
+	}
+
+	waypoints, err := s.routePlanRepo.FindWaypointsByRoutePlanID(routePlan.ID)
+	if err != nil {
+		return err
+	}
+	if len(waypoints) < 2 || waypoints[len(waypoints)-1].ID != visit.WaypointID {
+		return nil
+	}
+
+	// Most of the route must have been driven
+	if progress, err := s.progressRepo.FindByRoutePlanID(routePlan.ID); err == nil && progress.TotalDistance > 0 {
+		if progress.DistanceTravelled/progress.TotalDistance < routeCompleteMinProgress {
+			return nil
+		}
+	}
+
+	return s.statusUpdater.apply(routePlan, model.RoutePlanStatusCompleted, model.StatusChangeSourceAutomatic,
+		fmt.Sprintf("Arrived at the last waypoint and stayed %d minutes", visit.DwellSeconds/60), nil)
+}
+
+// StartWatchdog periodically flags planned route plans that were never started
+func (s *routeLifecycleService) StartWatchdog(interval, notStartedAfter time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.FlagUnstartedRoutePlans(notStartedAfter); err != nil {
+				log.Printf("Error flagging unstarted route plans: %v", err)
+			}
+		}
+	}()
+}
+
+// FlagUnstartedRoutePlans flags route plans that are still planned notStartedAfter after their
+// planned start, or after they were created when they have none, and alerts management and the
+// driver once per route plan
+func (s *routeLifecycleService) FlagUnstartedRoutePlans(notStartedAfter time.Duration) (int, error) {
+	dueBefore := time.Now().Add(-notStartedAfter)
+	routePlans, err := s.routePlanRepo.FindUnstartedRoutePlans(dueBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	flagged := 0
+	for _, routePlan := range routePlans {
+		if !dueAt(routePlan).Before(dueBefore) {
+			continue
+		}
+		now := time.Now()
+		ok, err := s.routePlanRepo.FlagNotStarted(routePlan.ID, now)
+		if err != nil {
+			log.Printf("Failed to flag unstarted route plan %d: %v", routePlan.ID, err)
+			continue
+		}
+		if !ok {
+			// Started, cancelled or flagged since it was loaded
+			continue
+		}
+		routePlan.NotStartedFlaggedAt = &now
+		flagged++
+		log.Printf("Route plan %d has not been started since %s", routePlan.ID, dueAt(routePlan).Format(time.RFC3339))
+
+		s.publishNotStarted(routePlan)
+
+		truck, err := s.truckRepo.FindByID(routePlan.TruckID)
+		if err != nil {
+			continue
+		}
+		plateNumber := truck.PlateNumber
+		if plateNumber == "" {
+			plateNumber = truck.MacID
+		}
+		event := "was created"
+		if routePlan.PlannedStartAt != nil {
+			event = "was planned to start"
+		}
+		message := fmt.Sprintf("Route plan #%d for vehicle %s %s %s ago and has not been started.",
+			routePlan.ID, plateNumber, event, time.Since(dueAt(routePlan)).Round(time.Hour))
+		if err := postPushNotification(truck, "Route Not Started", message, routePlan.DriverID); err != nil {
+			log.Printf("Error sending route not started notification: %v", err)
+		}
+	}
+
+	return flagged, nil
+}
+
+// dueAt returns when a route plan was due to start, its creation time when it has no planned start
+func dueAt(routePlan *model.RoutePlan) time.Time {
+	if routePlan.PlannedStartAt != nil {
+		return *routePlan.PlannedStartAt
+	}
+	return routePlan.CreatedAt
+}
+
+// publishNotStarted sends a not started alert to the alert and route subscribers
+func (s *routeLifecycleService) publishNotStarted(routePlan *model.RoutePlan) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"type":             "route_plan_not_started",
+		"route_plan_id":    routePlan.ID,
+		"truck_id":         routePlan.TruckID,
+		"driver_id":        routePlan.DriverID,
+		"created_at":       routePlan.CreatedAt,
+		"planned_start_at": routePlan.PlannedStartAt,
+		"flagged_at":       routePlan.NotStartedFlaggedAt,
+	})
+	if err != nil {
+		log.Printf("Error marshaling route not started alert: %v", err)
+		return
+	}
+
+	wsHub.Publish(jsonData, websocket.ChannelAlerts, websocket.RouteChannel(routePlan.ID))
+}
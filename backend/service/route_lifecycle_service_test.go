@@ -0,0 +1,226 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+)
+
+// fakeUnstartedRepo returns fixed unstarted route plans and flags only the IDs in flaggable
+type fakeUnstartedRepo struct {
+	repository.RoutePlanRepository
+	plans     []*model.RoutePlan
+	flaggable map[uint]bool
+	flagErr   error
+	flagged   []uint
+}
+
+// FindUnstartedRoutePlans returns every plan, the service must skip plans that are not due yet
+func (r *fakeUnstartedRepo) FindUnstartedRoutePlans(dueBefore time.Time) ([]*model.RoutePlan, error) {
+	return r.plans, nil
+}
+
+func (r *fakeUnstartedRepo) FlagNotStarted(id uint, flaggedAt time.Time) (bool, error) {
+	if r.flagErr != nil {
+		return false, r.flagErr
+	}
+	if !r.flaggable[id] {
+		return false, nil
+	}
+	r.flagged = append(r.flagged, id)
+	return true, nil
+}
+
+// fakeMissingTruckRepo finds no trucks, so no push notification is sent
+type fakeMissingTruckRepo struct {
+	repository.TruckRepository
+}
+
+func (r *fakeMissingTruckRepo) FindByID(id uint) (*model.Truck, error) {
+	return nil, repository.ErrNotFound
+}
+
+func TestFlagUnstartedRoutePlans(t *testing.T) {
+	created := time.Now().Add(-48 * time.Hour)
+	plans := func() []*model.RoutePlan {
+		return []*model.RoutePlan{
+			{ID: 1, TruckID: 1, DriverID: 2, Status: model.RoutePlanStatusPlanned, CreatedAt: created},
+			{ID: 2, TruckID: 1, DriverID: 2, Status: model.RoutePlanStatusPlanned, CreatedAt: created},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		flaggable   map[uint]bool
+		flagErr     error
+		wantFlagged int
+	}{
+		{"both still planned", map[uint]bool{1: true, 2: true}, nil, 2},
+		{"one started meanwhile", map[uint]bool{2: true}, nil, 1},
+		{"none planned anymore", map[uint]bool{}, nil, 0},
+		{"database error", map[uint]bool{1: true, 2: true}, errors.New("connection refused"), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeUnstartedRepo{plans: plans(), flaggable: tt.flaggable, flagErr: tt.flagErr}
+			svc := &routeLifecycleService{truckRepo: &fakeMissingTruckRepo{}, routePlanRepo: repo}
+
+			flagged, err := svc.FlagUnstartedRoutePlans(RouteNotStartedAfter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if flagged != tt.wantFlagged || len(repo.flagged) != tt.wantFlagged {
+				t.Errorf("flagged = %d (repo %v), want %d", flagged, repo.flagged, tt.wantFlagged)
+			}
+			for _, plan := range repo.plans {
+				want := tt.flaggable[plan.ID] && tt.flagErr == nil
+				if (plan.NotStartedFlaggedAt != nil) != want {
+					t.Errorf("route plan %d flagged at %v, want flagged %v", plan.ID, plan.NotStartedFlaggedAt, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFlagUnstartedRoutePlansUsesPlannedStart(t *testing.T) {
+	now := time.Now()
+	at := func(offset time.Duration) *time.Time {
+		value := now.Add(offset)
+		return &value
+	}
+
+	tests := []struct {
+		name           string
+		createdAt      time.Time
+		plannedStartAt *time.Time
+		wantFlagged    bool
+	}{
+		{"created long ago without planned start", now.Add(-48 * time.Hour), nil, true},
+		{"created recently without planned start", now.Add(-time.Hour), nil, false},
+		{"created long ago, scheduled in the future", now.Add(-48 * time.Hour), at(72 * time.Hour), false},
+		{"created long ago, planned start missed recently", now.Add(-48 * time.Hour), at(-time.Hour), false},
+		{"planned start missed long ago", now.Add(-72 * time.Hour), at(-30 * time.Hour), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &model.RoutePlan{ID: 1, Status: model.RoutePlanStatusPlanned, CreatedAt: tt.createdAt, PlannedStartAt: tt.plannedStartAt}
+			repo := &fakeUnstartedRepo{plans: []*model.RoutePlan{plan}, flaggable: map[uint]bool{1: true}}
+			svc := &routeLifecycleService{truckRepo: &fakeMissingTruckRepo{}, routePlanRepo: repo}
+
+			flagged, err := svc.FlagUnstartedRoutePlans(RouteNotStartedAfter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (flagged == 1) != tt.wantFlagged {
+				t.Errorf("flagged = %d, want flagged %v", flagged, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+// fakePlannedStartRepo serves planned route plans that all start at the same depot
+type fakePlannedStartRepo struct {
+	repository.RoutePlanRepository
+	plans   []*model.RoutePlan
+	depot   model.RouteWaypoint
+	updated []uint
+}
+
+func (r *fakePlannedStartRepo) FindPlannedRoutePlansByTruckID(truckID uint) ([]*model.RoutePlan, error) {
+	return r.plans, nil
+}
+
+func (r *fakePlannedStartRepo) FindWaypointsByRoutePlanID(routePlanID uint) ([]*model.RouteWaypoint, error) {
+	depot := r.depot
+	return []*model.RouteWaypoint{&depot}, nil
+}
+
+func (r *fakePlannedStartRepo) FindByID(id uint) (*model.RoutePlan, error) {
+	for _, plan := range r.plans {
+		if plan.ID == id {
+			return plan, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakePlannedStartRepo) FindActiveByTruckOrDriver(truckID, driverID, excludeID uint) ([]*model.RoutePlan, error) {
+	return nil, nil
+}
+
+func (r *fakePlannedStartRepo) Update(routePlan *model.RoutePlan) error {
+	r.updated = append(r.updated, routePlan.ID)
+	return nil
+}
+
+type fakeStatusHistoryRepo struct {
+	repository.RoutePlanStatusHistoryRepository
+}
+
+func (r *fakeStatusHistoryRepo) Create(history *model.RoutePlanStatusHistory) error {
+	return nil
+}
+
+func TestCheckStartSkipsRoutePlansScheduledForLater(t *testing.T) {
+	now := time.Now()
+	at := func(offset time.Duration) *time.Time {
+		value := now.Add(offset)
+		return &value
+	}
+
+	tests := []struct {
+		name       string
+		plans      []*model.RoutePlan
+		wantActive uint // 0 = none
+	}{
+		{"no planned start", []*model.RoutePlan{{ID: 1}}, 1},
+		{"planned start soon", []*model.RoutePlan{{ID: 1, PlannedStartAt: at(30 * time.Minute)}}, 1},
+		{"planned start passed", []*model.RoutePlan{{ID: 1, PlannedStartAt: at(-2 * time.Hour)}}, 1},
+		{"only tomorrow's route plan", []*model.RoutePlan{{ID: 1, PlannedStartAt: at(24 * time.Hour)}}, 0},
+		{"tomorrow's route plan is older", []*model.RoutePlan{
+			{ID: 1, PlannedStartAt: at(24 * time.Hour)},
+			{ID: 2, PlannedStartAt: at(10 * time.Minute)},
+		}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, plan := range tt.plans {
+				plan.TruckID, plan.DriverID, plan.Status = 1, 2, model.RoutePlanStatusPlanned
+			}
+			repo := &fakePlannedStartRepo{plans: tt.plans, depot: model.RouteWaypoint{Latitude: -6.2, Longitude: 106.8}}
+			cache := NewRouteGeometryCache(repo)
+			svc := &routeLifecycleService{
+				routePlanRepo: repo,
+				routeCache:    cache,
+				statusUpdater: &routePlanStatusUpdater{routePlanRepo: repo, historyRepo: &fakeStatusHistoryRepo{}, routeCache: cache},
+				plannedStarts: make(map[uint]*plannedStarts),
+				atStart:       make(map[uint]uint),
+			}
+			truck := &model.Truck{ID: 1, MacID: "truck-1"}
+
+			// At the depot, then about a kilometre away
+			if err := svc.checkStart(truck, -6.2, 106.8, now); err != nil {
+				t.Fatalf("unexpected error at the depot: %v", err)
+			}
+			if err := svc.checkStart(truck, -6.21, 106.8, now.Add(5*time.Minute)); err != nil {
+				t.Fatalf("unexpected error after departing: %v", err)
+			}
+
+			var active uint
+			for _, plan := range tt.plans {
+				if plan.Status == model.RoutePlanStatusActive {
+					active = plan.ID
+				}
+			}
+			if active != tt.wantActive {
+				t.Errorf("active route plan = %d, want %d", active, tt.wantActive)
+			}
+		})
+	}
+}
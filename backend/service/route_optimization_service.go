@@ -0,0 +1,423 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+// Batas waktu panggilan ke ORS optimization API
+const orsOptimizationTimeout = 30 * time.Second
+
+// RouteOptimizationService optimizes multi-stop routes over several trucks
+type RouteOptimizationService interface {
+	OptimizeRoutes(req model.RouteOptimizationRequest, plannerID uint) (*model.RouteOptimizationResponse, error)
+}
+
+type routeOptimizationService struct {
+	routePlanService RoutePlanService
+	routingService   RoutingService // Menghitung geometri route plan yang dibuat, boleh nil
+	config           RoutingConfig  // URL, API key dan profile ORS
+	httpClient       *http.Client
+}
+
+// NewRouteOptimizationService creates a new route optimization service. Without a routing service
+// the routes are only suggested; create_plans is rejected because no geometry can be computed.
+func NewRouteOptimizationService(routePlanService RoutePlanService, routingService RoutingService, config RoutingConfig) RouteOptimizationService {
+	return &routeOptimizationService{
+		routePlanService: routePlanService,
+		routingService:   routingService,
+		config:           config,
+		httpClient:       &http.Client{Timeout: orsOptimizationTimeout},
+	}
+}
+
+// OptimizeRoutes assigns the stops to the trucks and orders them. Each optimized route comes with a
+// route plan request the planner can submit as is, or that is created right away when CreatePlans is set.
+func (s *routeOptimizationService) OptimizeRoutes(req model.RouteOptimizationRequest, plannerID uint) (*model.RouteOptimizationResponse, error) {
+	if err := validateOptimizationRequest(&req); err != nil {
+		return nil, err
+	}
+	// Route plan tanpa geometri tidak bisa dipantau, jadi jangan buat plan jika rute tidak bisa dihitung
+	if req.CreatePlans && s.routingService == nil {
+		return nil, errors.New("create_plans requires server-side route computation, routing service is not available")
+	}
+
+	startAt := time.Now()
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+	problem := newVRPProblem(req, startAt)
+
+	solver := req.Solver
+	if solver == "" {
+		solver = model.OptimizationSolverAuto
+	}
+
+	var solution *vrpSolution
+	if solver != model.OptimizationSolverHeuristic && s.config.ORSAPIKey != "" {
+		var err error
+		solution, err = s.solveORS(problem)
+		if err != nil {
+			if solver == model.OptimizationSolverORS {
+				return nil, err
+			}
+			log.Printf("ORS optimization failed, using heuristic solver: %v", err)
+			solution = nil
+		} else {
+			solver = model.OptimizationSolverORS
+		}
+	} else if solver == model.OptimizationSolverORS {
+		return nil, errors.New("API key tidak dikonfigurasi")
+	}
+	if solution == nil {
+		solution = problem.solveHeuristic()
+		solver = model.OptimizationSolverHeuristic
+	}
+
+	response := &model.RouteOptimizationResponse{
+		Solver:     solver,
+		Routes:     []model.OptimizedRoute{},
+		Unassigned: []model.UnassignedStop{},
+	}
+
+	for v, route := range solution.routes {
+		if len(route.stops) == 0 {
+			continue
+		}
+		optimized := buildOptimizedRoute(problem, req, v, route)
+
+		if req.CreatePlans {
+			plan, err := s.routePlanService.CreateRoutePlan(*optimized.RoutePlanRequest, plannerID)
+			if err != nil {
+				optimized.CreateError = err.Error()
+			} else {
+				optimized.RoutePlan = plan
+			}
+		}
+
+		response.TotalDistanceMeters += optimized.DistanceMeters
+		response.TotalDurationSeconds += optimized.DurationSeconds
+		response.Routes = append(response.Routes, optimized)
+	}
+
+	for i := range req.Stops {
+		if reason, ok := solution.unassigned[i]; ok {
+			response.Unassigned = append(response.Unassigned, model.UnassignedStop{
+				StopID: req.Stops[i].ID,
+				Reason: reason,
+			})
+		}
+	}
+
+	return response, nil
+}
+
+// validateOptimizationRequest checks the request and fills in missing stop IDs
+func validateOptimizationRequest(req *model.RouteOptimizationRequest) error {
+	if len(req.Stops) == 0 {
+		return errors.New("at least one stop is required")
+	}
+	if len(req.Stops) > MaxOptimizationStops {
+		return fmt.Errorf("at most %d stops can be optimized at once", MaxOptimizationStops)
+	}
+	if len(req.Vehicles) == 0 {
+		return errors.New("at least one vehicle is required")
+	}
+
+	switch req.Solver {
+	case "", model.OptimizationSolverAuto, model.OptimizationSolverORS, model.OptimizationSolverHeuristic:
+	default:
+		return fmt.Errorf("unknown solver %q", req.Solver)
+	}
+
+	if !validCoordinate(req.Depot.Latitude, req.Depot.Longitude) {
+		return errors.New("invalid depot coordinates")
+	}
+
+	for i := range req.Stops {
+		stop := &req.Stops[i]
+		if stop.ID == "" {
+			stop.ID = strconv.Itoa(i + 1)
+		}
+		if !validCoordinate(stop.Latitude, stop.Longitude) {
+			return fmt.Errorf("stop %s: invalid coordinates", stop.ID)
+		}
+		if stop.Demand < 0 || stop.ServiceMinutes < 0 {
+			return fmt.Errorf("stop %s: demand and service_minutes must not be negative", stop.ID)
+		}
+		if stop.EarliestArrival != nil && stop.LatestArrival != nil && stop.LatestArrival.Before(*stop.EarliestArrival) {
+			return fmt.Errorf("stop %s: latest_arrival must not be before earliest_arrival", stop.ID)
+		}
+	}
+
+	for i, vehicle := range req.Vehicles {
+		if vehicle.VehiclePlate == "" || vehicle.DriverName == "" {
+			return fmt.Errorf("vehicle %d: vehicle plate and driver name are required", i)
+		}
+		if vehicle.Capacity < 0 {
+			return fmt.Errorf("vehicle %d: capacity must not be negative", i)
+		}
+	}
+
+	return nil
+}
+
+// validCoordinate reports whether a latitude and longitude are in range
+func validCoordinate(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180 && (lat != 0 || lng != 0)
+}
+
+// buildOptimizedRoute converts the route of one vehicle into the response and a route plan request.
+// The route plan request carries no geometry: CreateRoutePlan computes it through the routing service,
+// avoiding the approved avoidance areas, so the plan is stored with the road geometry of its waypoints.
+func buildOptimizedRoute(p *vrpProblem, req model.RouteOptimizationRequest, vehicle int, route vrpRoute) model.OptimizedRoute {
+	optimized := model.OptimizedRoute{
+		VehiclePlate:    p.vehicles[vehicle].VehiclePlate,
+		DriverName:      p.vehicles[vehicle].DriverName,
+		Load:            route.load,
+		Capacity:        p.vehicles[vehicle].Capacity,
+		DistanceMeters:  route.distance,
+		DurationSeconds: route.duration,
+		Stops:           make([]model.OptimizedStop, 0, len(route.stops)),
+	}
+
+	depot := model.WaypointRequest{
+		Latitude:  req.Depot.Latitude,
+		Longitude: req.Depot.Longitude,
+		Address:   req.Depot.Address,
+	}
+	waypoints := []model.WaypointRequest{depot}
+
+	for i, index := range route.stops {
+		stop := p.stops[index]
+		optimized.Stops = append(optimized.Stops, model.OptimizedStop{
+			StopID:           stop.ID,
+			Latitude:         stop.Latitude,
+			Longitude:        stop.Longitude,
+			Address:          stop.Address,
+			Demand:           stop.Demand,
+			EstimatedArrival: route.arrivals[i],
+			EarliestArrival:  stop.EarliestArrival,
+			LatestArrival:    stop.LatestArrival,
+		})
+		waypoints = append(waypoints, model.WaypointRequest{
+			Latitude:        stop.Latitude,
+			Longitude:       stop.Longitude,
+			Address:         stop.Address,
+			EarliestArrival: stop.EarliestArrival,
+			LatestArrival:   stop.LatestArrival,
+		})
+	}
+	if p.returnToDepot {
+		waypoints = append(waypoints, depot)
+	}
+
+	plannedStart := p.startAt
+	plannedEnd := plannedStart.Add(time.Duration(route.duration * float64(time.Second)))
+	if !plannedEnd.After(plannedStart) {
+		plannedEnd = plannedStart.Add(time.Minute)
+	}
+
+	optimized.RoutePlanRequest = &model.RoutePlanCreateRequest{
+		DriverName:     optimized.DriverName,
+		VehiclePlate:   optimized.VehiclePlate,
+		Waypoints:      waypoints,
+		PlannedStartAt: &plannedStart,
+		PlannedEndAt:   &plannedEnd,
+	}
+	return optimized
+}
+
+// orsOptimizationRequest is the VROOM problem sent to the ORS optimization API
+type orsOptimizationRequest struct {
+	Jobs     []orsJob     `json:"jobs"`
+	Vehicles []orsVehicle `json:"vehicles"`
+}
+
+type orsJob struct {
+	ID          int       `json:"id"`
+	Location    []float64 `json:"location"` // [lng, lat]
+	Service     int       `json:"service,omitempty"`
+	Delivery    []int     `json:"delivery,omitempty"`
+	TimeWindows [][]int64 `json:"time_windows,omitempty"`
+}
+
+type orsVehicle struct {
+	ID         int       `json:"id"`
+	Profile    string    `json:"profile"`
+	Start      []float64 `json:"start"`
+	End        []float64 `json:"end,omitempty"`
+	Capacity   []int     `json:"capacity,omitempty"`
+	TimeWindow []int64   `json:"time_window"`
+}
+
+type orsOptimizationResponse struct {
+	Error  string `json:"error"`
+	Routes []struct {
+		Vehicle  int     `json:"vehicle"`
+		Distance float64 `json:"distance"`
+		Steps    []struct {
+			Type    string `json:"type"`
+			Job     int    `json:"job"`
+			Arrival int64  `json:"arrival"`
+			Service int64  `json:"service"`
+		} `json:"steps"`
+	} `json:"routes"`
+	Unassigned []struct {
+		ID int `json:"id"`
+	} `json:"unassigned"`
+}
+
+// solveORS solves the problem with the ORS (VROOM) optimization API. Job IDs are stop index + 1
+// and vehicle IDs are vehicle index + 1. Times are unix seconds.
+func (s *routeOptimizationService) solveORS(p *vrpProblem) (*vrpSolution, error) {
+	start := p.startAt.Unix()
+	horizon := p.startAt.AddDate(0, 0, 30).Unix()
+
+	// VROOM needs the same amount dimensions on every job and vehicle
+	useCapacity := false
+	totalDemand := 0
+	for _, vehicle := range p.vehicles {
+		if vehicle.Capacity > 0 {
+			useCapacity = true
+		}
+	}
+	for _, stop := range p.stops {
+		totalDemand += stop.Demand
+	}
+
+	problem := orsOptimizationRequest{}
+	for i, stop := range p.stops {
+		job := orsJob{
+			ID:       i + 1,
+			Location: []float64{stop.Longitude, stop.Latitude},
+			Service:  int(stop.ServiceMinutes * 60),
+		}
+		if useCapacity {
+			job.Delivery = []int{stop.Demand}
+		}
+		if stop.EarliestArrival != nil || stop.LatestArrival != nil {
+			window := []int64{0, horizon}
+			if stop.EarliestArrival != nil {
+				window[0] = stop.EarliestArrival.Unix()
+			}
+			if stop.LatestArrival != nil {
+				window[1] = stop.LatestArrival.Unix()
+			}
+			job.TimeWindows = [][]int64{window}
+		}
+		problem.Jobs = append(problem.Jobs, job)
+	}
+
+	depot := []float64{p.depot.Lng, p.depot.Lat}
+	for i, vehicle := range p.vehicles {
+		orsVehicle := orsVehicle{
+			ID:         i + 1,
+			Profile:    s.config.ORSProfile,
+			Start:      depot,
+			TimeWindow: []int64{start, horizon},
+		}
+		if p.returnToDepot {
+			orsVehicle.End = depot
+		}
+		if useCapacity {
+			capacity := vehicle.Capacity
+			if capacity == 0 {
+				capacity = totalDemand
+			}
+			orsVehicle.Capacity = []int{capacity}
+		}
+		problem.Vehicles = append(problem.Vehicles, orsVehicle)
+	}
+
+	requestBody, err := json.Marshal(problem)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", strings.TrimRight(s.config.ORSURL, "/")+"/optimization", bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, errors.New("Gagal membuat request: " + err.Error())
+	}
+	req.Header.Set("Authorization", s.config.ORSAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.New("Gagal mengirim request: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.New("Gagal membaca response: " + err.Error())
+	}
+
+	var result orsOptimizationResponse
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("invalid ORS optimization response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ORS optimization API returned %d: %s", resp.StatusCode, result.Error)
+	}
+
+	solution := &vrpSolution{
+		routes:     make([]vrpRoute, len(p.vehicles)),
+		unassigned: make(map[int]string),
+	}
+	for _, route := range result.Routes {
+		vehicle := route.Vehicle - 1
+		if vehicle < 0 || vehicle >= len(p.vehicles) {
+			return nil, fmt.Errorf("ORS optimization returned unknown vehicle %d", route.Vehicle)
+		}
+
+		var stops []int
+		var arrivals []time.Time
+		end := start
+		for _, step := range route.Steps {
+			if step.Arrival+step.Service > end {
+				end = step.Arrival + step.Service
+			}
+			if step.Type != "job" {
+				continue
+			}
+			if step.Job < 1 || step.Job > len(p.stops) {
+				return nil, fmt.Errorf("ORS optimization returned unknown job %d", step.Job)
+			}
+			stops = append(stops, step.Job-1)
+			arrivals = append(arrivals, time.Unix(step.Arrival, 0))
+		}
+
+		// Jarak hanya dikirim ORS jika geometri diminta, pakai perkiraan sendiri
+		estimate, _, _ := p.evaluate(vehicle, stops)
+		distance := route.Distance
+		if distance == 0 {
+			distance = estimate.distance
+		}
+
+		solution.routes[vehicle] = vrpRoute{
+			stops:    stops,
+			arrivals: arrivals,
+			load:     estimate.load,
+			distance: distance,
+			duration: float64(end - start),
+		}
+	}
+	for _, job := range result.Unassigned {
+		if job.ID >= 1 && job.ID <= len(p.stops) {
+			solution.unassigned[job.ID-1] = "not assigned by the optimizer, check capacity and time windows"
+		}
+	}
+
+	return solution, nil
+}
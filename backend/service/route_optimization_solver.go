@@ -0,0 +1,295 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+// Perkiraan perjalanan yang dipakai solver heuristik, tanpa data jalan sebenarnya
+const (
+	OptimizationAverageSpeed = 40.0 / 3.6 // m/s, rata-rata kecepatan truk di kota
+	OptimizationRoadFactor   = 1.3        // Jarak jalan dibanding jarak garis lurus
+	MaxOptimizationStops     = 200        // Batas stop per permintaan optimasi
+	maxImprovementPasses     = 50
+)
+
+// vrpProblem is a multi-stop routing problem. Node 0 is the depot and node i+1 is stop i.
+type vrpProblem struct {
+	depot         utils.LatLng
+	stops         []model.OptimizationStop
+	vehicles      []model.OptimizationVehicle
+	startAt       time.Time
+	returnToDepot bool
+	distances     [][]float64
+}
+
+// vrpRoute is the stop sequence of one vehicle with its estimated schedule
+type vrpRoute struct {
+	stops    []int // Indeks stop, tanpa depot
+	arrivals []time.Time
+	load     int
+	distance float64 // meter
+	duration float64 // detik, dari berangkat sampai stop terakhir atau kembali ke depot
+}
+
+// vrpSolution assigns stops to vehicles. routes has one entry per vehicle.
+type vrpSolution struct {
+	routes     []vrpRoute
+	unassigned map[int]string // Indeks stop -> alasan
+}
+
+// newVRPProblem builds a problem and its distance matrix
+func newVRPProblem(req model.RouteOptimizationRequest, startAt time.Time) *vrpProblem {
+	p := &vrpProblem{
+		depot:         utils.LatLng{Lat: req.Depot.Latitude, Lng: req.Depot.Longitude},
+		stops:         req.Stops,
+		vehicles:      req.Vehicles,
+		startAt:       startAt,
+		returnToDepot: req.ReturnToDepot,
+	}
+
+	nodes := make([]utils.LatLng, len(req.Stops)+1)
+	nodes[0] = p.depot
+	for i, stop := range req.Stops {
+		nodes[i+1] = utils.LatLng{Lat: stop.Latitude, Lng: stop.Longitude}
+	}
+
+	p.distances = make([][]float64, len(nodes))
+	for i := range nodes {
+		p.distances[i] = make([]float64, len(nodes))
+		for j := range nodes {
+			if i != j {
+				p.distances[i][j] = utils.CalculateHaversineDistance(nodes[i], nodes[j]) * OptimizationRoadFactor
+			}
+		}
+	}
+	return p
+}
+
+// evaluate simulates a vehicle driving a stop sequence. It returns false with a reason when the
+// sequence exceeds the vehicle capacity or misses a time window.
+func (p *vrpProblem) evaluate(vehicle int, stops []int) (vrpRoute, bool, string) {
+	route := vrpRoute{stops: stops, arrivals: make([]time.Time, len(stops))}
+
+	for _, stop := range stops {
+		route.load += p.stops[stop].Demand
+	}
+	if capacity := p.vehicles[vehicle].Capacity; capacity > 0 && route.load > capacity {
+		return route, false, "not enough truck capacity left"
+	}
+
+	now := p.startAt
+	node := 0
+	for i, stop := range stops {
+		distance := p.distances[node][stop+1]
+		route.distance += distance
+		now = now.Add(time.Duration(distance / OptimizationAverageSpeed * float64(time.Second)))
+
+		window := p.stops[stop]
+		if window.EarliestArrival != nil && now.Before(*window.EarliestArrival) {
+			// Truk menunggu sampai jendela waktu dibuka
+			now = *window.EarliestArrival
+		}
+		if window.LatestArrival != nil && now.After(*window.LatestArrival) {
+			return route, false, "no truck can reach the stop within its time window"
+		}
+
+		route.arrivals[i] = now
+		now = now.Add(time.Duration(window.ServiceMinutes * float64(time.Minute)))
+		node = stop + 1
+	}
+
+	if p.returnToDepot && node != 0 {
+		distance := p.distances[node][0]
+		route.distance += distance
+		now = now.Add(time.Duration(distance / OptimizationAverageSpeed * float64(time.Second)))
+	}
+
+	route.duration = now.Sub(p.startAt).Seconds()
+	return route, true, ""
+}
+
+// solveHeuristic assigns stops with cheapest insertion, then improves the routes with
+// 2-opt inside each route and by relocating stops between routes
+func (p *vrpProblem) solveHeuristic() *vrpSolution {
+	solution := &vrpSolution{
+		routes:     make([]vrpRoute, len(p.vehicles)),
+		unassigned: make(map[int]string),
+	}
+	for v := range p.vehicles {
+		solution.routes[v], _, _ = p.evaluate(v, nil)
+	}
+
+	for _, stop := range p.insertionOrder() {
+		bestVehicle, bestCost := -1, 0.0
+		var bestRoute vrpRoute
+		reason := "demand exceeds the capacity of every truck"
+
+		for v := range p.vehicles {
+			current := solution.routes[v]
+			for pos := 0; pos <= len(current.stops); pos++ {
+				candidate, ok, why := p.evaluate(v, insertStop(current.stops, pos, stop))
+				if !ok {
+					if capacity := p.vehicles[v].Capacity; capacity == 0 || p.stops[stop].Demand <= capacity {
+						reason = why
+					}
+					continue
+				}
+				if cost := candidate.distance - current.distance; bestVehicle < 0 || cost < bestCost {
+					bestVehicle, bestCost, bestRoute = v, cost, candidate
+				}
+			}
+		}
+
+		if bestVehicle < 0 {
+			solution.unassigned[stop] = reason
+			continue
+		}
+		solution.routes[bestVehicle] = bestRoute
+	}
+
+	for pass := 0; pass < maxImprovementPasses; pass++ {
+		improved := false
+		for v := range solution.routes {
+			if p.twoOpt(solution, v) {
+				improved = true
+			}
+		}
+		if p.relocate(solution) {
+			improved = true
+		}
+		if !improved {
+			break
+		}
+	}
+
+	return solution
+}
+
+// insertionOrder returns the stops with the tightest deadline first, then the stops
+// farthest from the depot, which are the hardest to fit in later
+func (p *vrpProblem) insertionOrder() []int {
+	order := make([]int, len(p.stops))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		stopA, stopB := p.stops[order[a]], p.stops[order[b]]
+		switch {
+		case stopA.LatestArrival != nil && stopB.LatestArrival != nil:
+			if !stopA.LatestArrival.Equal(*stopB.LatestArrival) {
+				return stopA.LatestArrival.Before(*stopB.LatestArrival)
+			}
+		case stopA.LatestArrival != nil:
+			return true
+		case stopB.LatestArrival != nil:
+			return false
+		}
+		return p.distances[0][order[a]+1] > p.distances[0][order[b]+1]
+	})
+	return order
+}
+
+// twoOpt reverses segments of a route while that shortens it and keeps it feasible
+func (p *vrpProblem) twoOpt(solution *vrpSolution, vehicle int) bool {
+	improved := false
+	current := solution.routes[vehicle]
+
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(current.stops)-1; i++ {
+			for j := i + 1; j < len(current.stops); j++ {
+				candidate, ok, _ := p.evaluate(vehicle, reverseStops(current.stops, i, j))
+				if ok && candidate.distance < current.distance-1 {
+					current = candidate
+					changed, improved = true, true
+				}
+			}
+		}
+	}
+
+	solution.routes[vehicle] = current
+	return improved
+}
+
+// relocate moves single stops to another route, or another position, when that lowers the total distance
+func (p *vrpProblem) relocate(solution *vrpSolution) bool {
+	improved := false
+
+	for from := range solution.routes {
+		for i := 0; i < len(solution.routes[from].stops); i++ {
+			source := solution.routes[from]
+			stop := source.stops[i]
+			shortened, ok, _ := p.evaluate(from, removeStop(source.stops, i))
+			if !ok {
+				continue
+			}
+
+			bestVehicle, bestGain := -1, 1.0
+			var bestSource, bestTarget vrpRoute
+			for to := range solution.routes {
+				target := solution.routes[to]
+				base := target.stops
+				if to == from {
+					target = shortened
+					base = shortened.stops
+				}
+				for pos := 0; pos <= len(base); pos++ {
+					candidate, ok, _ := p.evaluate(to, insertStop(base, pos, stop))
+					if !ok {
+						continue
+					}
+
+					var gain float64
+					if to == from {
+						gain = source.distance - candidate.distance
+					} else {
+						gain = source.distance + target.distance - shortened.distance - candidate.distance
+					}
+					if gain > bestGain {
+						bestVehicle, bestGain, bestSource, bestTarget = to, gain, shortened, candidate
+					}
+				}
+			}
+
+			if bestVehicle < 0 {
+				continue
+			}
+			if bestVehicle != from {
+				solution.routes[from] = bestSource
+			}
+			solution.routes[bestVehicle] = bestTarget
+			improved = true
+		}
+	}
+
+	return improved
+}
+
+// insertStop returns a copy of stops with stop inserted at pos
+func insertStop(stops []int, pos, stop int) []int {
+	result := make([]int, 0, len(stops)+1)
+	result = append(result, stops[:pos]...)
+	result = append(result, stop)
+	return append(result, stops[pos:]...)
+}
+
+// removeStop returns a copy of stops without the stop at pos
+func removeStop(stops []int, pos int) []int {
+	result := make([]int, 0, len(stops)-1)
+	result = append(result, stops[:pos]...)
+	return append(result, stops[pos+1:]...)
+}
+
+// reverseStops returns a copy of stops with the segment i..j reversed
+func reverseStops(stops []int, i, j int) []int {
+	result := append([]int(nil), stops...)
+	for ; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+func TestVRPSolveHeuristic(t *testing.T) {
+	startAt := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		value := startAt.Add(time.Duration(minutes) * time.Minute)
+		return &value
+	}
+	// stop is about km kilometers east of the depot, west when negative
+	stop := func(id string, km float64, demand int) model.OptimizationStop {
+		return model.OptimizationStop{ID: id, Latitude: -6.2, Longitude: 106.8 + km*0.009, Demand: demand, ServiceMinutes: 5}
+	}
+	withWindow := func(s model.OptimizationStop, earliest, latest *time.Time) model.OptimizationStop {
+		s.EarliestArrival, s.LatestArrival = earliest, latest
+		return s
+	}
+	truck := func(plate string, capacity int) model.OptimizationVehicle {
+		return model.OptimizationVehicle{VehiclePlate: plate, DriverName: "Driver " + plate, Capacity: capacity}
+	}
+
+	tests := []struct {
+		name           string
+		stops          []model.OptimizationStop
+		vehicles       []model.OptimizationVehicle
+		returnToDepot  bool
+		wantUnassigned map[string]string
+		wantRoutes     int // Jumlah truk yang dipakai
+	}{
+		{
+			name:       "one truck without capacity",
+			stops:      []model.OptimizationStop{stop("a", 1, 5), stop("b", 2, 5), stop("c", 3, 5)},
+			vehicles:   []model.OptimizationVehicle{truck("B1", 0)},
+			wantRoutes: 1,
+		},
+		{
+			name:       "capacity splits the stops",
+			stops:      []model.OptimizationStop{stop("a", 1, 5), stop("b", 2, 5), stop("c", 3, 5), stop("d", 4, 5)},
+			vehicles:   []model.OptimizationVehicle{truck("B1", 10), truck("B2", 10)},
+			wantRoutes: 2,
+		},
+		{
+			name:           "capacity runs out",
+			stops:          []model.OptimizationStop{stop("a", 1, 5), stop("b", 2, 5), stop("c", 3, 5)},
+			vehicles:       []model.OptimizationVehicle{truck("B1", 10)},
+			wantUnassigned: map[string]string{"*": "not enough truck capacity left"},
+			wantRoutes:     1,
+		},
+		{
+			name:           "demand above every truck",
+			stops:          []model.OptimizationStop{stop("a", 1, 5), stop("big", 2, 25)},
+			vehicles:       []model.OptimizationVehicle{truck("B1", 10), truck("B2", 20)},
+			wantUnassigned: map[string]string{"big": "demand exceeds the capacity of every truck"},
+			wantRoutes:     1,
+		},
+		{
+			name:           "deadline before the truck can arrive",
+			stops:          []model.OptimizationStop{stop("a", 1, 1), withWindow(stop("far", 20, 1), nil, at(10))},
+			vehicles:       []model.OptimizationVehicle{truck("B1", 0)},
+			wantUnassigned: map[string]string{"far": "no truck can reach the stop within its time window"},
+			wantRoutes:     1,
+		},
+		{
+			name: "time windows decide the order",
+			stops: []model.OptimizationStop{
+				withWindow(stop("near-late", 1, 1), at(60), at(90)),
+				withWindow(stop("far-early", 5, 1), nil, at(20)),
+			},
+			vehicles:   []model.OptimizationVehicle{truck("B1", 0)},
+			wantRoutes: 1,
+		},
+		{
+			name: "tight windows need two trucks",
+			stops: []model.OptimizationStop{
+				withWindow(stop("east", 6, 1), nil, at(15)),
+				withWindow(stop("west", -6, 1), nil, at(15)),
+			},
+			vehicles:   []model.OptimizationVehicle{truck("B1", 0), truck("B2", 0)},
+			wantRoutes: 2,
+		},
+		{
+			name:          "return to depot",
+			stops:         []model.OptimizationStop{stop("a", 2, 1), stop("b", 3, 1)},
+			vehicles:      []model.OptimizationVehicle{truck("B1", 0)},
+			returnToDepot: true,
+			wantRoutes:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := model.RouteOptimizationRequest{
+				Depot:         model.OptimizationLocation{Latitude: -6.2, Longitude: 106.8},
+				Stops:         tt.stops,
+				Vehicles:      tt.vehicles,
+				ReturnToDepot: tt.returnToDepot,
+			}
+			problem := newVRPProblem(req, startAt)
+			solution := problem.solveHeuristic()
+
+			// Setiap stop dipakai tepat sekali atau tercatat tidak terjadwal
+			seen := make(map[int]bool)
+			used := 0
+			for v, route := range solution.routes {
+				if len(route.stops) > 0 {
+					used++
+				}
+				load := 0
+				for i, index := range route.stops {
+					if seen[index] {
+						t.Errorf("stop %s is assigned twice", tt.stops[index].ID)
+					}
+					seen[index] = true
+					load += tt.stops[index].Demand
+
+					arrival := route.arrivals[i]
+					window := tt.stops[index]
+					if window.EarliestArrival != nil && arrival.Before(*window.EarliestArrival) {
+						t.Errorf("stop %s reached at %s, before its window opens", window.ID, arrival.Format("15:04"))
+					}
+					if window.LatestArrival != nil && arrival.After(*window.LatestArrival) {
+						t.Errorf("stop %s reached at %s, after its window closes", window.ID, arrival.Format("15:04"))
+					}
+					if i > 0 && arrival.Before(route.arrivals[i-1]) {
+						t.Errorf("stop %s reached before the previous stop", window.ID)
+					}
+				}
+				if capacity := tt.vehicles[v].Capacity; capacity > 0 && load > capacity {
+					t.Errorf("truck %s carries %d, capacity %d", tt.vehicles[v].VehiclePlate, load, capacity)
+				}
+				if load != route.load {
+					t.Errorf("truck %s load = %d, want %d", tt.vehicles[v].VehiclePlate, route.load, load)
+				}
+			}
+			for index := range tt.stops {
+				_, unassigned := solution.unassigned[index]
+				if seen[index] == unassigned {
+					t.Errorf("stop %s assigned %v, unassigned %v", tt.stops[index].ID, seen[index], unassigned)
+				}
+			}
+
+			if used != tt.wantRoutes {
+				t.Errorf("used %d trucks, want %d", used, tt.wantRoutes)
+			}
+			if len(solution.unassigned) != len(tt.wantUnassigned) {
+				t.Fatalf("unassigned = %v, want %v", solution.unassigned, tt.wantUnassigned)
+			}
+			for index, reason := range solution.unassigned {
+				want, ok := tt.wantUnassigned[tt.stops[index].ID]
+				if !ok {
+					want = tt.wantUnassigned["*"] // Stop mana pun boleh
+				}
+				if reason != want {
+					t.Errorf("stop %s unassigned because %q, want %q", tt.stops[index].ID, reason, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVRPEvaluateReturnToDepot(t *testing.T) {
+	req := model.RouteOptimizationRequest{
+		Depot:    model.OptimizationLocation{Latitude: -6.2, Longitude: 106.8},
+		Stops:    []model.OptimizationStop{{ID: "a", Latitude: -6.2, Longitude: 106.818}},
+		Vehicles: []model.OptimizationVehicle{{VehiclePlate: "B1", DriverName: "Driver"}},
+	}
+	startAt := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+
+	oneWay, _, _ := newVRPProblem(req, startAt).evaluate(0, []int{0})
+	req.ReturnToDepot = true
+	roundTrip, _, _ := newVRPProblem(req, startAt).evaluate(0, []int{0})
+
+	if roundTrip.distance < 2*oneWay.distance-1e-6 || roundTrip.distance > 2*oneWay.distance+1e-6 {
+		t.Errorf("round trip distance = %.1f m, want twice %.1f m", roundTrip.distance, oneWay.distance)
+	}
+	if roundTrip.duration <= oneWay.duration {
+		t.Errorf("round trip duration = %.0f s, want more than %.0f s", roundTrip.duration, oneWay.duration)
+	}
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+// RouteWaypointTolerance is how far in meters a waypoint may lie from the route geometry.
+// Routing backends snap waypoints to the nearest road, so addresses off the road still pass.
+const RouteWaypointTolerance = 150.0
+
+// routeExtraInfo is the extra information requested for server side routes, as the planner map does
+var routeExtraInfo = []string{"suitability", "surface", "waycategory", "waytype", "tollways"}
+
+// RouteValidationError is returned when a route geometry sent by the client does not match the route plan
+type RouteValidationError struct {
+	Report *model.RouteValidationReport
+}
+
+func (e *RouteValidationError) Error() string {
+	if len(e.Report.Issues) == 0 {
+		return "route geometry is invalid"
+	}
+	return "route geometry is invalid: " + e.Report.Issues[0].Message
+}
+
+// routeAvoidanceArea is an approved avoidance area the route must stay out of
+type routeAvoidanceArea struct {
+	Area    *model.RouteAvoidanceArea // nil untuk area yang belum disimpan
+	Reason  string
+	Polygon []utils.LatLng
+}
+
+// approvedAvoidanceAreas returns the approved areas of a create request followed by the approved permanent areas
+func (s *routePlanService) approvedAvoidanceAreas(areaRequests []model.AvoidanceAreaRequest) ([]routeAvoidanceArea, error) {
+	areas, err := loadApprovedAvoidanceAreas(s.routePlanRepo, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var requestAreas []routeAvoidanceArea
+	for _, areaReq := range areaRequests {
+		if areaReq.Status != "approved" || len(areaReq.Points) < 3 {
+			continue
+		}
+		area := routeAvoidanceArea{Reason: areaReq.Reason}
+		for _, point := range areaReq.Points {
+			area.Polygon = append(area.Polygon, utils.LatLng{Lat: point.Latitude, Lng: point.Longitude})
+		}
+		requestAreas = append(requestAreas, area)
+	}
+	return append(requestAreas, areas...), nil
+}
+
+// prepareRouteGeometry fills in the geometry, extras, distance and duration of a new route plan.
+// Without a geometry in the request the route is computed through the routing service; a
+// geometry from the client is validated against the waypoints and approved avoidance areas.
+func (s *routePlanService) prepareRouteGeometry(req *model.RoutePlanCreateRequest, routePlan *model.RoutePlan) (*model.RouteValidationReport, error) {
+	waypoints := make([]utils.LatLng, 0, len(req.Waypoints))
+	for _, waypoint := range req.Waypoints {
+		waypoints = append(waypoints, utils.LatLng{Lat: waypoint.Latitude, Lng: waypoint.Longitude})
+	}
+	areas, err := s.approvedAvoidanceAreas(req.AvoidanceAreas)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.RouteGeometry == "" {
+		if err := s.computeRouteGeometry(context.Background(), waypoints, areas, routePlan); err != nil {
+			return nil, err
+		}
+		report := validateRouteGeometry(routePlan.RouteGeometry, waypoints, areas)
+		report.GeometrySource = model.RouteGeometrySourceServer
+		report.DurationSeconds = routePlan.DurationSeconds
+		// Rute dari routing service tetap disimpan, laporan hanya sebagai informasi
+		return report, nil
+	}
+
+	report := validateRouteGeometry(req.RouteGeometry, waypoints, areas)
+	report.GeometrySource = model.RouteGeometrySourceClient
+	report.DurationSeconds = req.DurationSeconds
+	if !report.Valid {
+		return nil, &RouteValidationError{Report: report}
+	}
+
+	distance := report.DistanceMeters
+	routePlan.RouteGeometry = req.RouteGeometry
+	routePlan.DistanceMeters = &distance
+	routePlan.DurationSeconds = req.DurationSeconds
+	return report, nil
+}
+
+// computeRouteGeometry asks the routing service for a route through the waypoints that avoids the areas
+func (s *routePlanService) computeRouteGeometry(ctx context.Context, waypoints []utils.LatLng, areas []routeAvoidanceArea, routePlan *model.RoutePlan) error {
+	if s.routingService == nil {
+		return errors.New("route_geometry is required, routing service is not available")
+	}
+
+	request := model.RoutingRequest{
+		ExtraInfo:        routeExtraInfo,
+		GeometrySimplify: "false",
+		Elevation:        true, // Geometri disimpan dalam format 3D seperti dari frontend
+	}
+	for _, waypoint := range waypoints {
+		request.Coordinates = append(request.Coordinates, []float64{waypoint.Lng, waypoint.Lat})
+	}
+	if len(areas) > 0 {
+		request.Options = map[string]interface{}{"avoid_polygons": avoidPolygonsGeoJSON(areas)}
+	}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	responseBody, _, err := s.routingService.GetDirections(ctx, requestBody, false)
+	if err != nil {
+		return fmt.Errorf("failed to compute route: %v", err)
+	}
+
+	var directions orsDirectionsResponse
+	if err := json.Unmarshal(responseBody, &directions); err != nil {
+		return fmt.Errorf("invalid directions response: %v", err)
+	}
+	if len(directions.Routes) == 0 || directions.Routes[0].Geometry == "" {
+		return errors.New("routing service found no route through the waypoints")
+	}
+
+	route := directions.Routes[0]
+	routePlan.RouteGeometry = route.Geometry
+	routePlan.DistanceMeters = &route.Summary.Distance
+	routePlan.DurationSeconds = &route.Summary.Duration
+
+	if len(route.Extras) > 0 {
+		extrasData, err := json.Marshal(route.Extras)
+		if err != nil {
+			return err
+		}
+		var extras model.RouteExtras
+		if err := json.Unmarshal(extrasData, &extras); err == nil {
+			if err := routePlan.SetExtras(&extras); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// avoidPolygonsGeoJSON converts avoidance areas to the ORS avoid_polygons option
+func avoidPolygonsGeoJSON(areas []routeAvoidanceArea) map[string]interface{} {
+	polygons := make([][][][]float64, 0, len(areas))
+	for _, area := range areas {
+		ring := make([][]float64, 0, len(area.Polygon)+1)
+		for _, point := range area.Polygon {
+			ring = append(ring, []float64{point.Lng, point.Lat})
+		}
+		// GeoJSON ring harus tertutup
+		ring = append(ring, []float64{area.Polygon[0].Lng, area.Polygon[0].Lat})
+		polygons = append(polygons, [][][]float64{ring})
+	}
+	return map[string]interface{}{
+		"type":        "MultiPolygon",
+		"coordinates": polygons,
+	}
+}
+
+// validateRouteGeometry checks that a route passes every waypoint in order and stays out of the avoidance areas
+func validateRouteGeometry(geometry string, waypoints []utils.LatLng, areas []routeAvoidanceArea) *model.RouteValidationReport {
+	report := &model.RouteValidationReport{
+		Waypoints:      []model.WaypointValidation{},
+		AvoidanceAreas: []model.AvoidanceAreaValidation{},
+		Issues:         []model.RouteValidationIssue{},
+	}
+
+	points, err := utils.DecodeRouteGeometry(geometry)
+	if err != nil || len(points) < 2 {
+		report.Issues = append(report.Issues, model.RouteValidationIssue{
+			Code:     model.RouteIssueInvalidGeometry,
+			Message:  "route_geometry is not a valid encoded polyline with at least two points",
+			Location: "route_geometry",
+		})
+		return report
+	}
+
+	index := utils.NewRouteIndex(points)
+	report.DistanceMeters = index.Length()
+
+	// Setiap waypoint dicari mulai dari segmen waypoint sebelumnya agar urutannya ikut diperiksa
+	fromSegment := 0
+	for i, waypoint := range waypoints {
+		nearest, _, _ := index.Nearest(waypoint, -1)
+		forward, segment := nearestSegmentFrom(points, waypoint, fromSegment)
+
+		check := model.WaypointValidation{
+			Index:          i,
+			DistanceMeters: math.Round(nearest*10) / 10,
+			OnRoute:        nearest <= RouteWaypointTolerance,
+			InOrder:        forward <= RouteWaypointTolerance,
+		}
+		report.Waypoints = append(report.Waypoints, check)
+
+		location := fmt.Sprintf("waypoints[%d]", i)
+		switch {
+		case !check.OnRoute:
+			report.Issues = append(report.Issues, model.RouteValidationIssue{
+				Code:     model.RouteIssueWaypointOffRoute,
+				Message:  fmt.Sprintf("waypoint %d is %.0f m from the route", i, nearest),
+				Location: location,
+			})
+		case !check.InOrder:
+			report.Issues = append(report.Issues, model.RouteValidationIssue{
+				Code:     model.RouteIssueWaypointOutOfOrder,
+				Message:  fmt.Sprintf("the route passes waypoint %d before waypoint %d", i, i-1),
+				Location: location,
+			})
+		default:
+			fromSegment = segment
+		}
+	}
+
+	for i, area := range areas {
+		inside, crossed := routeCrossesPolygon(points, area.Polygon)
+		report.AvoidanceAreas = append(report.AvoidanceAreas, model.AvoidanceAreaValidation{
+			Index:        i,
+			Reason:       area.Reason,
+			Crossed:      crossed,
+			PointsInside: inside,
+		})
+		if crossed {
+			issue := model.RouteValidationIssue{
+				Code:     model.RouteIssueAvoidanceAreaCrossed,
+				Message:  fmt.Sprintf("the route enters approved avoidance area %d", i),
+				Location: fmt.Sprintf("avoidance_areas[%d]", i),
+			}
+			if area.Area != nil {
+				// Area permanen yang tersimpan, bukan bagian dari request
+				issue.Message = fmt.Sprintf("the route enters permanent avoidance area %d (%s)", area.Area.ID, area.Reason)
+				issue.Location = fmt.Sprintf("permanent_avoidance_areas[%d]", area.Area.ID)
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	report.Valid = len(report.Issues) == 0
+	return report
+}
+
+// nearestSegmentFrom returns the distance to the closest segment at or after fromSegment and its index
+func nearestSegmentFrom(points []utils.LatLng, point utils.LatLng, fromSegment int) (float64, int) {
+	best, bestSegment := math.Inf(1), fromSegment
+	for i := fromSegment; i < len(points)-1; i++ {
+		if distance, _ := utils.CalculateDistanceToSegment(point, points[i], points[i+1]); distance < best {
+			best, bestSegment = distance, i
+		}
+	}
+	return best, bestSegment
+}
+
+// routeCrossesPolygon counts the route points inside a polygon and reports whether the route
+// enters it, including segments that cut through the polygon between two points
+func routeCrossesPolygon(points, polygon []utils.LatLng) (int, bool) {
+	minLat, maxLat, minLng, maxLng := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, vertex := range polygon {
+		minLat, maxLat = math.Min(minLat, vertex.Lat), math.Max(maxLat, vertex.Lat)
+		minLng, maxLng = math.Min(minLng, vertex.Lng), math.Max(maxLng, vertex.Lng)
+	}
+
+	inside := 0
+	crossed := false
+	for i, point := range points {
+		if point.Lat >= minLat && point.Lat <= maxLat && point.Lng >= minLng && point.Lng <= maxLng &&
+			utils.PointInPolygon(point, polygon) {
+			inside++
+		}
+		if crossed || i == 0 {
+			continue
+		}
+
+		prev := points[i-1]
+		if math.Max(prev.Lat, point.Lat) < minLat || math.Min(prev.Lat, point.Lat) > maxLat ||
+			math.Max(prev.Lng, point.Lng) < minLng || math.Min(prev.Lng, point.Lng) > maxLng {
+			continue
+		}
+		for j := range polygon {
+			if segmentsIntersect(prev, point, polygon[j], polygon[(j+1)%len(polygon)]) {
+				crossed = true
+				break
+			}
+		}
+	}
+
+	return inside, crossed || inside > 0
+}
+
+// segmentsIntersect reports whether segments ab and cd intersect, treating coordinates as planar
+func segmentsIntersect(a, b, c, d utils.LatLng) bool {
+	orientation := func(p, q, r utils.LatLng) float64 {
+		return (q.Lng-p.Lng)*(r.Lat-p.Lat) - (q.Lat-p.Lat)*(r.Lng-p.Lng)
+	}
+	d1, d2 := orientation(c, d, a), orientation(c, d, b)
+	d3, d4 := orientation(a, b, c), orientation(a, b, d)
+	return ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0))
+}
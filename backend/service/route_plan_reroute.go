@@ -0,0 +1,386 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
+)
+
+const (
+	// RerouteMaxPositionAge is how old the last truck position may be for a recomputed route to start
+	// from it. Without a recent position the route starts at the last visited waypoint.
+	RerouteMaxPositionAge = 15 * time.Minute
+
+	// rerouteTimeout bounds recomputing every route affected by one approved avoidance area
+	rerouteTimeout = 2 * time.Minute
+
+	// geometryRevisionAttempts is how often a revision number is picked again after another request took it
+	geometryRevisionAttempts = 3
+
+	// geometryRevisionIndex is the unique index on the route plan and revision number
+	geometryRevisionIndex = "idx_route_geometry_revisions_plan_revision"
+)
+
+// rerouteAroundAvoidanceArea recomputes the active route plans whose remaining route enters a newly
+// approved avoidance area and publishes the outcome to the alert subscribers. A permanent area applies
+// to every active route plan, a non permanent area only to the route plan it was reported on, as when routing.
+func (s *routePlanService) rerouteAroundAvoidanceArea(ctx context.Context, area *model.RouteAvoidanceArea) *model.AvoidanceRerouteResult {
+	result := &model.AvoidanceRerouteResult{
+		AvoidanceAreaID: area.ID,
+		Rerouted:        []uint{},
+		Failed:          []model.AvoidanceRerouteFailure{},
+	}
+	defer func() {
+		result.CompletedAt = time.Now()
+		publishRerouteResult(result)
+	}()
+
+	points, err := s.routePlanRepo.FindAvoidancePointsByAreaID(area.ID)
+	if err != nil {
+		log.Printf("Failed to load points of avoidance area %d: %v", area.ID, err)
+		result.Error = "failed to load the avoidance area"
+		return result
+	}
+	polygon := make([]utils.LatLng, 0, len(points))
+	for _, point := range points {
+		polygon = append(polygon, utils.LatLng{Lat: point.Latitude, Lng: point.Longitude})
+	}
+	if len(polygon) < 3 {
+		return result
+	}
+
+	var routePlans []*model.RoutePlan
+	if area.IsPermanent {
+		routePlans, err = s.routePlanRepo.FindAllActiveRoutePlans()
+		if err != nil {
+			log.Printf("Failed to load active route plans for avoidance area %d: %v", area.ID, err)
+			result.Error = "failed to load the active route plans"
+			return result
+		}
+	} else if routePlan, err := s.routePlanRepo.FindByID(area.RoutePlanID); err == nil && routePlan.Status == model.RoutePlanStatusActive {
+		routePlans = append(routePlans, routePlan)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rerouteTimeout)
+	defer cancel()
+
+	for _, routePlan := range routePlans {
+		result.Checked++
+		ok, err := s.rerouteRoutePlan(ctx, routePlan, area, polygon)
+		if err != nil {
+			log.Printf("Failed to reroute route plan %d around avoidance area %d: %v", routePlan.ID, area.ID, err)
+			result.Failed = append(result.Failed, model.AvoidanceRerouteFailure{RoutePlanID: routePlan.ID, Error: err.Error()})
+			continue
+		}
+		if ok {
+			result.Rerouted = append(result.Rerouted, routePlan.ID)
+		}
+	}
+	if len(result.Rerouted) > 0 {
+		log.Printf("Rerouted %d active route plan(s) around avoidance area %d", len(result.Rerouted), area.ID)
+	}
+	return result
+}
+
+// rerouteRoutePlan recomputes a route plan from the truck's current position through the remaining
+// waypoints when the rest of its route enters the polygon. It reports whether the route was changed.
+func (s *routePlanService) rerouteRoutePlan(ctx context.Context, routePlan *model.RoutePlan, area *model.RouteAvoidanceArea, polygon []utils.LatLng) (rerouted bool, err error) {
+	// Geometri yang rusak hanya menggagalkan route plan ini
+	defer recoverTask(&err)
+
+	points, err := utils.DecodeRouteGeometry(routePlan.RouteGeometry)
+	if err != nil || len(points) < 2 {
+		return false, nil
+	}
+
+	truck, err := s.truckRepo.FindByID(routePlan.TruckID)
+	if err != nil {
+		return false, errors.New("truck not found")
+	}
+	start := reroutePosition(truck)
+
+	// Bagian rute yang sudah dilewati truk tidak perlu dihindari lagi
+	ahead := points
+	if start != nil {
+		if _, nearest, segment := utils.NewRouteIndex(points).Nearest(*start, -1); segment >= 0 {
+			ahead = append([]utils.LatLng{nearest}, points[segment+1:]...)
+		}
+	}
+	if _, crossed := routeCrossesPolygon(ahead, polygon); !crossed {
+		return false, nil
+	}
+
+	remaining, lastVisited, err := s.remainingWaypoints(routePlan.ID)
+	if err != nil {
+		return false, err
+	}
+	var waypoints []utils.LatLng
+	if start != nil {
+		waypoints = append(waypoints, *start)
+	} else if lastVisited != nil {
+		waypoints = append(waypoints, utils.LatLng{Lat: lastVisited.Latitude, Lng: lastVisited.Longitude})
+	}
+	for _, waypoint := range remaining {
+		waypoints = append(waypoints, utils.LatLng{Lat: waypoint.Latitude, Lng: waypoint.Longitude})
+	}
+	if len(waypoints) < 2 {
+		// Tidak ada sisa perjalanan yang bisa dihitung ulang
+		return false, nil
+	}
+
+	areas, err := loadApprovedAvoidanceAreas(s.routePlanRepo, routePlan.ID)
+	if err != nil {
+		return false, err
+	}
+
+	previous := *routePlan
+	// Extras lama menunjuk ke titik geometri lama
+	routePlan.ExtrasData = ""
+	if err := s.computeRouteGeometry(ctx, waypoints, areas, routePlan); err != nil {
+		return false, err
+	}
+	routePlan.UpdatedAt = time.Now()
+
+	// Hanya kolom geometri yang ditulis, dan hanya jika rute masih aktif: status bisa berubah
+	// selama routing service dihubungi dan tidak boleh dikembalikan ke active
+	updated, err := s.routePlanRepo.UpdateActiveRouteGeometry(routePlan)
+	if err != nil {
+		return false, err
+	}
+	if !updated {
+		log.Printf("Route plan %d is no longer active, keeping its geometry", routePlan.ID)
+		return false, nil
+	}
+	s.routeCache.InvalidateRoutePlan(routePlan)
+
+	revision, err := s.recordGeometryRevision(&previous, routePlan, model.GeometryRevisionSourceAvoidance, &area.ID, start)
+	if err != nil {
+		log.Printf("Failed to record geometry revision for route plan %d: %v", routePlan.ID, err)
+	}
+
+	s.publishRerouted(routePlan, truck, area, revision)
+	if err := sendReroutedNotification(routePlan, truck, area); err != nil {
+		log.Printf("Error sending route updated notification: %v", err)
+	}
+	return true, nil
+}
+
+// reroutePosition returns the last known truck position, nil when it is missing or too old
+func reroutePosition(truck *model.Truck) *utils.LatLng {
+	if truck.Latitude == 0 && truck.Longitude == 0 {
+		return nil
+	}
+	if time.Since(truck.LastPosition) > RerouteMaxPositionAge {
+		return nil
+	}
+	return &utils.LatLng{Lat: truck.Latitude, Lng: truck.Longitude}
+}
+
+// remainingWaypoints returns the waypoints after the last visited one, in order, and the last visited
+// waypoint, nil when the truck has not reached any waypoint yet
+func (s *routePlanService) remainingWaypoints(routePlanID uint) ([]*model.RouteWaypoint, *model.RouteWaypoint, error) {
+	waypoints, err := s.routePlanRepo.FindWaypointsByRoutePlanID(routePlanID)
+	if err != nil {
+		return nil, nil, err
+	}
+	visits, err := s.visitRepo.FindByRoutePlanID(routePlanID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	visited := make(map[uint]bool, len(visits))
+	for _, visit := range visits {
+		visited[visit.WaypointID] = true
+	}
+
+	var lastVisited *model.RouteWaypoint
+	for _, waypoint := range waypoints {
+		if visited[waypoint.ID] {
+			lastVisited = waypoint
+		}
+	}
+	if lastVisited == nil {
+		return waypoints, nil, nil
+	}
+
+	var remaining []*model.RouteWaypoint
+	for _, waypoint := range waypoints {
+		if waypoint.Order > lastVisited.Order {
+			remaining = append(remaining, waypoint)
+		}
+	}
+	return remaining, lastVisited, nil
+}
+
+// recordGeometryRevision saves the current geometry of a route plan as its next revision. Route plans
+// created before revisions were kept first get their previous geometry saved as the initial revision.
+// The next number is read before inserting, so a number taken in the meantime is retried with a new one.
+func (s *routePlanService) recordGeometryRevision(previous, routePlan *model.RoutePlan, source string, areaID *uint, start *utils.LatLng) (*model.RouteGeometryRevision, error) {
+	if s.revisionRepo == nil {
+		return nil, nil
+	}
+
+	var err error
+	for attempt := 0; attempt < geometryRevisionAttempts; attempt++ {
+		var revision *model.RouteGeometryRevision
+		revision, err = s.createGeometryRevision(previous, routePlan, source, areaID, start)
+		if !repository.IsUniqueViolation(err, geometryRevisionIndex) {
+			return revision, err
+		}
+		log.Printf("Geometry revision of route plan %d was taken concurrently, retrying", routePlan.ID)
+	}
+	return nil, err
+}
+
+// createGeometryRevision inserts the revision after the newest one of the route plan
+func (s *routePlanService) createGeometryRevision(previous, routePlan *model.RoutePlan, source string, areaID *uint, start *utils.LatLng) (*model.RouteGeometryRevision, error) {
+	next := 1
+	latest, err := s.revisionRepo.FindLatestByRoutePlanID(routePlan.ID)
+	switch {
+	case err == nil:
+		next = latest.Revision + 1
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	case previous != nil && previous.RouteGeometry != "":
+		initial := &model.RouteGeometryRevision{
+			RoutePlanID:     routePlan.ID,
+			Revision:        1,
+			RouteGeometry:   previous.RouteGeometry,
+			ExtrasData:      previous.ExtrasData,
+			DistanceMeters:  previous.DistanceMeters,
+			DurationSeconds: previous.DurationSeconds,
+			Source:          model.GeometryRevisionSourceInitial,
+			CreatedAt:       previous.CreatedAt,
+		}
+		if err := s.revisionRepo.Create(initial); err != nil {
+			return nil, err
+		}
+		next = 2
+	}
+
+	revision := &model.RouteGeometryRevision{
+		RoutePlanID:     routePlan.ID,
+		Revision:        next,
+		RouteGeometry:   routePlan.RouteGeometry,
+		ExtrasData:      routePlan.ExtrasData,
+		DistanceMeters:  routePlan.DistanceMeters,
+		DurationSeconds: routePlan.DurationSeconds,
+		Source:          source,
+		AvoidanceAreaID: areaID,
+		CreatedAt:       time.Now(),
+	}
+	if start != nil {
+		revision.StartLatitude = &start.Lat
+		revision.StartLongitude = &start.Lng
+	}
+	if err := s.revisionRepo.Create(revision); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// GetGeometryRevisions returns the geometries a route plan has had, oldest first
+func (s *routePlanService) GetGeometryRevisions(id uint) ([]model.RouteGeometryRevisionResponse, error) {
+	routePlan, err := s.routePlanRepo.FindByID(id)
+	if err != nil {
+		return nil, notFoundError("route plan", err)
+	}
+
+	revisions, err := s.revisionRepo.FindByRoutePlanID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.RouteGeometryRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		responses[i] = revision.ToRouteGeometryRevisionResponse()
+	}
+	if last := len(responses) - 1; last >= 0 {
+		responses[last].Current = responses[last].RouteGeometry == routePlan.RouteGeometry
+	}
+	return responses, nil
+}
+
+// publishRerouted sends the new route to the alert, truck and route subscribers, including the driver app
+func (s *routePlanService) publishRerouted(routePlan *model.RoutePlan, truck *model.Truck, area *model.RouteAvoidanceArea, revision *model.RouteGeometryRevision) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"type":              "route_plan_rerouted",
+		"route_plan_id":     routePlan.ID,
+		"truck_id":          routePlan.TruckID,
+		"mac_id":            truck.MacID,
+		"driver_id":         routePlan.DriverID,
+		"avoidance_area_id": area.ID,
+		"reason":            area.Reason,
+		"route_geometry":    routePlan.RouteGeometry,
+		"distance_meters":   routePlan.DistanceMeters,
+		"duration_seconds":  routePlan.DurationSeconds,
+		"rerouted_at":       routePlan.UpdatedAt,
+	}
+	if revision != nil {
+		event["revision"] = revision.Revision
+	}
+
+	jsonData, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshaling route rerouted update: %v", err)
+		return
+	}
+
+	wsHub.Publish(jsonData, websocket.ChannelAlerts, websocket.TruckChannel(truck.MacID), websocket.RouteChannel(routePlan.ID))
+}
+
+// publishRerouteResult tells the alert subscribers, including the approving planner, which routes were recomputed
+func publishRerouteResult(result *model.AvoidanceRerouteResult) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"type":   "avoidance_area_reroute",
+		"result": result,
+	})
+	if err != nil {
+		log.Printf("Error marshaling avoidance reroute result: %v", err)
+		return
+	}
+
+	wsHub.Publish(jsonData, websocket.ChannelAlerts)
+}
+
+// sendReroutedNotification sends a push notification about the updated route to the driver
+func sendReroutedNotification(routePlan *model.RoutePlan, truck *model.Truck, area *model.RouteAvoidanceArea) error {
+	plateNumber := truck.PlateNumber
+	if plateNumber == "" {
+		plateNumber = truck.MacID
+	}
+	message := fmt.Sprintf("The route of vehicle %s was updated to avoid a newly approved area: %s. Open the route to see the new directions.",
+		plateNumber, area.Reason)
+
+	err := sendNotificationRequest(NotificationRequest{
+		Title:         "Route Updated",
+		Message:       message,
+		URL:           "/driver/route",
+		TargetRoles:   []string{},
+		TargetUserIDs: []uint{routePlan.DriverID},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Route Updated notification sent for truck %s (Driver ID: %d)", truck.MacID, routePlan.DriverID)
+	return nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// fakeRevisionRepo keeps revisions in memory. Before each of the first conflicts Create calls,
+// another request "takes" the next revision number, as a concurrent reroute would.
+type fakeRevisionRepo struct {
+	revisions []*model.RouteGeometryRevision
+	conflicts int
+	findErr   error
+}
+
+func (r *fakeRevisionRepo) Create(revision *model.RouteGeometryRevision) error {
+	if r.conflicts > 0 {
+		r.conflicts--
+		r.revisions = append(r.revisions, &model.RouteGeometryRevision{RoutePlanID: revision.RoutePlanID, Revision: revision.Revision})
+		return &pgconn.PgError{Code: "23505", ConstraintName: geometryRevisionIndex}
+	}
+	for _, existing := range r.revisions {
+		if existing.RoutePlanID == revision.RoutePlanID && existing.Revision == revision.Revision {
+			return &pgconn.PgError{Code: "23505", ConstraintName: geometryRevisionIndex}
+		}
+	}
+	r.revisions = append(r.revisions, revision)
+	return nil
+}
+
+func (r *fakeRevisionRepo) FindByRoutePlanID(routePlanID uint) ([]*model.RouteGeometryRevision, error) {
+	return r.revisions, nil
+}
+
+func (r *fakeRevisionRepo) FindLatestByRoutePlanID(routePlanID uint) (*model.RouteGeometryRevision, error) {
+	if r.findErr != nil {
+		return nil, r.findErr
+	}
+	var latest *model.RouteGeometryRevision
+	for _, revision := range r.revisions {
+		if revision.RoutePlanID == routePlanID && (latest == nil || revision.Revision > latest.Revision) {
+			latest = revision
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("geometry revision %w", repository.ErrNotFound)
+	}
+	return latest, nil
+}
+
+func TestRecordGeometryRevision(t *testing.T) {
+	dbErr := errors.New("connection refused")
+
+	tests := []struct {
+		name         string
+		existing     []*model.RouteGeometryRevision
+		previous     *model.RoutePlan
+		conflicts    int
+		findErr      error
+		wantRevision int
+		wantCount    int
+		wantErr      error
+	}{
+		{"first revision", nil, nil, 0, nil, 1, 1, nil},
+		{"initial revision of an older route plan", nil, &model.RoutePlan{RouteGeometry: "old"}, 0, nil, 2, 2, nil},
+		{"next revision", []*model.RouteGeometryRevision{{RoutePlanID: 1, Revision: 1}}, nil, 0, nil, 2, 2, nil},
+		{"retry after a concurrent revision", []*model.RouteGeometryRevision{{RoutePlanID: 1, Revision: 1}}, nil, 1, nil, 3, 3, nil},
+		{"retry twice", []*model.RouteGeometryRevision{{RoutePlanID: 1, Revision: 1}}, nil, 2, nil, 4, 4, nil},
+		{"give up after the last attempt", []*model.RouteGeometryRevision{{RoutePlanID: 1, Revision: 1}}, nil, geometryRevisionAttempts, nil, 0, 1 + geometryRevisionAttempts, nil},
+		{"lookup error is not treated as no revisions", nil, nil, 0, dbErr, 0, 0, dbErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRevisionRepo{revisions: tt.existing, conflicts: tt.conflicts, findErr: tt.findErr}
+			s := &routePlanService{revisionRepo: repo}
+			routePlan := &model.RoutePlan{ID: 1, RouteGeometry: "new"}
+
+			revision, err := s.recordGeometryRevision(tt.previous, routePlan, model.GeometryRevisionSourceManual, nil, nil)
+			if tt.wantRevision == 0 {
+				if err == nil {
+					t.Fatalf("expected an error, got revision %+v", revision)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if revision.Revision != tt.wantRevision || revision.RouteGeometry != "new" {
+					t.Errorf("revision = %d with geometry %q, want %d with \"new\"", revision.Revision, revision.RouteGeometry, tt.wantRevision)
+				}
+			}
+			if len(repo.revisions) != tt.wantCount {
+				t.Errorf("stored %d revisions, want %d", len(repo.revisions), tt.wantCount)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,26 +19,44 @@ type RoutePlanService interface {
 	GetRoutePlanByID(id uint) (*model.RoutePlanResponse, error)
 	GetAllRoutePlans() ([]*model.RoutePlanResponse, error)
 	GetAllActiveRoutePlans() ([]*model.RoutePlanResponse, error)
-	UpdateRoutePlanStatus(id uint, status string) error
-	UpdateAvoidanceAreaStatus(id uint, status string) error
+	UpdateRoutePlanStatus(id uint, status, reason string, userID uint, role string) error
+	GetStatusHistory(id uint) ([]model.RoutePlanStatusHistoryResponse, error)
+	GetGeometryRevisions(id uint) ([]model.RouteGeometryRevisionResponse, error)
+	UpdateAvoidanceAreaStatus(id uint, status string) (*model.AvoidanceAreaStatusResponse, error)
 	DeleteRoutePlan(id uint) error
 	DeleteAvoidanceArea(id uint) error
 	AddAvoidanceAreaToRoutePlan(routePlanID uint, areaRequests []model.AvoidanceAreaRequest) (*model.RoutePlanResponse, error)
 	GetAvoidanceAreasByPermanentStatus(isPermanent bool) ([]model.AvoidanceAreaResponse, error)
 	UpdateRoutePlan(id uint, routeGeometry string, extras map[string]interface{}) (*model.RoutePlanResponse, error)
+	UpdateDeviationSettings(id uint, settings *model.RouteDeviationSettings) (*model.RoutePlanResponse, error)
 }
 
 type routePlanService struct {
 	routePlanRepo repository.RoutePlanRepository
 	truckRepo     repository.TruckRepository
 	userRepo      repository.UserRepository
+	visitRepo     repository.WaypointVisitRepository
+	routeCache    *RouteGeometryCache
+	historyRepo   repository.RoutePlanStatusHistoryRepository
+	statusUpdater *routePlanStatusUpdater
 	s3Service     S3Service
+	routingService RoutingService
+	revisionRepo  repository.RouteGeometryRevisionRepository
+	progressSvc   RouteProgressService
+	worker        *BackgroundWorker
 }
 
 func NewRoutePlanService(
 	routePlanRepo repository.RoutePlanRepository,
 	truckRepo repository.TruckRepository,
 	userRepo repository.UserRepository,
+	visitRepo repository.WaypointVisitRepository,
+	historyRepo repository.RoutePlanStatusHistoryRepository,
+	routeCache *RouteGeometryCache,
+	routingService RoutingService,
+	revisionRepo repository.RouteGeometryRevisionRepository,
+	progressSvc RouteProgressService,
+	worker *BackgroundWorker,
 ) RoutePlanService {
 	s3Service, _ := NewS3Service()
 
@@ -45,7 +64,19 @@ func NewRoutePlanService(
 		routePlanRepo: routePlanRepo,
 		truckRepo:     truckRepo,
 		userRepo:      userRepo,
+		visitRepo:     visitRepo,
+		routeCache:    routeCache,
+		historyRepo:   historyRepo,
+		statusUpdater: &routePlanStatusUpdater{
+			routePlanRepo: routePlanRepo,
+			historyRepo:   historyRepo,
+			routeCache:    routeCache,
+		},
 		s3Service:     s3Service,
+		routingService: routingService,
+		revisionRepo:  revisionRepo,
+		progressSvc:   progressSvc,
+		worker:        worker,
 	}
 }
 
@@ -147,6 +178,12 @@ func (s *routePlanService) CreateRoutePlan(req model.RoutePlanCreateRequest, pla
 		return nil, errors.New("driver not found")
 	}
 
+	if req.DeviationSettings != nil {
+		if err := ValidateDeviationSettings(req.DeviationSettings); err != nil {
+			return nil, err
+		}
+	}
+
 	// Find truck by plate number or MAC ID
 	var truckId uint
 	plateAndMac := strings.Split(req.VehiclePlate, "/")
@@ -163,19 +200,35 @@ func (s *routePlanService) CreateRoutePlan(req model.RoutePlanCreateRequest, pla
 
 	truckId = truck.ID
 
+	if err := validatePlannedWindow(req.PlannedStartAt, req.PlannedEndAt); err != nil {
+		return nil, err
+	}
+	for i, waypointReq := range req.Waypoints {
+		if waypointReq.EarliestArrival != nil && waypointReq.LatestArrival != nil && waypointReq.LatestArrival.Before(*waypointReq.EarliestArrival) {
+			return nil, fmt.Errorf("waypoint %d: latest_arrival must not be before earliest_arrival", i)
+		}
+	}
+
 	// Create new route plan
 	routePlan := &model.RoutePlan{
 		DriverID:      driverId,
 		TruckID:       truckId,
 		PlannerID:     plannerId,
-		RouteGeometry: req.RouteGeometry,
 		Status:        "planned",
+		PlannedStartAt: req.PlannedStartAt,
+		PlannedEndAt:  req.PlannedEndAt,
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 	}
 
+	// Rute dihitung backend jika route_geometry kosong, jika tidak divalidasi
+	validation, err := s.prepareRouteGeometry(&req, routePlan)
+	if err != nil {
+		return nil, err
+	}
+
 	// Jika ada informasi extras dari body request
-	if req.ExtrasData != "" {
+	if req.RouteGeometry != "" && req.ExtrasData != "" {
 		// Parse extras data
 		var extras model.RouteExtras
 		if err := json.Unmarshal([]byte(req.ExtrasData), &extras); err == nil {
@@ -186,10 +239,19 @@ func (s *routePlanService) CreateRoutePlan(req model.RoutePlanCreateRequest, pla
 		}
 	}
 
+	if err := routePlan.SetDeviationSettings(req.DeviationSettings); err != nil {
+		return nil, err
+	}
+
 	if err := s.routePlanRepo.Create(routePlan); err != nil {
 		return nil, err
 	}
 
+	// Geometri awal menjadi revisi pertama
+	if _, err := s.recordGeometryRevision(nil, routePlan, model.GeometryRevisionSourceInitial, nil, nil); err != nil {
+		log.Printf("Failed to record geometry revision for route plan %d: %v", routePlan.ID, err)
+	}
+
 	// Tambahkan waypoints
 	var waypoints []*model.RouteWaypoint
 	for i, waypointReq := range req.Waypoints {
@@ -199,6 +261,8 @@ func (s *routePlanService) CreateRoutePlan(req model.RoutePlanCreateRequest, pla
 			Longitude:   waypointReq.Longitude,
 			Address:     waypointReq.Address,
 			Order:       i,
+			EarliestArrival: waypointReq.EarliestArrival,
+			LatestArrival:   waypointReq.LatestArrival,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
 		}
@@ -266,8 +330,56 @@ func (s *routePlanService) CreateRoutePlan(req model.RoutePlanCreateRequest, pla
 		}
 	}
 
-	// Return created route plan
-	return s.GetRoutePlanByID(routePlan.ID)
+	// Return created route plan with the plans it overlaps, the planner decides what to do
+	response, err := s.GetRoutePlanByID(routePlan.ID)
+	if err != nil {
+		return nil, err
+	}
+	response.ScheduleConflicts = s.findScheduleConflicts(routePlan)
+	response.Validation = validation
+	return response, nil
+}
+
+// validatePlannedWindow checks that a planned window has both ends in the right order
+func validatePlannedWindow(start, end *time.Time) error {
+	if (start == nil) != (end == nil) {
+		return errors.New("planned_start_at and planned_end_at must be set together")
+	}
+	if start != nil && !end.After(*start) {
+		return errors.New("planned_end_at must be after planned_start_at")
+	}
+	return nil
+}
+
+// findScheduleConflicts returns the open route plans of the same truck or driver whose
+// planned window overlaps the window of a route plan
+func (s *routePlanService) findScheduleConflicts(routePlan *model.RoutePlan) []model.ScheduleConflict {
+	if routePlan.PlannedStartAt == nil || routePlan.PlannedEndAt == nil {
+		return nil
+	}
+
+	overlaps, err := s.routePlanRepo.FindScheduleOverlaps(routePlan.TruckID, routePlan.DriverID, routePlan.ID,
+		*routePlan.PlannedStartAt, *routePlan.PlannedEndAt)
+	if err != nil {
+		log.Printf("Failed to check schedule conflicts of route plan %d: %v", routePlan.ID, err)
+		return nil
+	}
+
+	conflicts := make([]model.ScheduleConflict, 0, len(overlaps))
+	for _, other := range overlaps {
+		conflictOn := "driver"
+		if other.TruckID == routePlan.TruckID {
+			conflictOn = "truck"
+		}
+		conflicts = append(conflicts, model.ScheduleConflict{
+			RoutePlanID:    other.ID,
+			Status:         other.Status,
+			ConflictOn:     conflictOn,
+			PlannedStartAt: other.PlannedStartAt,
+			PlannedEndAt:   other.PlannedEndAt,
+		})
+	}
+	return conflicts
 }
 
 // GetRoutePlanByID retrieves a route plan by ID
@@ -303,6 +415,12 @@ func (s *routePlanService) GetRoutePlanByID(id uint) (*model.RoutePlanResponse,
 		return nil, err
 	}
 
+	// Get waypoint visits so planners see stop-by-stop progress
+	visits, err := s.visitRepo.FindByRoutePlanID(routePlan.ID)
+	if err != nil {
+		return nil, err
+	}
+
 	waypointResponses := make([]model.WaypointResponse, len(waypoints))
 	for i, waypoint := range waypoints {
 		waypointResponses[i] = model.WaypointResponse{
@@ -311,6 +429,13 @@ func (s *routePlanService) GetRoutePlanByID(id uint) (*model.RoutePlanResponse,
 			Longitude: waypoint.Longitude,
 			Address:   waypoint.Address,
 			Order:     waypoint.Order,
+			EarliestArrival: waypoint.EarliestArrival,
+			LatestArrival:   waypoint.LatestArrival,
+		}
+		for _, visit := range visits {
+			if visit.WaypointID == waypoint.ID {
+				waypointResponses[i].Visits = append(waypointResponses[i].Visits, visit.ToWaypointVisitResponse(waypoint))
+			}
 		}
 	}
 
@@ -371,6 +496,9 @@ func (s *routePlanService) GetRoutePlanByID(id uint) (*model.RoutePlanResponse,
 		extras, _ = routePlan.GetExtras()
 	}
 
+	// Per-plan deviation detector overrides
+	deviationSettings, _ := routePlan.GetDeviationSettings()
+
 	// Create response
 	response := &model.RoutePlanResponse{
 		ID:             routePlan.ID,
@@ -382,6 +510,14 @@ func (s *routePlanService) GetRoutePlanByID(id uint) (*model.RoutePlanResponse,
 		Status:         routePlan.Status,
 		Waypoints:      waypointResponses,
 		AvoidanceAreas: areaResponses,
+		DeviationSettings: deviationSettings,
+		PlannedStartAt: routePlan.PlannedStartAt,
+		PlannedEndAt:   routePlan.PlannedEndAt,
+		DistanceMeters: routePlan.DistanceMeters,
+		DurationSeconds: routePlan.DurationSeconds,
+		StartedAt:      routePlan.StartedAt,
+		CompletedAt:    routePlan.CompletedAt,
+		NotStartedFlaggedAt: routePlan.NotStartedFlaggedAt,
 		CreatedAt:      routePlan.CreatedAt,
 		UpdatedAt:      routePlan.UpdatedAt,
 	}
@@ -410,37 +546,56 @@ func (s *routePlanService) GetAllRoutePlans() ([]*model.RoutePlanResponse, error
 	return responses, nil
 }
 
-// UpdateRoutePlanStatus updates the status of a route plan
-func (s *routePlanService) UpdateRoutePlanStatus(id uint, status string) error {
-	// Validate status
-	validStatuses := []string{"planned", "active", "completed", "cancelled", "on confirmation"}
-	isValidStatus := false
-	for _, validStatus := range validStatuses {
-		if status == validStatus {
-			isValidStatus = true
-			break
-		}
-	}
-
-	if !isValidStatus {
+// UpdateRoutePlanStatus moves a route plan to a new status on behalf of a user.
+// The change must be an allowed transition and allowed for the user's role.
+func (s *routePlanService) UpdateRoutePlanStatus(id uint, status, reason string, userID uint, role string) error {
+	if !containsStatus(routePlanStatuses, status) {
 		return errors.New("invalid status")
 	}
 
 	// Get route plan
 	routePlan, err := s.routePlanRepo.FindByID(id)
 	if err != nil {
+		return notFoundError("route plan", err)
+	}
+
+	if err := checkStatusTransition(routePlan, status); err != nil {
+		return err
+	}
+	if err := checkStatusPermission(routePlan, status, userID, role); err != nil {
 		return err
 	}
 
-	// Update status
-	routePlan.Status = status
-	routePlan.UpdatedAt = time.Now()
+	// Update status and record the change
+	return s.statusUpdater.apply(routePlan, status, model.StatusChangeSourceManual, strings.TrimSpace(reason), &userID)
+}
+
+// GetStatusHistory returns the status changes of a route plan, oldest first
+func (s *routePlanService) GetStatusHistory(id uint) ([]model.RoutePlanStatusHistoryResponse, error) {
+	if _, err := s.routePlanRepo.FindByID(id); err != nil {
+		return nil, notFoundError("route plan", err)
+	}
+
+	histories, err := s.historyRepo.FindByRoutePlanID(id)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.routePlanRepo.Update(routePlan)
+	responses := make([]model.RoutePlanStatusHistoryResponse, len(histories))
+	for i, history := range histories {
+		responses[i] = history.ToRoutePlanStatusHistoryResponse()
+		if history.ChangedByID != nil {
+			if user, err := s.userRepo.FindByID(*history.ChangedByID); err == nil {
+				responses[i].ChangedByName = user.Name
+			}
+		}
+	}
+	return responses, nil
 }
 
-// UpdateAvoidanceAreaStatus updates status of an avoidance area
-func (s *routePlanService) UpdateAvoidanceAreaStatus(id uint, status string) error {
+// UpdateAvoidanceAreaStatus updates status of an avoidance area. Approving an area starts
+// recomputing the active routes that enter it in the background.
+func (s *routePlanService) UpdateAvoidanceAreaStatus(id uint, status string) (*model.AvoidanceAreaStatusResponse, error) {
 	// Validasi status
 	validStatuses := []string{"pending", "approved", "rejected"}
 	isValidStatus := false
@@ -452,17 +607,32 @@ func (s *routePlanService) UpdateAvoidanceAreaStatus(id uint, status string) err
 	}
 
 	if !isValidStatus {
-		return errors.New("invalid status: must be 'pending', 'approved', or 'rejected'")
+		return nil, errors.New("invalid status: must be 'pending', 'approved', or 'rejected'")
 	}
 
 	// Verify avoidance area exists
-	_, err := s.routePlanRepo.FindAvoidanceAreaByID(id)
+	area, err := s.routePlanRepo.FindAvoidanceAreaByID(id)
 	if err != nil {
-		return errors.New("avoidance area not found")
+		return nil, errors.New("avoidance area not found")
 	}
 
 	// Update status
-	return s.routePlanRepo.UpdateAvoidanceAreaStatus(id, status)
+	if err := s.routePlanRepo.UpdateAvoidanceAreaStatus(id, status); err != nil {
+		return nil, err
+	}
+	response := &model.AvoidanceAreaStatusResponse{ID: id, Status: status}
+
+	// Rute aktif yang melewati area yang baru disetujui dihitung ulang di background
+	if status == "approved" && area.Status != "approved" && s.worker != nil {
+		area.Status = status
+		response.ReroutePending = s.worker.Go(fmt.Sprintf("reroute around avoidance area %d", id), func(ctx context.Context) {
+			s.rerouteAroundAvoidanceArea(ctx, area)
+		})
+		if !response.ReroutePending {
+			log.Printf("Server is shutting down, active routes are not rerouted around avoidance area %d", id)
+		}
+	}
+	return response, nil
 }
 
 // DeleteAvoidanceArea deletes an avoidance area and its points
@@ -478,7 +648,7 @@ func (s *routePlanService) DeleteAvoidanceArea(id uint) error {
 		_ = s.s3Service.DeleteObject(area.PhotoKey)
 	}
 
-	if err := s.UpdateAvoidanceAreaStatus(id, "rejected"); err != nil {
+	if _, err := s.UpdateAvoidanceAreaStatus(id, "rejected"); err != nil {
 		log.Printf("failed to update avoidance area status to rejected: %v", err)
 	}
 	
@@ -489,6 +659,11 @@ func (s *routePlanService) DeleteAvoidanceArea(id uint) error {
 
 // DeleteRoutePlan deletes a route plan
 func (s *routePlanService) DeleteRoutePlan(id uint) error {
+	routePlan, err := s.routePlanRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
 	// Get avoidance areas to delete photos from S3
 	if s.s3Service != nil {
 		areas, err := s.routePlanRepo.FindAvoidanceAreasByRoutePlanID(id)
@@ -503,7 +678,13 @@ func (s *routePlanService) DeleteRoutePlan(id uint) error {
 	}
 
 	// Delete route plan and related data from database
-	return s.routePlanRepo.Delete(id)
+	if err := s.routePlanRepo.Delete(id); err != nil {
+		return err
+	}
+
+	s.routeCache.InvalidateRoutePlan(routePlan)
+	publishRoutePlanDeleted(routePlan)
+	return nil
 }
 
 // GetAvoidanceAreasByPermanentStatus returns all avoidance areas filtered by permanent status
@@ -572,6 +753,8 @@ func (s *routePlanService) UpdateRoutePlan(id uint, routeGeometry string, extras
 		return nil, errors.New("route plan not found")
 	}
 
+	previous := *routePlan
+
 	// Update route geometry
 	routePlan.RouteGeometry = routeGeometry
 	routePlan.UpdatedAt = time.Now()
@@ -592,7 +775,41 @@ func (s *routePlanService) UpdateRoutePlan(id uint, routeGeometry string, extras
 	if err := s.routePlanRepo.Update(routePlan); err != nil {
 		return nil, err
 	}
+	s.routeCache.InvalidateRoutePlan(routePlan)
+
+	if previous.RouteGeometry != routePlan.RouteGeometry {
+		if _, err := s.recordGeometryRevision(&previous, routePlan, model.GeometryRevisionSourceManual, nil, nil); err != nil {
+			log.Printf("Failed to record geometry revision for route plan %d: %v", routePlan.ID, err)
+		}
+	}
 
 	// Return updated route plan
 	return s.GetRoutePlanByID(id)
-}
\ No newline at end of file
+}
+
+// UpdateDeviationSettings replaces the deviation detector overrides of a route plan.
+// Nil settings make the route plan use the global settings again.
+func (s *routePlanService) UpdateDeviationSettings(id uint, settings *model.RouteDeviationSettings) (*model.RoutePlanResponse, error) {
+	routePlan, err := s.routePlanRepo.FindByID(id)
+	if err != nil {
+		return nil, notFoundError("route plan", err)
+	}
+
+	if settings != nil {
+		if err := ValidateDeviationSettings(settings); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := routePlan.SetDeviationSettings(settings); err != nil {
+		return nil, err
+	}
+	routePlan.UpdatedAt = time.Now()
+
+	if err := s.routePlanRepo.Update(routePlan); err != nil {
+		return nil, err
+	}
+	s.routeCache.InvalidateRoutePlan(routePlan)
+
+	return s.GetRoutePlanByID(id)
+}
@@ -19,6 +19,10 @@ func (s *routePlanService) GetAllActiveRoutePlans() ([]*model.RoutePlanResponse,
 		if err != nil {
 			return nil, err
 		}
+		// Progress is only known once the truck has reported a position on the route
+		if s.progressSvc != nil {
+			response.Progress, _ = s.progressSvc.GetProgress(routePlan.ID)
+		}
 		responses[i] = response
 	}
 
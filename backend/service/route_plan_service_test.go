@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidatePlannedWindow(t *testing.T) {
+	start := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Hour)
+	before := start.Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		start, end *time.Time
+		valid      bool
+	}{
+		{"no window", nil, nil, true},
+		{"window", &start, &end, true},
+		{"start only", &start, nil, false},
+		{"end only", nil, &end, false},
+		{"empty window", &start, &start, false},
+		{"end before start", &start, &before, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePlannedWindow(tt.start, tt.end); (err == nil) != tt.valid {
+				t.Errorf("validatePlannedWindow() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
+)
+
+// routePlanStatuses lists every route plan status
+var routePlanStatuses = []string{
+	model.RoutePlanStatusPlanned,
+	model.RoutePlanStatusActive,
+	model.RoutePlanStatusCompleted,
+	model.RoutePlanStatusCancelled,
+	model.RoutePlanStatusOnConfirmation,
+}
+
+// routePlanTransitions lists the statuses a route plan may move to from each status.
+// Completed and cancelled route plans are final.
+var routePlanTransitions = map[string][]string{
+	model.RoutePlanStatusPlanned: {
+		model.RoutePlanStatusActive,
+		model.RoutePlanStatusOnConfirmation,
+		model.RoutePlanStatusCancelled,
+	},
+	model.RoutePlanStatusActive: {
+		model.RoutePlanStatusCompleted,
+		model.RoutePlanStatusOnConfirmation,
+		model.RoutePlanStatusCancelled,
+	},
+	// Menunggu persetujuan area hindaran yang dilaporkan driver
+	model.RoutePlanStatusOnConfirmation: {
+		model.RoutePlanStatusPlanned,
+		model.RoutePlanStatusActive,
+		model.RoutePlanStatusCancelled,
+	},
+}
+
+// routePlanRoleStatuses lists the statuses each role may set. Drivers only act on their own route plans.
+var routePlanRoleStatuses = map[string][]string{
+	"driver": {
+		model.RoutePlanStatusActive,
+		model.RoutePlanStatusCompleted,
+		model.RoutePlanStatusOnConfirmation,
+	},
+	"planner": {
+		model.RoutePlanStatusPlanned,
+		model.RoutePlanStatusCancelled,
+	},
+	"management": {
+		model.RoutePlanStatusPlanned,
+		model.RoutePlanStatusActive,
+		model.RoutePlanStatusCompleted,
+		model.RoutePlanStatusOnConfirmation,
+		model.RoutePlanStatusCancelled,
+	},
+}
+
+// checkStatusTransition reports whether a route plan may move from its current status to the new one
+func checkStatusTransition(routePlan *model.RoutePlan, status string) error {
+	if routePlan.Status == status {
+		return fmt.Errorf("route plan is already %s", status)
+	}
+	if !containsStatus(routePlanTransitions[routePlan.Status], status) {
+		return fmt.Errorf("cannot change route plan status from %s to %s", routePlan.Status, status)
+	}
+	return nil
+}
+
+// checkStatusPermission reports whether a user may set the status of a route plan
+func checkStatusPermission(routePlan *model.RoutePlan, status string, userID uint, role string) error {
+	if !containsStatus(routePlanRoleStatuses[role], status) {
+		return fmt.Errorf("%s is %w to set route plan status to %s", role, ErrNotAllowed, status)
+	}
+	if role == "driver" && routePlan.DriverID != userID {
+		return fmt.Errorf("driver is %w to change the status of another driver's route plan", ErrNotAllowed)
+	}
+	return nil
+}
+
+// containsStatus reports whether status is in the list
+func containsStatus(statuses []string, status string) bool {
+	for _, candidate := range statuses {
+		if candidate == status {
+			return true
+		}
+	}
+	return false
+}
+
+// routePlanStatusUpdater applies route plan status changes for manual and automatic transitions
+type routePlanStatusUpdater struct {
+	routePlanRepo repository.RoutePlanRepository
+	historyRepo   repository.RoutePlanStatusHistoryRepository
+	routeCache    *RouteGeometryCache
+}
+
+// apply changes the status of a route plan, records it in the status history and
+// notifies the route subscribers. changedByID is nil for automatic transitions.
+func (u *routePlanStatusUpdater) apply(routePlan *model.RoutePlan, status, source, reason string, changedByID *uint) error {
+	if err := checkStatusTransition(routePlan, status); err != nil {
+		return err
+	}
+
+	// Satu truk dan satu driver hanya boleh punya satu rute aktif
+	if status == model.RoutePlanStatusActive {
+		if err := u.checkActiveConflict(routePlan); err != nil {
+			return err
+		}
+	}
+
+	from := routePlan.Status
+	now := time.Now()
+
+	routePlan.Status = status
+	routePlan.UpdatedAt = now
+	switch status {
+	case model.RoutePlanStatusActive:
+		if routePlan.StartedAt == nil {
+			routePlan.StartedAt = &now
+		}
+	case model.RoutePlanStatusCompleted:
+		routePlan.CompletedAt = &now
+	}
+
+	if err := u.routePlanRepo.Update(routePlan); err != nil {
+		routePlan.Status = from
+		if repository.IsUniqueViolation(err, "idx_route_plans_active_") {
+			// Another route plan became active at the same time
+			return fmt.Errorf("%w: the truck or driver already has an active route plan", ErrRoutePlanConflict)
+		}
+		return err
+	}
+
+	// The truck may have gained or lost its active route
+	u.routeCache.InvalidateRoutePlan(routePlan)
+
+	history := &model.RoutePlanStatusHistory{
+		RoutePlanID: routePlan.ID,
+		FromStatus:  from,
+		ToStatus:    status,
+		Source:      source,
+		Reason:      reason,
+		ChangedByID: changedByID,
+		CreatedAt:   now,
+	}
+	if err := u.historyRepo.Create(history); err != nil {
+		// The status itself has changed, a missing history row must not undo that
+		log.Printf("Failed to record status history of route plan %d: %v", routePlan.ID, err)
+	}
+
+	log.Printf("Route plan %d changed from %s to %s (%s): %s", routePlan.ID, from, status, source, reason)
+	u.publish(history)
+	return nil
+}
+
+// checkActiveConflict returns ErrRoutePlanConflict when the truck or driver of a route plan is busy
+func (u *routePlanStatusUpdater) checkActiveConflict(routePlan *model.RoutePlan) error {
+	active, err := u.routePlanRepo.FindActiveByTruckOrDriver(routePlan.TruckID, routePlan.DriverID, routePlan.ID)
+	if err != nil {
+		return err
+	}
+	for _, other := range active {
+		if other.TruckID == routePlan.TruckID {
+			return fmt.Errorf("%w: truck already has active route plan %d", ErrRoutePlanConflict, other.ID)
+		}
+		return fmt.Errorf("%w: driver already has active route plan %d", ErrRoutePlanConflict, other.ID)
+	}
+	return nil
+}
+
+// publish sends a status change to the route and fleet WebSocket subscribers. Drivers may lose
+// access to the route and its truck with the new status, so their subscriptions are rechecked.
+func (u *routePlanStatusUpdater) publish(history *model.RoutePlanStatusHistory) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"type":   "route_plan_status",
+		"change": history.ToRoutePlanStatusHistoryResponse(),
+	})
+	if err != nil {
+		log.Printf("Error marshaling route plan status update: %v", err)
+		return
+	}
+
+	wsHub.PublishAccessChange(jsonData, websocket.RouteChannel(history.RoutePlanID), websocket.ChannelFleet)
+}
+
+// publishRoutePlanDeleted tells the route and fleet WebSocket subscribers that a route plan is gone,
+// which also ends the subscriptions drivers held through it
+func publishRoutePlanDeleted(routePlan *model.RoutePlan) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"type":          "route_plan_deleted",
+		"route_plan_id": routePlan.ID,
+		"truck_id":      routePlan.TruckID,
+		"driver_id":     routePlan.DriverID,
+	})
+	if err != nil {
+		log.Printf("Error marshaling route plan deletion: %v", err)
+		return
+	}
+
+	wsHub.PublishAccessChange(jsonData, websocket.RouteChannel(routePlan.ID), websocket.ChannelFleet)
+}
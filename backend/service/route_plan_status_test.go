@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+func TestCheckStatusTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{model.RoutePlanStatusPlanned, model.RoutePlanStatusActive, true},
+		{model.RoutePlanStatusPlanned, model.RoutePlanStatusOnConfirmation, true},
+		{model.RoutePlanStatusPlanned, model.RoutePlanStatusCancelled, true},
+		{model.RoutePlanStatusPlanned, model.RoutePlanStatusCompleted, false},
+		{model.RoutePlanStatusPlanned, model.RoutePlanStatusPlanned, false},
+		{model.RoutePlanStatusActive, model.RoutePlanStatusCompleted, true},
+		{model.RoutePlanStatusActive, model.RoutePlanStatusOnConfirmation, true},
+		{model.RoutePlanStatusActive, model.RoutePlanStatusCancelled, true},
+		{model.RoutePlanStatusActive, model.RoutePlanStatusPlanned, false},
+		{model.RoutePlanStatusOnConfirmation, model.RoutePlanStatusPlanned, true},
+		{model.RoutePlanStatusOnConfirmation, model.RoutePlanStatusActive, true},
+		{model.RoutePlanStatusOnConfirmation, model.RoutePlanStatusCompleted, false},
+		{model.RoutePlanStatusCompleted, model.RoutePlanStatusActive, false},
+		{model.RoutePlanStatusCompleted, model.RoutePlanStatusCancelled, false},
+		{model.RoutePlanStatusCancelled, model.RoutePlanStatusPlanned, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			err := checkStatusTransition(&model.RoutePlan{Status: tt.from}, tt.to)
+			if (err == nil) != tt.allowed {
+				t.Errorf("checkStatusTransition(%s, %s) = %v, want allowed %v", tt.from, tt.to, err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestCheckStatusPermission(t *testing.T) {
+	routePlan := &model.RoutePlan{DriverID: 7}
+
+	tests := []struct {
+		name    string
+		role    string
+		userID  uint
+		status  string
+		allowed bool
+	}{
+		{"driver starts own route", "driver", 7, model.RoutePlanStatusActive, true},
+		{"driver completes own route", "driver", 7, model.RoutePlanStatusCompleted, true},
+		{"driver asks confirmation", "driver", 7, model.RoutePlanStatusOnConfirmation, true},
+		{"driver cannot cancel", "driver", 7, model.RoutePlanStatusCancelled, false},
+		{"driver cannot start another driver's route", "driver", 8, model.RoutePlanStatusActive, false},
+		{"planner cancels", "planner", 1, model.RoutePlanStatusCancelled, true},
+		{"planner replans", "planner", 1, model.RoutePlanStatusPlanned, true},
+		{"planner cannot start", "planner", 1, model.RoutePlanStatusActive, false},
+		{"management completes", "management", 1, model.RoutePlanStatusCompleted, true},
+		{"management cancels", "management", 1, model.RoutePlanStatusCancelled, true},
+		{"unknown role", "guest", 1, model.RoutePlanStatusPlanned, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkStatusPermission(routePlan, tt.status, tt.userID, tt.role)
+			if (err == nil) != tt.allowed {
+				t.Fatalf("checkStatusPermission() = %v, want allowed %v", err, tt.allowed)
+			}
+			if err != nil && !errors.Is(err, ErrNotAllowed) {
+				t.Errorf("error %v does not wrap ErrNotAllowed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
+)
+
+const (
+	// progressMaxOffRoute is the distance in meters beyond which a position does not move progress
+	progressMaxOffRoute = 500.0
+
+	// progressMaxSpeed bounds how fast progress may advance, in m/s, so a projection onto
+	// a later part of a route that passes close to itself is ignored
+	progressMaxSpeed = 40.0
+
+	// progressJumpSlack is the advance in meters always allowed on top of progressMaxSpeed
+	progressJumpSlack = 200.0
+
+	// progressSpeedWindow is how far back samples are used to estimate the speed along the route
+	progressSpeedWindow = 5 * time.Minute
+
+	// progressMinSpeedSpan is the minimum time covered by samples before a speed is estimated
+	progressMinSpeedSpan = 30 * time.Second
+
+	// progressMinSpeed is the speed in m/s below which no ETA is given
+	progressMinSpeed = 1.0
+)
+
+// RouteProgressService menghitung progres truk di sepanjang rute aktif beserta ETA
+type RouteProgressService interface {
+	ProcessPosition(macID string, latitude, longitude float64, timestamp time.Time) error
+	GetProgress(routePlanID uint) (*model.RouteProgressResponse, error)
+}
+
+type routeProgressService struct {
+	progressRepo  repository.RouteProgressRepository
+	truckRepo     repository.TruckRepository
+	routePlanRepo repository.RoutePlanRepository
+	routeCache    *RouteGeometryCache
+	states        map[uint]*progressState // Per truck ID
+	stateMutex    sync.Mutex
+}
+
+// progressState is the progress of a truck along the geometry of its active route plan
+type progressState struct {
+	RoutePlanID uint
+	Geometry    string
+	Index       *utils.RouteIndex
+	Waypoints   []waypointMark
+	Travelled   float64
+	LastTime    time.Time
+	Samples     []progressSample
+}
+
+// waypointMark is a waypoint with its position along the route
+type waypointMark struct {
+	ID    uint
+	Along float64
+}
+
+// progressSample is the progress at one position, used to estimate the speed
+type progressSample struct {
+	Time      time.Time
+	Travelled float64
+}
+
+// NewRouteProgressService creates a new instance of RouteProgressService
+func NewRouteProgressService(
+	progressRepo repository.RouteProgressRepository,
+	truckRepo repository.TruckRepository,
+	routePlanRepo repository.RoutePlanRepository,
+	routeCache *RouteGeometryCache,
+) RouteProgressService {
+	return &routeProgressService{
+		progressRepo:  progressRepo,
+		truckRepo:     truckRepo,
+		routePlanRepo: routePlanRepo,
+		routeCache:    routeCache,
+		states:        make(map[uint]*progressState),
+	}
+}
+
+// ProcessPosition projects a position onto the active route of the truck and updates
+// its progress, distance remaining and ETAs
+func (s *routeProgressService) ProcessPosition(macID string, latitude, longitude float64, timestamp time.Time) error {
+	truck, err := s.truckRepo.FindByMacID(macID)
+	if err != nil {
+		return err
+	}
+
+	route, err := s.routeCache.activeRoute(truck.ID)
+	if err != nil {
+		return err
+	}
+	if route.Plan == nil || len(route.Index.Points()) < 2 {
+		// No active route plan, nothing to track
+		s.stateMutex.Lock()
+		delete(s.states, truck.ID)
+		s.stateMutex.Unlock()
+		return nil
+	}
+
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+
+	state, err := s.stateFor(truck.ID, route)
+	if err != nil {
+		return err
+	}
+	if !state.LastTime.IsZero() && !timestamp.After(state.LastTime) {
+		// Out of order or duplicate position
+		return nil
+	}
+
+	point := utils.LatLng{Lat: latitude, Lng: longitude}
+	distance, reference, segmentIndex := route.Nearest(point)
+	along := route.Index.DistanceAlong(segmentIndex, reference)
+
+	// Progress only moves forward, and only by a distance the truck could have driven
+	if distance <= progressMaxOffRoute && along > state.Travelled {
+		allowed := math.Inf(1)
+		if !state.LastTime.IsZero() {
+			allowed = timestamp.Sub(state.LastTime).Seconds()*progressMaxSpeed + progressJumpSlack
+		}
+		if along-state.Travelled <= allowed {
+			state.Travelled = along
+		}
+	}
+	state.LastTime = timestamp
+
+	// Keep the samples of the speed window
+	state.Samples = append(state.Samples, progressSample{Time: timestamp, Travelled: state.Travelled})
+	for len(state.Samples) > 1 && timestamp.Sub(state.Samples[0].Time) > progressSpeedWindow {
+		state.Samples = state.Samples[1:]
+	}
+
+	progress := &model.RouteProgress{
+		RoutePlanID:       route.Plan.ID,
+		TruckID:           truck.ID,
+		MacID:             macID,
+		DistanceTravelled: state.Travelled,
+		TotalDistance:     route.Index.Length(),
+		SegmentIndex:      segmentIndex,
+		Speed:             state.speed(),
+		Timestamp:         timestamp,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+
+	// Waypoint berikutnya adalah waypoint pertama yang belum dilewati
+	for _, mark := range state.Waypoints {
+		if mark.Along > state.Travelled+WaypointArrivalRadius {
+			id := mark.ID
+			progress.NextWaypointID = &id
+			progress.NextWaypointDistance = mark.Along - state.Travelled
+			break
+		}
+	}
+
+	if progress.Speed >= progressMinSpeed {
+		remaining := math.Max(progress.TotalDistance-state.Travelled, 0)
+		destinationETA := timestamp.Add(time.Duration(remaining / progress.Speed * float64(time.Second)))
+		progress.DestinationETA = &destinationETA
+		if progress.NextWaypointID != nil {
+			waypointETA := timestamp.Add(time.Duration(progress.NextWaypointDistance / progress.Speed * float64(time.Second)))
+			progress.NextWaypointETA = &waypointETA
+		}
+	}
+
+	if err := s.progressRepo.Save(progress); err != nil {
+		return err
+	}
+
+	s.publishProgress(progress)
+	return nil
+}
+
+// GetProgress returns the latest progress of a route plan
+func (s *routeProgressService) GetProgress(routePlanID uint) (*model.RouteProgressResponse, error) {
+	progress, err := s.progressRepo.FindByRoutePlanID(routePlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := progress.ToRouteProgressResponse()
+	return &response, nil
+}
+
+// stateFor returns the progress state of a truck for its current route geometry. A new
+// route plan or geometry starts from scratch; after a restart progress resumes from the
+// stored value as long as the route length is unchanged. Must hold stateMutex.
+func (s *routeProgressService) stateFor(truckID uint, route *cachedRoute) (*progressState, error) {
+	state := s.states[truckID]
+	if state != nil && state.RoutePlanID == route.Plan.ID && state.Index == route.Index {
+		return state, nil
+	}
+
+	waypoints, err := s.routePlanRepo.FindWaypointsByRoutePlanID(route.Plan.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	fresh := &progressState{
+		RoutePlanID: route.Plan.ID,
+		Geometry:    route.Plan.RouteGeometry,
+		Index:       route.Index,
+		Waypoints:   markWaypoints(route.Index, waypoints),
+	}
+
+	if state != nil && state.RoutePlanID == fresh.RoutePlanID && state.Geometry == fresh.Geometry {
+		// Only the cache was reloaded, keep going
+		fresh.Travelled = state.Travelled
+		fresh.LastTime = state.LastTime
+		fresh.Samples = state.Samples
+	} else if state == nil {
+		stored, err := s.progressRepo.FindByRoutePlanID(route.Plan.ID)
+		if err == nil && math.Abs(stored.TotalDistance-route.Index.Length()) < 1 {
+			fresh.Travelled = stored.DistanceTravelled
+		}
+	}
+
+	s.states[truckID] = fresh
+	return fresh, nil
+}
+
+// speed estimates the speed along the route in m/s from the recent samples
+func (p *progressState) speed() float64 {
+	if len(p.Samples) < 2 {
+		return 0
+	}
+
+	first, last := p.Samples[0], p.Samples[len(p.Samples)-1]
+	span := last.Time.Sub(first.Time)
+	if span < progressMinSpeedSpan {
+		return 0
+	}
+	return (last.Travelled - first.Travelled) / span.Seconds()
+}
+
+// markWaypoints projects the waypoints onto the route in order. Each waypoint is searched
+// from the previous one onwards so a route passing a place twice keeps the visit order.
+func markWaypoints(index *utils.RouteIndex, waypoints []*model.RouteWaypoint) []waypointMark {
+	points := index.Points()
+	marks := make([]waypointMark, 0, len(waypoints))
+
+	from := 0
+	for _, waypoint := range waypoints {
+		position := utils.LatLng{Lat: waypoint.Latitude, Lng: waypoint.Longitude}
+		_, reference, segmentIndex := utils.CalculateDistanceToPolyline(position, points[from:])
+		if segmentIndex < 0 {
+			break
+		}
+		segmentIndex += from
+
+		marks = append(marks, waypointMark{
+			ID:    waypoint.ID,
+			Along: index.DistanceAlong(segmentIndex, reference),
+		})
+		from = segmentIndex
+	}
+	return marks
+}
+
+// publishProgress sends a progress update to the route and truck WebSocket subscribers
+func (s *routeProgressService) publishProgress(progress *model.RouteProgress) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"type":     "route_progress",
+		"mac_id":   progress.MacID,
+		"progress": progress.ToRouteProgressResponse(),
+	})
+	if err != nil {
+		log.Printf("Error marshaling route progress update: %v", err)
+		return
+	}
+
+	wsHub.Publish(jsonData, websocket.RouteChannel(progress.RoutePlanID), websocket.TruckChannel(progress.MacID))
+}
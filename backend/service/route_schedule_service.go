@@ -0,0 +1,344 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
+)
+
+// RouteScheduleWatchdogInterval is how often active route plans are checked for late arrivals
+const RouteScheduleWatchdogInterval = time.Minute
+
+// RouteScheduleService membandingkan jadwal route plan dengan kunjungan sebenarnya
+type RouteScheduleService interface {
+	GetOnTimeReport(start, end time.Time, driverID *uint) (*model.OnTimeReportResponse, error)
+	CheckLateArrivals(now time.Time) (int, error)
+	StartWatchdog(interval time.Duration)
+}
+
+type routeScheduleService struct {
+	routePlanRepo repository.RoutePlanRepository
+	visitRepo     repository.WaypointVisitRepository
+	progressRepo  repository.RouteProgressRepository
+	truckRepo     repository.TruckRepository
+	userRepo      repository.UserRepository
+}
+
+// NewRouteScheduleService creates a new instance of RouteScheduleService
+func NewRouteScheduleService(
+	routePlanRepo repository.RoutePlanRepository,
+	visitRepo repository.WaypointVisitRepository,
+	progressRepo repository.RouteProgressRepository,
+	truckRepo repository.TruckRepository,
+	userRepo repository.UserRepository,
+) RouteScheduleService {
+	return &routeScheduleService{
+		routePlanRepo: routePlanRepo,
+		visitRepo:     visitRepo,
+		progressRepo:  progressRepo,
+		truckRepo:     truckRepo,
+		userRepo:      userRepo,
+	}
+}
+
+// GetOnTimeReport returns the punctuality of the route plans scheduled between two dates,
+// per route and per driver. Cancelled route plans are left out.
+func (s *routeScheduleService) GetOnTimeReport(start, end time.Time, driverID *uint) (*model.OnTimeReportResponse, error) {
+	routePlans, err := s.routePlanRepo.FindByScheduleRange(start, end, driverID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.OnTimeReportResponse{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+		Drivers:   []*model.DriverOnTimeReport{},
+		Routes:    []*model.RouteOnTimeReport{},
+	}
+
+	drivers := make(map[uint]*model.DriverOnTimeReport)
+	delayTotals := make(map[uint]float64)
+	now := time.Now()
+	for _, routePlan := range routePlans {
+		if routePlan.Status == model.RoutePlanStatusCancelled {
+			continue
+		}
+
+		waypoints, err := s.routePlanRepo.FindWaypointsByRoutePlanID(routePlan.ID)
+		if err != nil {
+			return nil, err
+		}
+		visits, err := s.visitRepo.FindByRoutePlanID(routePlan.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		routeReport := evaluateRoutePunctuality(routePlan, waypoints, visits, now)
+		routeReport.DriverName = s.driverName(routePlan.DriverID)
+		report.Routes = append(report.Routes, routeReport)
+
+		driver, ok := drivers[routePlan.DriverID]
+		if !ok {
+			driver = &model.DriverOnTimeReport{
+				DriverID:   routePlan.DriverID,
+				DriverName: routeReport.DriverName,
+			}
+			drivers[routePlan.DriverID] = driver
+			report.Drivers = append(report.Drivers, driver)
+		}
+		driver.Routes++
+		driver.Counts.OnTime += routeReport.Counts.OnTime
+		driver.Counts.Early += routeReport.Counts.Early
+		driver.Counts.Late += routeReport.Counts.Late
+		driver.Counts.Missed += routeReport.Counts.Missed
+		for _, waypoint := range routeReport.Waypoints {
+			delayTotals[routePlan.DriverID] += waypoint.DelayMinutes
+		}
+	}
+
+	for _, driver := range report.Drivers {
+		driver.Counts.OnTimeRate = onTimeRate(driver.Counts)
+		if driver.Counts.Late > 0 {
+			driver.AvgDelayMinutes = delayTotals[driver.DriverID] / float64(driver.Counts.Late)
+		}
+	}
+
+	return report, nil
+}
+
+// evaluateRoutePunctuality compares the first visit of every waypoint that has a time window
+// with that window, and the actual start and completion with the planned ones
+func evaluateRoutePunctuality(routePlan *model.RoutePlan, waypoints []*model.RouteWaypoint, visits []*model.WaypointVisit, now time.Time) *model.RouteOnTimeReport {
+	report := &model.RouteOnTimeReport{
+		RoutePlanID:       routePlan.ID,
+		DriverID:          routePlan.DriverID,
+		Status:            routePlan.Status,
+		PlannedStartAt:    routePlan.PlannedStartAt,
+		StartedAt:         routePlan.StartedAt,
+		StartDelayMinutes: delayMinutes(routePlan.PlannedStartAt, routePlan.StartedAt),
+		PlannedEndAt:      routePlan.PlannedEndAt,
+		CompletedAt:       routePlan.CompletedAt,
+		EndDelayMinutes:   delayMinutes(routePlan.PlannedEndAt, routePlan.CompletedAt),
+		Waypoints:         []model.WaypointPunctuality{},
+	}
+
+	// Visits are in arrival order, the first one counts
+	arrivals := make(map[uint]time.Time)
+	for _, visit := range visits {
+		if _, ok := arrivals[visit.WaypointID]; !ok {
+			arrivals[visit.WaypointID] = visit.ArrivalTime
+		}
+	}
+
+	finished := routePlan.Status == model.RoutePlanStatusCompleted
+	for _, waypoint := range waypoints {
+		if waypoint.EarliestArrival == nil && waypoint.LatestArrival == nil {
+			continue
+		}
+
+		punctuality := model.WaypointPunctuality{
+			WaypointID:      waypoint.ID,
+			Order:           waypoint.Order,
+			Address:         waypoint.Address,
+			EarliestArrival: waypoint.EarliestArrival,
+			LatestArrival:   waypoint.LatestArrival,
+		}
+
+		if arrival, ok := arrivals[waypoint.ID]; ok {
+			arrivalTime := arrival
+			punctuality.ArrivalTime = &arrivalTime
+			switch {
+			case waypoint.LatestArrival != nil && arrival.After(*waypoint.LatestArrival):
+				punctuality.Result = model.PunctualityLate
+				punctuality.DelayMinutes = arrival.Sub(*waypoint.LatestArrival).Minutes()
+			case waypoint.EarliestArrival != nil && arrival.Before(*waypoint.EarliestArrival):
+				punctuality.Result = model.PunctualityEarly
+			default:
+				punctuality.Result = model.PunctualityOnTime
+			}
+		} else if finished {
+			punctuality.Result = model.PunctualityMissed
+		} else if waypoint.LatestArrival != nil && now.After(*waypoint.LatestArrival) {
+			// Belum datang dan sudah lewat batas waktu
+			punctuality.Result = model.PunctualityLate
+			punctuality.DelayMinutes = now.Sub(*waypoint.LatestArrival).Minutes()
+		} else {
+			punctuality.Result = model.PunctualityPending
+		}
+
+		switch punctuality.Result {
+		case model.PunctualityOnTime:
+			report.Counts.OnTime++
+		case model.PunctualityEarly:
+			report.Counts.Early++
+		case model.PunctualityLate:
+			report.Counts.Late++
+		case model.PunctualityMissed:
+			report.Counts.Missed++
+		}
+		report.Waypoints = append(report.Waypoints, punctuality)
+	}
+
+	report.Counts.OnTimeRate = onTimeRate(report.Counts)
+	return report
+}
+
+// driverName returns the name of a driver, or an empty string when the driver is unknown
+func (s *routeScheduleService) driverName(driverID uint) string {
+	driver, err := s.userRepo.FindByID(driverID)
+	if err != nil || driver == nil {
+		return ""
+	}
+	return driver.Name
+}
+
+// onTimeRate returns the percentage of judged arrivals that were not late
+func onTimeRate(counts model.PunctualityCounts) float64 {
+	judged := counts.OnTime + counts.Early + counts.Late + counts.Missed
+	if judged == 0 {
+		return 0
+	}
+	return float64(counts.OnTime+counts.Early) / float64(judged) * 100
+}
+
+// delayMinutes returns how many minutes actual is after planned, nil when either is unknown
+func delayMinutes(planned, actual *time.Time) *float64 {
+	if planned == nil || actual == nil {
+		return nil
+	}
+	delay := actual.Sub(*planned).Minutes()
+	return &delay
+}
+
+// StartWatchdog periodically checks active route plans for late arrivals
+func (s *routeScheduleService) StartWatchdog(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.CheckLateArrivals(time.Now()); err != nil {
+				log.Printf("Error checking late arrivals: %v", err)
+			}
+		}
+	}()
+}
+
+// CheckLateArrivals alerts once per waypoint of an active route plan when the truck has not
+// arrived by the latest arrival time, or its ETA to the next waypoint is already past it
+func (s *routeScheduleService) CheckLateArrivals(now time.Time) (int, error) {
+	routePlans, err := s.routePlanRepo.FindAllActiveRoutePlans()
+	if err != nil {
+		return 0, err
+	}
+
+	alerts := 0
+	for _, routePlan := range routePlans {
+		waypoints, err := s.routePlanRepo.FindWaypointsByRoutePlanID(routePlan.ID)
+		if err != nil {
+			continue
+		}
+		visits, err := s.visitRepo.FindByRoutePlanID(routePlan.ID)
+		if err != nil {
+			continue
+		}
+		progress, _ := s.progressRepo.FindByRoutePlanID(routePlan.ID)
+
+		visited := make(map[uint]bool)
+		for _, visit := range visits {
+			visited[visit.WaypointID] = true
+		}
+
+		for _, waypoint := range waypoints {
+			if waypoint.LatestArrival == nil || waypoint.LateAlertSentAt != nil || visited[waypoint.ID] {
+				continue
+			}
+
+			var eta *time.Time
+			if progress != nil && progress.NextWaypointID != nil && *progress.NextWaypointID == waypoint.ID {
+				eta = progress.NextWaypointETA
+			}
+			late := now.After(*waypoint.LatestArrival)
+			predicted := !late && eta != nil && eta.After(*waypoint.LatestArrival)
+			if !late && !predicted {
+				continue
+			}
+
+			sentAt := now
+			waypoint.LateAlertSentAt = &sentAt
+			if err := s.routePlanRepo.UpdateWaypoint(waypoint); err != nil {
+				log.Printf("Failed to mark late alert of waypoint %d: %v", waypoint.ID, err)
+				continue
+			}
+			alerts++
+
+			log.Printf("Route plan %d is late for waypoint %d (predicted: %t)", routePlan.ID, waypoint.Order, predicted)
+			s.publishLateArrival(routePlan, waypoint, eta, predicted)
+			s.sendLateArrivalNotification(routePlan, waypoint, eta, predicted)
+		}
+	}
+
+	return alerts, nil
+}
+
+// publishLateArrival sends a late arrival alert to the alert and route subscribers
+func (s *routeScheduleService) publishLateArrival(routePlan *model.RoutePlan, waypoint *model.RouteWaypoint, eta *time.Time, predicted bool) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"type":           "waypoint_late",
+		"route_plan_id":  routePlan.ID,
+		"truck_id":       routePlan.TruckID,
+		"driver_id":      routePlan.DriverID,
+		"waypoint_id":    waypoint.ID,
+		"waypoint_order": waypoint.Order,
+		"address":        waypoint.Address,
+		"latest_arrival": waypoint.LatestArrival,
+		"eta":            eta,
+		"predicted":      predicted, // true jika belum lewat batas waktu tetapi ETA sudah melewatinya
+	})
+	if err != nil {
+		log.Printf("Error marshaling late arrival alert: %v", err)
+		return
+	}
+
+	wsHub.Publish(jsonData, websocket.ChannelAlerts, websocket.RouteChannel(routePlan.ID))
+}
+
+// sendLateArrivalNotification sends a push notification about a late arrival
+func (s *routeScheduleService) sendLateArrivalNotification(routePlan *model.RoutePlan, waypoint *model.RouteWaypoint, eta *time.Time, predicted bool) {
+	truck, err := s.truckRepo.FindByID(routePlan.TruckID)
+	if err != nil {
+		return
+	}
+	plateNumber := truck.PlateNumber
+	if plateNumber == "" {
+		plateNumber = truck.MacID
+	}
+
+	stop := fmt.Sprintf("stop %d", waypoint.Order+1)
+	if waypoint.Address != "" {
+		stop = fmt.Sprintf("%s (%s)", stop, waypoint.Address)
+	}
+
+	var message string
+	if predicted {
+		message = fmt.Sprintf("Vehicle %s is expected at %s at %s, after the latest arrival time %s.",
+			plateNumber, stop, eta.Format("15:04"), waypoint.LatestArrival.Format("15:04"))
+	} else {
+		message = fmt.Sprintf("Vehicle %s has not arrived at %s by the latest arrival time %s.",
+			plateNumber, stop, waypoint.LatestArrival.Format("15:04"))
+	}
+
+	if err := postPushNotification(truck, "Late Arrival", message, routePlan.DriverID); err != nil {
+		log.Printf("Error sending late arrival notification: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+func TestEvaluateRoutePunctuality(t *testing.T) {
+	base := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		value := base.Add(time.Duration(minutes) * time.Minute)
+		return &value
+	}
+	window := func(id uint, earliest, latest *time.Time) *model.RouteWaypoint {
+		return &model.RouteWaypoint{ID: id, Order: int(id), EarliestArrival: earliest, LatestArrival: latest}
+	}
+	visit := func(id uint, minutes int) *model.WaypointVisit {
+		return &model.WaypointVisit{WaypointID: id, ArrivalTime: *at(minutes)}
+	}
+
+	tests := []struct {
+		name      string
+		status    string
+		waypoints []*model.RouteWaypoint
+		visits    []*model.WaypointVisit
+		now       *time.Time
+		want      []string
+		wantDelay []float64
+		wantRate  float64
+	}{
+		{
+			name:      "on time inside the window",
+			status:    model.RoutePlanStatusActive,
+			waypoints: []*model.RouteWaypoint{window(1, at(30), at(60))},
+			visits:    []*model.WaypointVisit{visit(1, 45)},
+			now:       at(50),
+			want:      []string{model.PunctualityOnTime},
+			wantDelay: []float64{0},
+			wantRate:  100,
+		},
+		{
+			name:      "early and late",
+			status:    model.RoutePlanStatusActive,
+			waypoints: []*model.RouteWaypoint{window(1, at(30), at(60)), window(2, nil, at(90))},
+			visits:    []*model.WaypointVisit{visit(1, 10), visit(2, 105)},
+			now:       at(110),
+			want:      []string{model.PunctualityEarly, model.PunctualityLate},
+			wantDelay: []float64{0, 15},
+			wantRate:  50,
+		},
+		{
+			name:      "first visit counts",
+			status:    model.RoutePlanStatusActive,
+			waypoints: []*model.RouteWaypoint{window(1, nil, at(60))},
+			visits:    []*model.WaypointVisit{visit(1, 50), visit(1, 80)},
+			now:       at(90),
+			want:      []string{model.PunctualityOnTime},
+			wantDelay: []float64{0},
+			wantRate:  100,
+		},
+		{
+			name:      "not visited yet",
+			status:    model.RoutePlanStatusActive,
+			waypoints: []*model.RouteWaypoint{window(1, nil, at(60))},
+			now:       at(40),
+			want:      []string{model.PunctualityPending},
+			wantDelay: []float64{0},
+			wantRate:  0,
+		},
+		{
+			name:      "not visited past the window",
+			status:    model.RoutePlanStatusActive,
+			waypoints: []*model.RouteWaypoint{window(1, nil, at(60))},
+			now:       at(75),
+			want:      []string{model.PunctualityLate},
+			wantDelay: []float64{15},
+			wantRate:  0,
+		},
+		{
+			name:      "missed on a completed route",
+			status:    model.RoutePlanStatusCompleted,
+			waypoints: []*model.RouteWaypoint{window(1, nil, at(60)), window(2, nil, at(90))},
+			visits:    []*model.WaypointVisit{visit(2, 80)},
+			now:       at(120),
+			want:      []string{model.PunctualityMissed, model.PunctualityOnTime},
+			wantDelay: []float64{0, 0},
+			wantRate:  50,
+		},
+		{
+			name:      "waypoints without a window are skipped",
+			status:    model.RoutePlanStatusActive,
+			waypoints: []*model.RouteWaypoint{window(1, nil, nil), window(2, at(0), nil)},
+			visits:    []*model.WaypointVisit{visit(1, 5), visit(2, 5)},
+			now:       at(10),
+			want:      []string{model.PunctualityOnTime},
+			wantDelay: []float64{0},
+			wantRate:  100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routePlan := &model.RoutePlan{ID: 1, DriverID: 2, Status: tt.status}
+			report := evaluateRoutePunctuality(routePlan, tt.waypoints, tt.visits, *tt.now)
+
+			if len(report.Waypoints) != len(tt.want) {
+				t.Fatalf("got %d waypoints, want %d", len(report.Waypoints), len(tt.want))
+			}
+			for i, waypoint := range report.Waypoints {
+				if waypoint.Result != tt.want[i] {
+					t.Errorf("waypoint %d result = %s, want %s", i, waypoint.Result, tt.want[i])
+				}
+				if waypoint.DelayMinutes != tt.wantDelay[i] {
+					t.Errorf("waypoint %d delay = %v, want %v", i, waypoint.DelayMinutes, tt.wantDelay[i])
+				}
+			}
+			if report.Counts.OnTimeRate != tt.wantRate {
+				t.Errorf("on time rate = %v, want %v", report.Counts.OnTimeRate, tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestEvaluateRoutePunctualityStartAndEnd(t *testing.T) {
+	planned := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	plannedEnd := planned.Add(4 * time.Hour)
+	started := planned.Add(20 * time.Minute)
+	completed := plannedEnd.Add(-10 * time.Minute)
+
+	tests := []struct {
+		name      string
+		routePlan *model.RoutePlan
+		wantStart *float64
+		wantEnd   *float64
+	}{
+		{"not planned", &model.RoutePlan{StartedAt: &started}, nil, nil},
+		{"not started", &model.RoutePlan{PlannedStartAt: &planned, PlannedEndAt: &plannedEnd}, nil, nil},
+		{"late start, early end", &model.RoutePlan{PlannedStartAt: &planned, PlannedEndAt: &plannedEnd, StartedAt: &started, CompletedAt: &completed},
+			floatPtr(20), floatPtr(-10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := evaluateRoutePunctuality(tt.routePlan, nil, nil, plannedEnd)
+			if !equalFloatPtr(report.StartDelayMinutes, tt.wantStart) {
+				t.Errorf("start delay = %v, want %v", report.StartDelayMinutes, tt.wantStart)
+			}
+			if !equalFloatPtr(report.EndDelayMinutes, tt.wantEnd) {
+				t.Errorf("end delay = %v, want %v", report.EndDelayMinutes, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+func equalFloatPtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+const (
+	// avoidanceSearchMargin is how far in degrees (about 11 km) around the requested coordinates
+	// approved avoidance areas are applied. Areas further away cannot shape the route and ORS
+	// limits the total size of avoid polygons.
+	avoidanceSearchMargin = 0.1
+
+	// AvoidanceAffectDistance is how close in meters a route must pass an avoidance area for the
+	// area to count as having shaped the route
+	AvoidanceAffectDistance = 100.0
+)
+
+// loadApprovedAvoidanceAreas returns the approved permanent avoidance areas and the approved areas
+// of a route plan with their polygons. routePlanID 0 returns only the permanent areas.
+func loadApprovedAvoidanceAreas(routePlanRepo repository.RoutePlanRepository, routePlanID uint) ([]routeAvoidanceArea, error) {
+	areas, err := routePlanRepo.FindApprovedAvoidanceAreas(routePlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	areaIDs := make([]uint, 0, len(areas))
+	for _, area := range areas {
+		areaIDs = append(areaIDs, area.ID)
+	}
+	points, err := routePlanRepo.FindAvoidancePointsByAreaIDs(areaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	polygons := make(map[uint][]utils.LatLng)
+	for _, point := range points {
+		polygons[point.RouteAvoidanceAreaID] = append(polygons[point.RouteAvoidanceAreaID],
+			utils.LatLng{Lat: point.Latitude, Lng: point.Longitude})
+	}
+
+	result := make([]routeAvoidanceArea, 0, len(areas))
+	for _, area := range areas {
+		if polygon := polygons[area.ID]; len(polygon) >= 3 {
+			result = append(result, routeAvoidanceArea{Area: area, Reason: area.Reason, Polygon: polygon})
+		}
+	}
+	return result, nil
+}
+
+// applyAvoidanceAreas merges the approved avoidance areas near the requested coordinates into
+// options.avoid_polygons as a GeoJSON MultiPolygon, next to the polygons the client sent. It
+// returns the request body to forward, without route_plan_id, and the stored areas that were merged.
+func (s *routingService) applyAvoidanceAreas(requestBody []byte, req *model.RoutingRequest) ([]byte, []routeAvoidanceArea, error) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(requestBody, &body); err != nil {
+		return nil, nil, errors.New("Invalid routing request: " + err.Error())
+	}
+	delete(body, "route_plan_id")
+
+	var applied []routeAvoidanceArea
+	if s.routePlanRepo != nil {
+		var routePlanID uint
+		if req.RoutePlanID != nil {
+			routePlanID = *req.RoutePlanID
+		}
+		areas, err := loadApprovedAvoidanceAreas(s.routePlanRepo, routePlanID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load avoidance areas: %v", err)
+		}
+		applied = areasNearCoordinates(areas, req.Coordinates)
+	}
+
+	if len(applied) > 0 {
+		rings, err := avoidPolygons(req)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Area yang juga dikirim client tidak dikirim dua kali
+		seen := make(map[string]bool)
+		for _, ring := range rings {
+			seen[ringKey(ring)] = true
+		}
+		for _, area := range applied {
+			ring := make([][]float64, 0, len(area.Polygon)+1)
+			for _, point := range area.Polygon {
+				ring = append(ring, []float64{point.Lng, point.Lat})
+			}
+			ring = append(ring, []float64{area.Polygon[0].Lng, area.Polygon[0].Lat})
+			if key := ringKey(ring); !seen[key] {
+				seen[key] = true
+				rings = append(rings, ring)
+			}
+		}
+
+		coordinates := make([][][][]float64, 0, len(rings))
+		for _, ring := range rings {
+			coordinates = append(coordinates, [][][]float64{ring})
+		}
+
+		options, _ := body["options"].(map[string]interface{})
+		if options == nil {
+			options = make(map[string]interface{})
+		}
+		options["avoid_polygons"] = map[string]interface{}{
+			"type":        "MultiPolygon",
+			"coordinates": coordinates,
+		}
+		body["options"] = options
+		req.Options = options
+	}
+
+	forwardBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return forwardBody, applied, nil
+}
+
+// areasNearCoordinates keeps the areas whose bounding box overlaps the requested coordinates plus a margin
+func areasNearCoordinates(areas []routeAvoidanceArea, coordinates [][]float64) []routeAvoidanceArea {
+	if len(coordinates) == 0 {
+		return nil
+	}
+
+	minLat, maxLat, minLng, maxLng := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, coordinate := range coordinates {
+		if len(coordinate) < 2 {
+			continue
+		}
+		minLng, maxLng = math.Min(minLng, coordinate[0]), math.Max(maxLng, coordinate[0])
+		minLat, maxLat = math.Min(minLat, coordinate[1]), math.Max(maxLat, coordinate[1])
+	}
+	minLat, maxLat = minLat-avoidanceSearchMargin, maxLat+avoidanceSearchMargin
+	minLng, maxLng = minLng-avoidanceSearchMargin, maxLng+avoidanceSearchMargin
+
+	var near []routeAvoidanceArea
+	for _, area := range areas {
+		for _, point := range area.Polygon {
+			if point.Lat >= minLat && point.Lat <= maxLat && point.Lng >= minLng && point.Lng <= maxLng {
+				near = append(near, area)
+				break
+			}
+		}
+	}
+	return near
+}
+
+// withoutAvoidanceAreas returns the request without the polygons of the stored areas that were merged
+// into it, for providers that cannot avoid polygons. It reports false when the client sent polygons of
+// its own, which must not be dropped.
+func withoutAvoidanceAreas(requestBody []byte, req *model.RoutingRequest, applied []routeAvoidanceArea) ([]byte, *model.RoutingRequest, bool, error) {
+	rings, err := avoidPolygons(req)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	stored := make(map[string]bool, len(applied))
+	for _, area := range applied {
+		ring := make([][]float64, 0, len(area.Polygon))
+		for _, point := range area.Polygon {
+			ring = append(ring, []float64{point.Lng, point.Lat})
+		}
+		stored[ringKey(ring)] = true
+	}
+	for _, ring := range rings {
+		if !stored[ringKey(ring)] {
+			return nil, nil, false, nil
+		}
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(requestBody, &body); err != nil {
+		return nil, nil, false, err
+	}
+	options := make(map[string]interface{})
+	for key, value := range req.Options {
+		if key != "avoid_polygons" {
+			options[key] = value
+		}
+	}
+	if len(options) > 0 {
+		body["options"] = options
+	} else {
+		delete(body, "options")
+		options = nil
+	}
+
+	fallbackBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	fallbackReq := *req
+	fallbackReq.Options = options
+	return fallbackBody, &fallbackReq, true, nil
+}
+
+// ringKey identifies a polygon ring regardless of whether it is closed
+func ringKey(ring [][]float64) string {
+	if len(ring) > 1 {
+		first, last := ring[0], ring[len(ring)-1]
+		if len(first) >= 2 && len(last) >= 2 && first[0] == last[0] && first[1] == last[1] {
+			ring = ring[:len(ring)-1]
+		}
+	}
+
+	parts := make([]string, 0, len(ring))
+	for _, point := range ring {
+		if len(point) >= 2 {
+			parts = append(parts, fmt.Sprintf("%.5f,%.5f", point[0], point[1]))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// annotateAvoidanceAreas adds the merged areas to the directions response as avoidance_areas,
+// telling for each whether the route passes close enough to have been shaped by it, and whether
+// the provider avoided them at all as avoidance_areas_applied
+func annotateAvoidanceAreas(responseBody []byte, applied []routeAvoidanceArea, avoided bool) ([]byte, error) {
+	if len(applied) == 0 {
+		return responseBody, nil
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(responseBody, &body); err != nil {
+		return nil, fmt.Errorf("invalid directions response: %v", err)
+	}
+
+	var points []utils.LatLng
+	if routes, ok := body["routes"].([]interface{}); ok && len(routes) > 0 {
+		if route, ok := routes[0].(map[string]interface{}); ok {
+			if geometry, ok := route["geometry"].(string); ok {
+				points, _ = utils.DecodeRouteGeometry(geometry)
+			}
+		}
+	}
+
+	report := make([]model.AppliedAvoidanceArea, 0, len(applied))
+	for _, area := range applied {
+		distance := routeDistanceToPolygon(points, area.Polygon)
+		applied := model.AppliedAvoidanceArea{
+			ID:          area.Area.ID,
+			RoutePlanID: area.Area.RoutePlanID,
+			Reason:      area.Area.Reason,
+			IsPermanent: area.Area.IsPermanent,
+			Affected:    distance <= AvoidanceAffectDistance,
+		}
+		if !math.IsInf(distance, 1) {
+			rounded := math.Round(distance*10) / 10
+			applied.DistanceMeters = &rounded
+		}
+		report = append(report, applied)
+	}
+
+	body["avoidance_areas"] = report
+	body["avoidance_areas_applied"] = avoided
+	return json.Marshal(body)
+}
+
+// routeDistanceToPolygon returns the closest distance in meters between a route and the boundary
+// of a polygon, 0 when the route enters it and +Inf when the route stays about 1 km away or is missing
+func routeDistanceToPolygon(points, polygon []utils.LatLng) float64 {
+	if len(points) == 0 {
+		return math.Inf(1)
+	}
+	if _, crossed := routeCrossesPolygon(points, polygon); crossed {
+		return 0
+	}
+
+	// Titik rute yang jauh dari kotak batas area dilewati, 0.01 derajat kira-kira 1 km
+	minLat, maxLat, minLng, maxLng := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, vertex := range polygon {
+		minLat, maxLat = math.Min(minLat, vertex.Lat), math.Max(maxLat, vertex.Lat)
+		minLng, maxLng = math.Min(minLng, vertex.Lng), math.Max(maxLng, vertex.Lng)
+	}
+	const margin = 0.01
+
+	best := math.Inf(1)
+	for _, point := range points {
+		if point.Lat < minLat-margin || point.Lat > maxLat+margin || point.Lng < minLng-margin || point.Lng > maxLng+margin {
+			continue
+		}
+		for j := range polygon {
+			if distance, _ := utils.CalculateDistanceToSegment(point, polygon[j], polygon[(j+1)%len(polygon)]); distance < best {
+				best = distance
+			}
+		}
+	}
+	return best
+}
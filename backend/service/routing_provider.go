@@ -0,0 +1,253 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+// Nama routing provider untuk ROUTING_PROVIDERS
+const (
+	RoutingProviderORS      = "ors"       // OpenRouteService publik, butuh ORS_API_KEY
+	RoutingProviderORSLocal = "ors-local" // ORS self-hosted dari routing_service/ors-docker, butuh ORS_LOCAL_URL
+	RoutingProviderOSRM     = "osrm"
+	RoutingProviderValhalla = "valhalla"
+)
+
+const (
+	// RoutingTimeout is how long a single routing backend may take to answer
+	RoutingTimeout = 15 * time.Second
+
+	defaultORSURL      = "https://api.openrouteservice.org"
+	defaultOSRMURL     = "http://localhost:5000"
+	defaultValhallaURL = "http://localhost:8002"
+)
+
+// ErrRoutingOptionUnsupported is returned by a provider that cannot honour the request,
+// e.g. avoid polygons on OSRM. The next provider is tried.
+var ErrRoutingOptionUnsupported = errors.New("routing option not supported by provider")
+
+// RoutingProvider gets directions from one routing backend. Requests use the ORS directions
+// format and responses are returned in that format, whatever the backend speaks.
+type RoutingProvider interface {
+	Name() string
+	Directions(ctx context.Context, req *model.RoutingRequest, requestBody []byte) ([]byte, error)
+}
+
+// RoutingConfig holds the routing backends to use, in order of preference
+type RoutingConfig struct {
+	Providers   []string
+	Timeout     time.Duration
+	ORSURL      string
+	ORSAPIKey   string
+	ORSLocalURL string
+	ORSProfile  string
+	OSRMURL     string
+	OSRMProfile string
+	ValhallaURL string
+	Costing     string // Valhalla costing model
+}
+
+// LoadRoutingConfig reads the routing settings from the environment. ROUTING_PROVIDERS is a comma
+// separated list such as "ors-local,ors"; the first provider is used and the others are fallbacks.
+func LoadRoutingConfig() RoutingConfig {
+	config := RoutingConfig{
+		Timeout:     time.Duration(envInt("ROUTING_TIMEOUT_SECONDS", int(RoutingTimeout/time.Second))) * time.Second,
+		ORSURL:      envString("ORS_BASE_URL", defaultORSURL),
+		ORSAPIKey:   os.Getenv("ORS_API_KEY"),
+		ORSLocalURL: os.Getenv("ORS_LOCAL_URL"),
+		ORSProfile:  envString("ORS_PROFILE", "driving-hgv"),
+		OSRMURL:     envString("OSRM_URL", defaultOSRMURL),
+		OSRMProfile: envString("OSRM_PROFILE", "driving"),
+		ValhallaURL: envString("VALHALLA_URL", defaultValhallaURL),
+		Costing:     envString("VALHALLA_COSTING", "truck"),
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = RoutingTimeout
+	}
+
+	for _, name := range strings.Split(envString("ROUTING_PROVIDERS", RoutingProviderORS), ",") {
+		if name = strings.TrimSpace(strings.ToLower(name)); name != "" {
+			config.Providers = append(config.Providers, name)
+		}
+	}
+	return config
+}
+
+// NewRoutingProviders creates the configured providers in order. Unknown names are skipped, and so is
+// ors-local without ORS_LOCAL_URL: there is no safe default, the ORS compose file publishes port 8080,
+// which is also the default port of this API.
+func NewRoutingProviders(config RoutingConfig) []RoutingProvider {
+	client := &http.Client{}
+	var providers []RoutingProvider
+
+	for _, name := range config.Providers {
+		switch name {
+		case RoutingProviderORS:
+			providers = append(providers, &orsProvider{
+				name:    RoutingProviderORS,
+				baseURL: config.ORSURL,
+				apiKey:  config.ORSAPIKey,
+				profile: config.ORSProfile,
+				client:  client,
+			})
+		case RoutingProviderORSLocal:
+			if config.ORSLocalURL == "" {
+				log.Printf("Routing provider %s needs ORS_LOCAL_URL, skipped", name)
+				continue
+			}
+			providers = append(providers, &orsProvider{
+				name:    RoutingProviderORSLocal,
+				baseURL: config.ORSLocalURL,
+				profile: config.ORSProfile,
+				client:  client,
+			})
+		case RoutingProviderOSRM:
+			providers = append(providers, &osrmProvider{
+				baseURL: config.OSRMURL,
+				profile: config.OSRMProfile,
+				client:  client,
+			})
+		case RoutingProviderValhalla:
+			providers = append(providers, &valhallaProvider{
+				baseURL: config.ValhallaURL,
+				costing: config.Costing,
+				client:  client,
+			})
+		default:
+			log.Printf("Unknown routing provider %q, skipped", name)
+		}
+	}
+	return providers
+}
+
+// sendRoutingRequest sends a request to a routing backend and returns the body of a 200 response
+func sendRoutingRequest(ctx context.Context, client *http.Client, provider, method, url string, body []byte, headers map[string]string) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, errors.New("Gagal membuat request: " + err.Error())
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.New("Gagal mengirim request: " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.New("Gagal membaca response: " + err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		message := string(responseBody)
+		if len(message) > 300 {
+			message = message[:300]
+		}
+		return nil, fmt.Errorf("%s returned %d: %s", provider, resp.StatusCode, message)
+	}
+	return responseBody, nil
+}
+
+// orsDirectionsResponse is the subset of the ORS directions response the application uses
+type orsDirectionsResponse struct {
+	Routes []orsDirectionsRoute `json:"routes"`
+}
+
+type orsDirectionsRoute struct {
+	Summary struct {
+		Distance float64 `json:"distance"` // meter
+		Duration float64 `json:"duration"` // detik
+	} `json:"summary"`
+	Geometry string                       `json:"geometry"`
+	Extras   map[string]model.ExtraValues `json:"extras,omitempty"`
+}
+
+// newORSDirectionsResponse builds an ORS style response for backends that speak another format.
+// They have no ORS extras, every requested extra is returned empty so clients can still read it.
+func newORSDirectionsResponse(req *model.RoutingRequest, points []utils.LatLng, distance, duration float64) ([]byte, error) {
+	route := orsDirectionsRoute{
+		Geometry: utils.EncodeRouteGeometry(points),
+		Extras:   make(map[string]model.ExtraValues),
+	}
+	route.Summary.Distance = distance
+	route.Summary.Duration = duration
+	for _, extra := range req.ExtraInfo {
+		route.Extras[extra] = model.ExtraValues{Values: [][]int{}, Summary: []model.ExtraSummary{}}
+	}
+
+	return json.Marshal(orsDirectionsResponse{Routes: []orsDirectionsRoute{route}})
+}
+
+// avoidPolygons returns the outer rings ([lng, lat] points) of the ORS avoid_polygons option,
+// which is a GeoJSON Polygon or MultiPolygon
+func avoidPolygons(req *model.RoutingRequest) ([][][]float64, error) {
+	raw, ok := req.Options["avoid_polygons"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return nil, fmt.Errorf("invalid avoid_polygons: %v", err)
+	}
+
+	var rings [][][]float64
+	switch geometry.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("invalid avoid_polygons: %v", err)
+		}
+		if len(polygon) > 0 {
+			rings = append(rings, polygon[0])
+		}
+	case "MultiPolygon":
+		var multiPolygon [][][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &multiPolygon); err != nil {
+			return nil, fmt.Errorf("invalid avoid_polygons: %v", err)
+		}
+		for _, polygon := range multiPolygon {
+			if len(polygon) > 0 {
+				rings = append(rings, polygon[0])
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid avoid_polygons type %q", geometry.Type)
+	}
+	return rings, nil
+}
+
+// envString returns the environment variable or the fallback when it is empty
+func envString(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
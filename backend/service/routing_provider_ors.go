@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+// orsProvider gets directions from OpenRouteService, either the public API or a self-hosted instance
+type orsProvider struct {
+	name    string
+	baseURL string
+	apiKey  string // Kosong untuk ORS self-hosted
+	profile string
+	client  *http.Client
+}
+
+// Name returns the provider name
+func (p *orsProvider) Name() string {
+	return p.name
+}
+
+// Directions forwards the request body unchanged, the response is already in ORS format
+func (p *orsProvider) Directions(ctx context.Context, req *model.RoutingRequest, requestBody []byte) ([]byte, error) {
+	headers := map[string]string{"Content-Type": "application/json"}
+	if p.name == RoutingProviderORS {
+		if p.apiKey == "" {
+			return nil, errors.New("API key tidak dikonfigurasi")
+		}
+		headers["Authorization"] = p.apiKey
+	}
+
+	url := strings.TrimRight(p.baseURL, "/") + "/v2/directions/" + p.profile + "/json"
+	return sendRoutingRequest(ctx, p.client, p.name, http.MethodPost, url, requestBody, headers)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+// osrmProvider gets directions from an OSRM server. OSRM cannot avoid polygons.
+type osrmProvider struct {
+	baseURL string
+	profile string
+	client  *http.Client
+}
+
+type osrmRouteResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Routes  []struct {
+		Distance float64 `json:"distance"`
+		Duration float64 `json:"duration"`
+		Geometry struct {
+			Coordinates [][]float64 `json:"coordinates"` // [lng, lat]
+		} `json:"geometry"`
+	} `json:"routes"`
+}
+
+// Name returns the provider name
+func (p *osrmProvider) Name() string {
+	return RoutingProviderOSRM
+}
+
+// Directions translates the request to the OSRM route service and the answer back to ORS format
+func (p *osrmProvider) Directions(ctx context.Context, req *model.RoutingRequest, requestBody []byte) ([]byte, error) {
+	if _, ok := req.Options["avoid_polygons"]; ok {
+		return nil, fmt.Errorf("%w: osrm cannot avoid polygons", ErrRoutingOptionUnsupported)
+	}
+
+	coordinates := make([]string, 0, len(req.Coordinates))
+	for _, coordinate := range req.Coordinates {
+		if len(coordinate) < 2 {
+			return nil, fmt.Errorf("invalid coordinate %v", coordinate)
+		}
+		coordinates = append(coordinates,
+			strconv.FormatFloat(coordinate[0], 'f', -1, 64)+","+strconv.FormatFloat(coordinate[1], 'f', -1, 64))
+	}
+
+	url := fmt.Sprintf("%s/route/v1/%s/%s?overview=full&geometries=geojson",
+		strings.TrimRight(p.baseURL, "/"), p.profile, strings.Join(coordinates, ";"))
+	responseBody, err := sendRoutingRequest(ctx, p.client, RoutingProviderOSRM, http.MethodGet, url, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result osrmRouteResponse
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("invalid osrm response: %v", err)
+	}
+	if result.Code != "Ok" || len(result.Routes) == 0 {
+		return nil, fmt.Errorf("osrm found no route: %s %s", result.Code, result.Message)
+	}
+
+	route := result.Routes[0]
+	points := make([]utils.LatLng, 0, len(route.Geometry.Coordinates))
+	for _, coordinate := range route.Geometry.Coordinates {
+		if len(coordinate) >= 2 {
+			points = append(points, utils.LatLng{Lat: coordinate[1], Lng: coordinate[0]})
+		}
+	}
+
+	return newORSDirectionsResponse(req, points, route.Distance, route.Duration)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewRoutingProviders(t *testing.T) {
+	tests := []struct {
+		name   string
+		config RoutingConfig
+		want   []string
+	}{
+		{"public ORS", RoutingConfig{Providers: []string{"ors"}}, []string{"ors"}},
+		{"ors-local with URL", RoutingConfig{Providers: []string{"ors-local", "ors"}, ORSLocalURL: "http://ors:8082/ors"}, []string{"ors-local", "ors"}},
+		{"ors-local without URL is skipped", RoutingConfig{Providers: []string{"ors-local", "osrm"}}, []string{"osrm"}},
+		{"unknown provider is skipped", RoutingConfig{Providers: []string{"graphhopper", "valhalla"}}, []string{"valhalla"}},
+		{"nothing usable", RoutingConfig{Providers: []string{"ors-local"}}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := []string{}
+			for _, provider := range NewRoutingProviders(tt.config) {
+				names = append(names, provider.Name())
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("providers = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRoutingConfigORSLocalURL(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		want string
+	}{
+		{"unset has no default", "", ""},
+		{"set", "http://localhost:8082/ors", "http://localhost:8082/ors"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ORS_LOCAL_URL", tt.env)
+			if got := LoadRoutingConfig().ORSLocalURL; got != tt.want {
+				t.Errorf("ORSLocalURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
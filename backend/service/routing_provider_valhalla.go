@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+// valhallaProvider gets directions from a Valhalla server
+type valhallaProvider struct {
+	baseURL string
+	costing string
+	client  *http.Client
+}
+
+type valhallaRouteRequest struct {
+	Locations       []valhallaLocation `json:"locations"`
+	Costing         string             `json:"costing"`
+	ExcludePolygons [][][]float64      `json:"exclude_polygons,omitempty"` // Ring [lng, lat]
+	DirectionsType  string             `json:"directions_type"`
+	Units           string             `json:"units"`
+}
+
+type valhallaLocation struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type valhallaRouteResponse struct {
+	Trip struct {
+		Legs []struct {
+			Shape string `json:"shape"` // Polyline presisi 6
+		} `json:"legs"`
+		Summary struct {
+			Length float64 `json:"length"` // km
+			Time   float64 `json:"time"`   // detik
+		} `json:"summary"`
+	} `json:"trip"`
+}
+
+// Name returns the provider name
+func (p *valhallaProvider) Name() string {
+	return RoutingProviderValhalla
+}
+
+// Directions translates the request to the Valhalla route service and the answer back to ORS format
+func (p *valhallaProvider) Directions(ctx context.Context, req *model.RoutingRequest, requestBody []byte) ([]byte, error) {
+	rings, err := avoidPolygons(req)
+	if err != nil {
+		return nil, err
+	}
+
+	valhallaReq := valhallaRouteRequest{
+		Costing:         p.costing,
+		ExcludePolygons: rings,
+		DirectionsType:  "none",
+		Units:           "kilometers",
+	}
+	for _, coordinate := range req.Coordinates {
+		if len(coordinate) < 2 {
+			return nil, fmt.Errorf("invalid coordinate %v", coordinate)
+		}
+		valhallaReq.Locations = append(valhallaReq.Locations, valhallaLocation{Lat: coordinate[1], Lon: coordinate[0]})
+	}
+
+	body, err := json.Marshal(valhallaReq)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimRight(p.baseURL, "/") + "/route"
+	responseBody, err := sendRoutingRequest(ctx, p.client, RoutingProviderValhalla, http.MethodPost, url, body,
+		map[string]string{"Content-Type": "application/json"})
+	if err != nil {
+		return nil, err
+	}
+
+	var result valhallaRouteResponse
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, fmt.Errorf("invalid valhalla response: %v", err)
+	}
+	if len(result.Trip.Legs) == 0 {
+		return nil, fmt.Errorf("valhalla found no route")
+	}
+
+	var points []utils.LatLng
+	for i, leg := range result.Trip.Legs {
+		legPoints, err := utils.DecodePolyline(leg.Shape, 6)
+		if err != nil {
+			return nil, fmt.Errorf("invalid valhalla shape: %v", err)
+		}
+		// Titik awal leg berikutnya sama dengan titik akhir leg sebelumnya
+		if i > 0 && len(legPoints) > 0 {
+			legPoints = legPoints[1:]
+		}
+		points = append(points, legPoints...)
+	}
+
+	return newORSDirectionsResponse(req, points, result.Trip.Summary.Length*1000, result.Trip.Summary.Time)
+}
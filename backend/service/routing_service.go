@@ -1,65 +1,162 @@
 package service
 
 import (
-	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
-	"io" // Replace io/ioutil with io
-	"net/http"
-	"os"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
 )
 
 type RoutingService interface {
-	GetDirections(requestBody []byte) ([]byte, error)
+	GetDirections(ctx context.Context, requestBody []byte, bypassCache bool) ([]byte, bool, error)
 }
 
-type routingService struct{}
+type routingService struct {
+	providers []RoutingProvider
+	timeout   time.Duration
+	cache     *DirectionsCache // nil jika cache dimatikan
 
-func NewRoutingService() RoutingService {
-	return &routingService{}
+	routePlanRepo repository.RoutePlanRepository // Sumber area hindari yang disetujui, boleh nil
 }
 
-// GetDirections mengambil rute dari OpenRouteService API
-func (s *routingService) GetDirections(requestBody []byte) ([]byte, error) {
-	// Dapatkan API key dari env
-	apiKey := os.Getenv("ORS_API_KEY")
-	if apiKey == "" {
-		return nil, errors.New("API key tidak dikonfigurasi")
+// NewRoutingService creates a routing service over the configured providers. cache may be nil.
+// Approved avoidance areas are read from routePlanRepo and applied to every request.
+func NewRoutingService(config RoutingConfig, cache *DirectionsCache, routePlanRepo repository.RoutePlanRepository) RoutingService {
+	providers := NewRoutingProviders(config)
+	if len(providers) == 0 {
+		log.Printf("No valid routing provider in ROUTING_PROVIDERS, using %s", RoutingProviderORS)
+		providers = NewRoutingProviders(RoutingConfig{
+			Providers:  []string{RoutingProviderORS},
+			ORSURL:     config.ORSURL,
+			ORSAPIKey:  config.ORSAPIKey,
+			ORSProfile: config.ORSProfile,
+		})
+	}
+
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name())
 	}
+	log.Printf("Routing providers: %s", strings.Join(names, ", "))
 
-	// Buat HTTP client
-	client := &http.Client{}
-	
-	// Buat request
-	req, err := http.NewRequest(
-		"POST",
-		"https://api.openrouteservice.org/v2/directions/driving-hgv/json",
-		bytes.NewReader(requestBody),
-	)
+	return &routingService{
+		providers: providers,
+		timeout:   config.Timeout,
+		cache:     cache,
+
+		routePlanRepo: routePlanRepo,
+	}
+}
+
+// GetDirections mengambil rute dalam format OpenRouteService, dari cache jika request yang sama
+// sudah pernah diminta. bypassCache selalu meminta rute baru dan menyegarkan cache.
+// Area hindari permanen yang disetujui, dan area milik route_plan_id yang disetujui, selalu
+// ditambahkan ke options.avoid_polygons dan dilaporkan di avoidance_areas pada respons.
+// Nilai bool kedua bernilai true jika respons berasal dari cache.
+func (s *routingService) GetDirections(ctx context.Context, requestBody []byte, bypassCache bool) ([]byte, bool, error) {
+	var req model.RoutingRequest
+	if err := json.Unmarshal(requestBody, &req); err != nil {
+		return nil, false, errors.New("Invalid routing request: " + err.Error())
+	}
+
+	requestBody, applied, err := s.applyAvoidanceAreas(requestBody, &req)
 	if err != nil {
-		return nil, errors.New("Gagal membuat request: " + err.Error())
+		return nil, false, err
 	}
 
-	// Set headers
-	req.Header.Set("Authorization", apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	if s.cache == nil {
+		responseBody, _, err := s.fetchDirections(ctx, &req, requestBody, applied)
+		return responseBody, false, err
+	}
 
-	// Kirim request
-	resp, err := client.Do(req)
+	// Key dihitung dari body yang sudah berisi area hindari, jadi cache ikut berganti saat area disetujui
+	key, err := DirectionsCacheKey(requestBody)
 	if err != nil {
-		return nil, errors.New("Gagal mengirim request: " + err.Error())
+		return nil, false, errors.New("Invalid routing request: " + err.Error())
 	}
-	defer resp.Body.Close()
 
-	// Baca response body using io.ReadAll instead of ioutil.ReadAll
-	responseBody, err := io.ReadAll(resp.Body)
+	if bypassCache {
+		directionsCacheRequests.WithLabelValues(directionsCacheBypass).Inc()
+	} else if cached, ok := s.cache.Get(key); ok {
+		directionsCacheRequests.WithLabelValues(directionsCacheHit).Inc()
+		return cached, true, nil
+	} else {
+		directionsCacheRequests.WithLabelValues(directionsCacheMiss).Inc()
+	}
+
+	responseBody, avoided, err := s.fetchDirections(ctx, &req, requestBody, applied)
 	if err != nil {
-		return nil, errors.New("Gagal membaca response: " + err.Error())
+		return nil, false, err
+	}
+	// Rute yang tidak menghindari area tidak disimpan, provider yang bisa menghindar dicoba lagi nanti
+	if avoided {
+		s.cache.Set(key, responseBody)
+	}
+	return responseBody, false, nil
+}
+
+// fetchDirections mengambil rute dari provider pertama yang berhasil.
+// Setiap provider dibatasi timeout, provider berikutnya dicoba jika gagal.
+// Jika hanya provider yang tidak bisa menghindari poligon yang tersisa, seperti OSRM, rute diminta
+// tanpa area hindari yang tersimpan dan respons menandai avoidance_areas_applied false.
+// Nilai bool bernilai false jika area hindari tidak diterapkan.
+func (s *routingService) fetchDirections(ctx context.Context, req *model.RoutingRequest, requestBody []byte, applied []routeAvoidanceArea) ([]byte, bool, error) {
+	var failures []string
+	var unsupported []RoutingProvider
+	for _, provider := range s.providers {
+		responseBody, err := s.tryProvider(ctx, provider, req, requestBody)
+		if err == nil {
+			responseBody, err = annotateAvoidanceAreas(responseBody, applied, true)
+			return responseBody, true, err
+		}
+
+		// Permintaan dibatalkan oleh client, provider lain tidak perlu dicoba
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+
+		log.Printf("Routing provider %s failed: %v", provider.Name(), err)
+		failures = append(failures, provider.Name()+": "+err.Error())
+		if errors.Is(err, ErrRoutingOptionUnsupported) {
+			unsupported = append(unsupported, provider)
+		}
 	}
 
-	// Periksa status code
-	if resp.StatusCode != http.StatusOK {
-		return responseBody, errors.New("OpenRouteService API returned error")
+	if len(unsupported) > 0 && len(applied) > 0 {
+		fallbackBody, fallbackReq, ok, err := withoutAvoidanceAreas(requestBody, req, applied)
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			// Poligon dari client sendiri tidak boleh diabaikan
+			unsupported = nil
+		}
+		for _, provider := range unsupported {
+			responseBody, err := s.tryProvider(ctx, provider, fallbackReq, fallbackBody)
+			if err == nil {
+				log.Printf("Routing provider %s cannot avoid polygons, route computed without %d avoidance areas", provider.Name(), len(applied))
+				responseBody, err = annotateAvoidanceAreas(responseBody, applied, false)
+				return responseBody, false, err
+			}
+			if ctx.Err() != nil {
+				return nil, false, ctx.Err()
+			}
+			log.Printf("Routing provider %s failed without avoidance areas: %v", provider.Name(), err)
+			failures = append(failures, provider.Name()+" without avoidance areas: "+err.Error())
+		}
 	}
 
-	return responseBody, nil
-}
\ No newline at end of file
+	return nil, false, errors.New("Semua routing provider gagal: " + strings.Join(failures, "; "))
+}
+
+// tryProvider asks one provider for directions within the routing timeout
+func (s *routingService) tryProvider(ctx context.Context, provider RoutingProvider, req *model.RoutingRequest, requestBody []byte) ([]byte, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return provider.Directions(attemptCtx, req, requestBody)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+)
+
+// fakeAvoidanceAreaRepo serves one approved permanent avoidance area
+type fakeAvoidanceAreaRepo struct {
+	repository.RoutePlanRepository
+	points []*model.RouteAvoidancePoint
+}
+
+func (r *fakeAvoidanceAreaRepo) FindApprovedAvoidanceAreas(routePlanID uint) ([]*model.RouteAvoidanceArea, error) {
+	return []*model.RouteAvoidanceArea{{ID: 1, Reason: "flooding", IsPermanent: true, Status: "approved"}}, nil
+}
+
+func (r *fakeAvoidanceAreaRepo) FindAvoidancePointsByAreaIDs(areaIDs []uint) ([]*model.RouteAvoidancePoint, error) {
+	return r.points, nil
+}
+
+// squareArea returns the corners of a small square avoidance area around a point
+func squareArea(lat, lng float64) []*model.RouteAvoidancePoint {
+	return []*model.RouteAvoidancePoint{
+		{RouteAvoidanceAreaID: 1, Latitude: lat - 0.001, Longitude: lng - 0.001},
+		{RouteAvoidanceAreaID: 1, Latitude: lat - 0.001, Longitude: lng + 0.001},
+		{RouteAvoidanceAreaID: 1, Latitude: lat + 0.001, Longitude: lng + 0.001},
+		{RouteAvoidanceAreaID: 1, Latitude: lat + 0.001, Longitude: lng - 0.001},
+	}
+}
+
+func TestGetDirectionsOSRMOnlyWithAvoidanceAreas(t *testing.T) {
+	var osrmCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		osrmCalls.Add(1)
+		w.Write([]byte(`{"code":"Ok","routes":[{"distance":1500,"duration":120,"geometry":{"coordinates":[[106.8,-6.2],[106.81,-6.21]]}}]}`))
+	}))
+	defer server.Close()
+
+	const request = `{"coordinates":[[106.8,-6.2],[106.81,-6.21]]}`
+	const clientPolygon = `{"coordinates":[[106.8,-6.2],[106.81,-6.21]],"options":{"avoid_polygons":{"type":"Polygon","coordinates":[[[106.9,-6.3],[106.91,-6.3],[106.91,-6.31],[106.9,-6.3]]]}}}`
+	const storedPolygon = `{"coordinates":[[106.8,-6.2],[106.81,-6.21]],"options":{"avoid_polygons":{"type":"Polygon","coordinates":[[[106.804,-6.206],[106.806,-6.206],[106.806,-6.204],[106.804,-6.204],[106.804,-6.206]]]}}}`
+
+	tests := []struct {
+		name        string
+		points      []*model.RouteAvoidancePoint
+		request     string
+		wantErr     bool
+		wantApplied *bool // avoidance_areas_applied, nil when no area was near
+		wantCalls   int32 // After two identical requests
+	}{
+		{"no area nearby", squareArea(-7.5, 110.4), request, false, nil, 1},
+		{"approved area nearby", squareArea(-6.205, 106.805), request, false, new(bool), 2},
+		{"client sent the stored area", squareArea(-6.205, 106.805), storedPolygon, false, new(bool), 2},
+		{"client sent a polygon of its own", squareArea(-6.205, 106.805), clientPolygon, true, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			osrmCalls.Store(0)
+			cache := NewDirectionsCache(DirectionsCacheConfig{Store: DirectionsCacheMemory, TTL: time.Minute, MaxEntries: 10}, nil)
+			svc := NewRoutingService(RoutingConfig{
+				Providers:   []string{RoutingProviderOSRM},
+				Timeout:     5 * time.Second,
+				OSRMURL:     server.URL,
+				OSRMProfile: "driving",
+			}, cache, &fakeAvoidanceAreaRepo{points: tt.points})
+
+			for i := 0; i < 2; i++ {
+				responseBody, _, err := svc.GetDirections(context.Background(), []byte(tt.request), false)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatalf("expected an error, got %s", responseBody)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				var response struct {
+					Routes                []json.RawMessage            `json:"routes"`
+					AvoidanceAreas        []model.AppliedAvoidanceArea `json:"avoidance_areas"`
+					AvoidanceAreasApplied *bool                        `json:"avoidance_areas_applied"`
+				}
+				if err := json.Unmarshal(responseBody, &response); err != nil {
+					t.Fatalf("invalid response: %v", err)
+				}
+				if len(response.Routes) != 1 {
+					t.Fatalf("routes = %d, want 1", len(response.Routes))
+				}
+				if (response.AvoidanceAreasApplied == nil) != (tt.wantApplied == nil) ||
+					response.AvoidanceAreasApplied != nil && *response.AvoidanceAreasApplied != *tt.wantApplied {
+					t.Errorf("avoidance_areas_applied = %v, want %v", response.AvoidanceAreasApplied, tt.wantApplied)
+				}
+				if tt.wantApplied != nil && len(response.AvoidanceAreas) != 1 {
+					t.Errorf("avoidance_areas = %d, want the nearby area", len(response.AvoidanceAreas))
+				}
+			}
+			if calls := osrmCalls.Load(); calls != tt.wantCalls {
+				t.Errorf("osrm calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+)
+
+const (
+	// IdleRebuildWindow is how much position history is replayed to rebuild idle state at startup
+	IdleRebuildWindow = 2 * time.Hour
+
+	// IdleWatchdogInterval is how often open idle detections are checked for offline trucks
+	IdleWatchdogInterval = time.Minute
+
+	// IdleOfflineTimeout is how long a truck may stay silent before its open idle detection is closed
+	IdleOfflineTimeout = 30 * time.Minute
+)
+
+// RebuildState replays recent position history into the idle detector cache so
+// that a restart does not reset the idle timer of trucks that are standing still.
+// Replaying has no side effects: detections are created or extended by the next
+// live position, using the stationary window rebuilt here.
+func (s *truckIdleService) RebuildState(since time.Time) error {
+	histories, err := s.historyRepo.GetPositionHistorySince(since)
+	if err != nil {
+		return err
+	}
+
+	trucks := 0
+	for _, history := range histories {
+		cache := s.getOrCreateCache(history.MacID)
+
+		cache.mutex.Lock()
+		// Engine hours were already recorded live, only the stop's engine-on time is restored
+		engineOnSeconds := cache.engineOnSince(history.Timestamp)
+		cache.Ignition = history.Ignition
+		cache.LastSampleAt = history.Timestamp
+		pos := IdlePosition{
+			Latitude:  history.Latitude,
+			Longitude: history.Longitude,
+			Timestamp: history.Timestamp,
+		}
+		if cache.Count == 0 {
+			cache.reset(pos)
+			cache.StopEngineOnSeconds = 0
+			trucks++
+		} else {
+			rule := s.ruleService.MatchRule(cache.TruckType, history.Timestamp)
+			if cache.track(pos, rule.Radius) {
+				cache.StopEngineOnSeconds += engineOnSeconds
+			} else {
+				cache.StopEngineOnSeconds = 0
+			}
+		}
+		cache.mutex.Unlock()
+	}
+
+	log.Printf("Rebuilt idle detection state for %d trucks from %d positions", trucks, len(histories))
+	return nil
+}
+
+// StartWatchdog periodically closes open idle detections of trucks that went offline
+func (s *truckIdleService) StartWatchdog(interval, offlineAfter time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.CloseStaleDetections(offlineAfter); err != nil {
+				log.Printf("Error closing stale idle detections: %v", err)
+			}
+		}
+	}()
+}
+
+// CloseStaleDetections closes open idle detections of trucks that have not sent a
+// position for offlineAfter. The detection ends at the truck's last known position.
+func (s *truckIdleService) CloseStaleDetections(offlineAfter time.Duration) (int, error) {
+	idles, err := s.idleRepo.FindAllActive()
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	now := time.Now()
+	for _, idle := range idles {
+		truck, err := s.truckRepo.FindByMacID(idle.MacID)
+		if err != nil {
+			continue
+		}
+
+		lastSeen := truck.LastPosition
+		if lastSeen.Before(idle.EndTime) {
+			lastSeen = idle.EndTime
+		}
+		if now.Sub(lastSeen) < offlineAfter {
+			continue
+		}
+
+		idle.IsResolved = true
+		idle.EndTime = lastSeen
+		idle.Duration = int(lastSeen.Sub(idle.StartTime).Seconds())
+		idle.CloseReason = model.IdleCloseReasonOffline
+		idle.UpdatedAt = now
+		if err := s.idleRepo.Update(idle); err != nil {
+			log.Printf("Failed to close stale idle detection %d: %v", idle.ID, err)
+			continue
+		}
+		closed++
+		log.Printf("Closed idle detection %d for truck %s, offline since %s", idle.ID, idle.MacID, lastSeen.Format(time.RFC3339))
+
+		// Start from scratch when the truck comes back online
+		s.cacheMutex.Lock()
+		delete(s.positionCache, idle.MacID)
+		s.cacheMutex.Unlock()
+
+		if !idle.IsSuppressed {
+			s.sendIdleResolvedNotification(idle.MacID, idle.ID, idle.Duration, idle.CloseReason)
+		}
+	}
+
+	return closed, nil
+}
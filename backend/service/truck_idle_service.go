@@ -1,13 +1,12 @@
 package service
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
-	"net/http"
-	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,15 +15,6 @@ import (
 	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
 )
 
-const (
-	// IdleRadius is the radius in meters within which a truck is considered to be idling
-	IdleRadius = 15.0
-	
-	// IdleDetectionCount is the number of position reports within IdleRadius needed to trigger idle detection
-	IdleDetectionCount = 12
-)
-
-
 // IdlePosition menyimpan posisi sementara untuk deteksi idle
 type IdlePosition struct {
 	Latitude  float64
@@ -35,18 +25,35 @@ type IdlePosition struct {
 // PositionCache menyimpan cache posisi untuk setiap truck
 type PositionCache struct {
 	MacID     string
-	Positions []IdlePosition
+	TruckID   uint
+	TruckType string
+	Anchor    IdlePosition // Posisi awal truk mulai diam
+	Last      IdlePosition // Posisi terakhir dalam radius anchor
+	Count     int          // Jumlah laporan posisi sejak anchor
+	Ignition     *bool     // Status kunci kontak pada laporan terakhir
+	LastSampleAt time.Time // Waktu laporan terakhir, dasar perhitungan jam mesin
+	StopEngineOnSeconds int // Lama mesin menyala sejak truk berhenti, direset saat truk bergerak
 	mutex     sync.Mutex
 }
 
+// engineSampleMaxGap is the longest gap between two reports that still counts as engine-on time
+const engineSampleMaxGap = 10 * time.Minute
+
 // TruckIdleService mengelola deteksi dan notifikasi idle truck
 type TruckIdleService interface {
-	ProcessPosition(macID string, latitude, longitude float64, timestamp time.Time) error
+	ProcessPosition(macID string, latitude, longitude float64, ignition *bool, timestamp time.Time) error
 	GetAllIdleDetections() ([]*model.TruckIdleResponse, error)
 	GetIdleDetectionsByTruckID(truckID uint) ([]*model.TruckIdleResponse, error)
 	GetIdleDetectionsByMacID(macID string) ([]*model.TruckIdleResponse, error)
-	ResolveIdleDetection(idleID uint) error
+	ResolveIdleDetection(idleID, userID uint) error
+	AcknowledgeIdleDetection(idleID, userID uint, req model.IdleAcknowledgeRequest) (*model.TruckIdleResponse, error)
+	ExplainIdleDetection(idleID, driverID uint, req model.IdleExplanationRequest) (*model.TruckIdleResponse, error)
+	GetIdleReasonStats(start, end time.Time) (*model.IdleReasonStatsResponse, error)
 	GetActiveIdleDetections() ([]*model.TruckIdleResponse, error)
+	GetIdleSummary(start, end time.Time) (*model.IdleSummaryResponse, error)
+	RebuildState(since time.Time) error
+	StartWatchdog(interval, offlineAfter time.Duration)
+	CloseStaleDetections(offlineAfter time.Duration) (int, error)
 }
 
 type truckIdleService struct {
@@ -54,6 +61,10 @@ type truckIdleService struct {
 	truckRepo     repository.TruckRepository
 	userRepo      repository.UserRepository
 	routePlanRepo repository.RoutePlanRepository
+	historyRepo   repository.TruckHistoryRepository
+	ruleService   IdleRuleService
+	zoneService   IdleZoneService
+	engineHoursService EngineHoursService
 	positionCache map[string]*PositionCache
 	cacheMutex    sync.RWMutex
 }
@@ -64,12 +75,20 @@ func NewTruckIdleService(
 	truckRepo repository.TruckRepository,
 	userRepo repository.UserRepository,
 	routePlanRepo repository.RoutePlanRepository,
+	historyRepo repository.TruckHistoryRepository,
+	ruleService IdleRuleService,
+	zoneService IdleZoneService,
+	engineHoursService EngineHoursService,
 ) TruckIdleService {
 	return &truckIdleService{
 		idleRepo:      idleRepo,
 		truckRepo:     truckRepo,
 		userRepo:      userRepo,
 		routePlanRepo: routePlanRepo,
+		historyRepo:   historyRepo,
+		ruleService:   ruleService,
+		zoneService:   zoneService,
+		engineHoursService: engineHoursService,
 		positionCache: make(map[string]*PositionCache),
 	}
 }
@@ -104,8 +123,12 @@ func (s *truckIdleService) getOrCreateCache(macID string) *PositionCache {
 	
 	if !exists {
 		cache = &PositionCache{
-			MacID:     macID,
-			Positions: make([]IdlePosition, 0, IdleDetectionCount),
+			MacID: macID,
+		}
+		// Truck type selects which idle rules apply
+		if truck, err := s.truckRepo.FindByMacID(macID); err == nil {
+			cache.TruckID = truck.ID
+			cache.TruckType = truck.Type
 		}
 		s.cacheMutex.Lock()
 		s.positionCache[macID] = cache
@@ -115,8 +138,52 @@ func (s *truckIdleService) getOrCreateCache(macID string) *PositionCache {
 	return cache
 }
 
+// reset starts a new stationary window at the given position
+func (c *PositionCache) reset(pos IdlePosition) {
+	c.Anchor = pos
+	c.Last = pos
+	c.Count = 1
+}
+
+// track adds a position to the stationary window. It returns false and starts
+// a new window when the position is outside the radius around the anchor.
+func (c *PositionCache) track(pos IdlePosition, radius float64) bool {
+	if !isWithinRadius(c.Anchor.Latitude, c.Anchor.Longitude, pos.Latitude, pos.Longitude, radius) {
+		c.reset(pos)
+		return false
+	}
+	c.Last = pos
+	c.Count++
+	return true
+}
+
+// idleTypeFor classifies a stop by the current ignition state
+func idleTypeFor(ignition *bool) string {
+	if ignition == nil {
+		return model.IdleTypeUnknown
+	}
+	if *ignition {
+		return model.IdleTypeEngineOn
+	}
+	return model.IdleTypeParked
+}
+
+// engineOnSince returns the engine-on seconds between the previous report and now.
+// Time only counts when the ignition was on at the previous report and the tracker
+// did not go silent in between.
+func (c *PositionCache) engineOnSince(timestamp time.Time) int {
+	if c.Ignition == nil || !*c.Ignition || c.LastSampleAt.IsZero() {
+		return 0
+	}
+	gap := timestamp.Sub(c.LastSampleAt)
+	if gap <= 0 || gap > engineSampleMaxGap {
+		return 0
+	}
+	return int(gap.Seconds())
+}
+
 // ProcessPosition processes a new position report and detects idle state
-func (s *truckIdleService) ProcessPosition(macID string, latitude, longitude float64, timestamp time.Time) error {
+func (s *truckIdleService) ProcessPosition(macID string, latitude, longitude float64, ignition *bool, timestamp time.Time) error {
 	// Get cache for this truck
 	cache := s.getOrCreateCache(macID)
 	
@@ -131,33 +198,52 @@ func (s *truckIdleService) ProcessPosition(macID string, latitude, longitude flo
 		Timestamp: timestamp,
 	}
 	
-	// If cache is empty, just add the position and return
-	if len(cache.Positions) == 0 {
-		cache.Positions = append(cache.Positions, newPos)
+	// Jam mesin sejak laporan sebelumnya, dihitung sebelum status kunci kontak diperbarui
+	engineOnSeconds := cache.engineOnSince(timestamp)
+	cache.Ignition = ignition
+	cache.LastSampleAt = timestamp
+	
+	// If cache is empty, just start a new anchor and return
+	if cache.Count == 0 {
+		cache.reset(newPos)
+		cache.StopEngineOnSeconds = 0
+		s.addEngineTime(cache, timestamp, engineOnSeconds, 0)
 		return nil
 	}
 	
-	// Get first (reference) position in cache
-	refPos := cache.Positions[0]
+	// Rule yang berlaku untuk tipe truk dan jam saat ini
+	rule := s.ruleService.MatchRule(cache.TruckType, timestamp)
 	
-	// Check if new position is within idle radius of reference position
-	if isWithinRadius(refPos.Latitude, refPos.Longitude, latitude, longitude, IdleRadius) {
-		// Add new position to cache
-		cache.Positions = append(cache.Positions, newPos)
+	// Check if new position is within idle radius of the anchor position
+	if cache.track(newPos, rule.Radius) {
+		// Truk diam dengan mesin menyala
+		cache.StopEngineOnSeconds += engineOnSeconds
+		s.addEngineTime(cache, timestamp, engineOnSeconds, engineOnSeconds)
+		
+		// Idle is decided by elapsed time, not by the number of reports
+		minDuration := time.Duration(rule.MinDurationMinutes) * time.Minute
+		
+		// Zona dengan policy extended menaikkan ambang idle
+		zone := s.zoneService.FindZone(cache.Anchor.Latitude, cache.Anchor.Longitude)
+		if zone != nil && zone.Policy == model.IdleZonePolicyExtended {
+			if extended := time.Duration(zone.ExtendedMinutes) * time.Minute; extended > minDuration {
+				minDuration = extended
+			}
+		}
 		
-		// Check if we have enough positions to trigger idle detection
-		if len(cache.Positions) >= IdleDetectionCount {
+		if cache.Last.Timestamp.Sub(cache.Anchor.Timestamp) >= minDuration {
 			// Trigger idle detection
-			if err := s.createIdleDetection(macID, refPos, newPos); err != nil {
+			if err := s.createIdleDetection(macID, cache.Anchor, newPos, rule, zone, ignition, cache.StopEngineOnSeconds); err != nil {
 				return err
 			}
 			
-			// Clear cache after detection
-			cache.Positions = []IdlePosition{}
+			// Start the next window here, an open detection is extended when it fires again
+			cache.reset(newPos)
 		}
 	} else {
-		// Position is outside idle radius, reset cache with new reference position
-		cache.Positions = []IdlePosition{newPos}
+		// Position is outside idle radius, the cache now starts at the new position
+		cache.StopEngineOnSeconds = 0
+		s.addEngineTime(cache, timestamp, engineOnSeconds, 0)
 		
 		// Check if there's an active idle detection and resolve it
 		existingIdle, err := s.idleRepo.FindActiveByMacID(macID)
@@ -166,6 +252,7 @@ func (s *truckIdleService) ProcessPosition(macID string, latitude, longitude flo
 			existingIdle.IsResolved = true
 			existingIdle.EndTime = timestamp
 			existingIdle.Duration = int(timestamp.Sub(existingIdle.StartTime).Seconds())
+			existingIdle.CloseReason = model.IdleCloseReasonMoved
 			existingIdle.UpdatedAt = time.Now()
 			
 			if err := s.idleRepo.Update(existingIdle); err != nil {
@@ -173,8 +260,10 @@ func (s *truckIdleService) ProcessPosition(macID string, latitude, longitude flo
 			} else {
 				log.Printf("Resolved idle detection for truck %s as it moved outside idle radius", macID)
 				
-				// Send notification about resolution
-				s.sendIdleResolvedNotification(macID, existingIdle.ID, existingIdle.Duration)
+				// Send notification about resolution, suppressed idles never alerted
+				if !existingIdle.IsSuppressed {
+					s.sendIdleResolvedNotification(macID, existingIdle.ID, existingIdle.Duration, existingIdle.CloseReason)
+				}
 			}
 		}
 	}
@@ -182,23 +271,46 @@ func (s *truckIdleService) ProcessPosition(macID string, latitude, longitude flo
 	return nil
 }
 
+// addEngineTime records engine-on time in the daily engine hours
+func (s *truckIdleService) addEngineTime(cache *PositionCache, timestamp time.Time, engineOnSeconds, idleEngineOnSeconds int) {
+	if s.engineHoursService == nil || engineOnSeconds == 0 || cache.TruckID == 0 {
+		return
+	}
+	if err := s.engineHoursService.AddEngineTime(cache.TruckID, cache.MacID, timestamp, engineOnSeconds, idleEngineOnSeconds); err != nil {
+		log.Printf("Failed to record engine hours for truck %s: %v", cache.MacID, err)
+	}
+}
+
 // createIdleDetection creates a new idle detection record and sends notification
-func (s *truckIdleService) createIdleDetection(macID string, startPos, endPos IdlePosition) error {
+func (s *truckIdleService) createIdleDetection(macID string, startPos, endPos IdlePosition, rule *model.IdleRule, zone *model.IdleZone,
+	ignition *bool, engineOnSeconds int) error {
 	// Check if there's already an active (unresolved) idle detection for this truck
 	existingIdle, err := s.idleRepo.FindActiveByMacID(macID)
 	if err == nil && existingIdle != nil {
 		// Update existing idle detection instead of creating a new one
 		existingIdle.EndTime = endPos.Timestamp
 		existingIdle.Duration = int(endPos.Timestamp.Sub(existingIdle.StartTime).Seconds())
+		existingIdle.IdleType = idleTypeFor(ignition)
+		existingIdle.EngineOnSeconds = engineOnSeconds
 		existingIdle.UpdatedAt = time.Now()
 		
+		alertEngine := s.engineAlertDue(existingIdle, rule)
+		if alertEngine {
+			existingIdle.EngineAlertSent = true
+		}
+		
 		if err := s.idleRepo.Update(existingIdle); err != nil {
 			return fmt.Errorf("failed to update existing idle detection: %w", err)
 		}
 		
 		// Send updated notification
-		s.sendIdleNotification(macID, existingIdle.Latitude, existingIdle.Longitude, 
-			existingIdle.StartTime, existingIdle.Duration)
+		if !existingIdle.IsSuppressed {
+			s.sendIdleNotification(macID, existingIdle.Latitude, existingIdle.Longitude, 
+				existingIdle.StartTime, existingIdle.Duration, existingIdle.RuleName, existingIdle.ZoneName, existingIdle.IdleType)
+		}
+		if alertEngine {
+			s.sendEngineIdleAlert(existingIdle)
+		}
 		
 		return nil
 	}
@@ -222,19 +334,45 @@ func (s *truckIdleService) createIdleDetection(macID string, startPos, endPos Id
 		EndTime:    endPos.Timestamp,
 		Duration:   duration,
 		IsResolved: false,
+		RuleName:   rule.Name,
+		IdleType:   idleTypeFor(ignition),
+		EngineOnSeconds: engineOnSeconds,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}
 	
+	if rule.ID != 0 {
+		ruleID := rule.ID
+		idleDetection.RuleID = &ruleID
+	}
+	if driverID, _ := s.findDriver(truck); driverID != 0 {
+		idleDetection.DriverID = &driverID
+	}
+	if zone != nil {
+		zoneID := zone.ID
+		idleDetection.ZoneID = &zoneID
+		idleDetection.ZoneName = zone.Name
+		idleDetection.IsSuppressed = zone.Policy == model.IdleZonePolicySuppress
+	}
+	
+	alertEngine := s.engineAlertDue(idleDetection, rule)
+	idleDetection.EngineAlertSent = alertEngine
+	
 	// Save to database
 	if err := s.idleRepo.Create(idleDetection); err != nil {
 		return fmt.Errorf("failed to create idle detection: %w", err)
 	}
 	
-	log.Printf("Idle detection created for truck %s: duration %d seconds", macID, duration)
+	log.Printf("Idle detection created for truck %s by rule %q: duration %d seconds", macID, rule.Name, duration)
+	
+	// Idle di zona suppress hanya dicatat untuk laporan
+	if idleDetection.IsSuppressed {
+		log.Printf("Idle alert suppressed for truck %s inside zone %s", macID, zone.Name)
+		return nil
+	}
 	
 	// Send WebSocket notification
-	s.sendIdleNotification(macID, startPos.Latitude, startPos.Longitude, startPos.Timestamp, duration)
+	s.sendIdleNotification(macID, startPos.Latitude, startPos.Longitude, startPos.Timestamp, duration, rule.Name, idleDetection.ZoneName, idleDetection.IdleType)
 	
 	// Send push notification
 	if err := s.sendIdlePushNotification(truck, duration); err != nil {
@@ -242,12 +380,71 @@ func (s *truckIdleService) createIdleDetection(macID string, startPos, endPos Id
 		// Don't return the error here to avoid disrupting the main flow
 	}
 	
+	if alertEngine {
+		s.sendEngineIdleAlert(idleDetection)
+	}
+	
 	return nil
 }
 
+// engineAlertDue reports whether an idle detection just passed the engine-on alert threshold.
+// The alert is sent once per detection and never for idles inside a suppress zone.
+func (s *truckIdleService) engineAlertDue(idle *model.TruckIdleDetection, rule *model.IdleRule) bool {
+	if idle.EngineAlertSent || idle.IsSuppressed || idle.IdleType != model.IdleTypeEngineOn {
+		return false
+	}
+	threshold := rule.EngineOnAlertMinutes
+	if threshold <= 0 {
+		threshold = DefaultEngineOnAlertMinutes
+	}
+	return idle.EngineOnSeconds >= threshold*60
+}
+
+// sendEngineIdleAlert notifies that a truck has been idling with the engine on for too long
+func (s *truckIdleService) sendEngineIdleAlert(idle *model.TruckIdleDetection) {
+	truck, err := s.truckRepo.FindByMacID(idle.MacID)
+	if err != nil {
+		log.Printf("Error finding truck for engine idle alert: %v", err)
+		return
+	}
+	
+	notification := map[string]interface{}{
+		"type":              "engine_idle_alert",
+		"mac_id":            idle.MacID,
+		"plate_number":      truck.PlateNumber,
+		"idle_id":           idle.ID,
+		"latitude":          idle.Latitude,
+		"longitude":         idle.Longitude,
+		"start_time":        idle.StartTime,
+		"engine_on_seconds": idle.EngineOnSeconds,
+		"timestamp":         time.Now(),
+	}
+	
+	jsonData, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("Error marshaling engine idle alert: %v", err)
+		return
+	}
+	
+	wsHub := websocket.GetHub()
+	if wsHub != nil {
+		wsHub.Publish(jsonData, websocket.ChannelAlerts, websocket.TruckChannel(idle.MacID))
+		log.Printf("Published engine idle alert for truck %s", idle.MacID)
+	}
+	
+	plateNumber := truck.PlateNumber
+	if plateNumber == "" {
+		plateNumber = truck.MacID
+	}
+	message := fmt.Sprintf("Vehicle %s has been idling with the engine on for %d minutes.", plateNumber, idle.EngineOnSeconds/60)
+	if err := s.sendPushNotification(truck, "Engine Idle Alert", message); err != nil {
+		log.Printf("Error sending engine idle push notification: %v", err)
+	}
+}
+
 // sendIdleNotification sends a notification about idle detection through WebSocket
 func (s *truckIdleService) sendIdleNotification(macID string, latitude, longitude float64, 
-	startTime time.Time, duration int) {
+	startTime time.Time, duration int, ruleName, zoneName, idleType string) {
 	
 	// Get truck info for plate number
 	truck, err := s.truckRepo.FindByMacID(macID)
@@ -265,6 +462,9 @@ func (s *truckIdleService) sendIdleNotification(macID string, latitude, longitud
 		Longitude:   longitude,
 		StartTime:   startTime,
 		Duration:    duration,
+		RuleName:    ruleName,
+		ZoneName:    zoneName,
+		IdleType:    idleType,
 	}
 	
 	// Marshal to JSON
@@ -274,21 +474,16 @@ func (s *truckIdleService) sendIdleNotification(macID string, latitude, longitud
 		return
 	}
 	
-	// Broadcast notification to all WebSocket clients
+	// Publish notification to alert and truck subscribers
 	wsHub := websocket.GetHub()
 	if wsHub != nil {
-		clientCount := wsHub.GetClientCount()
-		if clientCount > 0 {
-			wsHub.Broadcast(jsonData)
-			log.Printf("Broadcasted idle notification to %d clients", clientCount)
-		} else {
-			log.Printf("No WebSocket clients connected, idle notification not broadcasted")
-		}
+		wsHub.Publish(jsonData, websocket.ChannelAlerts, websocket.TruckChannel(macID))
+		log.Printf("Published idle notification for truck %s", macID)
 	}
 }
 
 // sendIdleResolvedNotification sends a notification when an idle state is resolved
-func (s *truckIdleService) sendIdleResolvedNotification(macID string, idleID uint, duration int) {
+func (s *truckIdleService) sendIdleResolvedNotification(macID string, idleID uint, duration int, reason string) {
 	// Get truck info for plate number
 	truck, err := s.truckRepo.FindByMacID(macID)
 	var plateNumber string
@@ -303,6 +498,7 @@ func (s *truckIdleService) sendIdleResolvedNotification(macID string, idleID uin
 		"plate_number": plateNumber,
 		"idle_id":      idleID,
 		"duration":     duration,
+		"reason":       reason,
 		"timestamp":    time.Now(),
 	}
 	
@@ -313,16 +509,11 @@ func (s *truckIdleService) sendIdleResolvedNotification(macID string, idleID uin
 		return
 	}
 	
-	// Broadcast notification to all WebSocket clients
+	// Publish notification to alert and truck subscribers
 	wsHub := websocket.GetHub()
 	if wsHub != nil {
-		clientCount := wsHub.GetClientCount()
-		if clientCount > 0 {
-			wsHub.Broadcast(jsonData)
-			log.Printf("Broadcasted idle resolution notification to %d clients", clientCount)
-		} else {
-			log.Printf("No WebSocket clients connected, idle resolution notification not broadcasted")
-		}
+		wsHub.Publish(jsonData, websocket.ChannelAlerts, websocket.TruckChannel(macID))
+		log.Printf("Published idle resolution notification for truck %s", macID)
 	}
 }
 
@@ -356,19 +547,92 @@ func (s *truckIdleService) GetIdleDetectionsByMacID(macID string) ([]*model.Truc
 	return s.mapIdleDetectionsToResponses(idles)
 }
 
-// ResolveIdleDetection marks an idle detection as resolved
-func (s *truckIdleService) ResolveIdleDetection(idleID uint) error {
+// ResolveIdleDetection marks an idle detection as resolved by a user
+func (s *truckIdleService) ResolveIdleDetection(idleID, userID uint) error {
 	idle, err := s.idleRepo.FindByID(idleID)
 	if err != nil {
 		return fmt.Errorf("idle detection not found: %w", err)
 	}
 	
-	idle.IsResolved = true
-	idle.UpdatedAt = time.Now()
-	
+	s.closeByUser(idle, userID)
 	return s.idleRepo.Update(idle)
 }
 
+// AcknowledgeIdleDetection records the reason for an idle event and resolves it if still open
+func (s *truckIdleService) AcknowledgeIdleDetection(idleID, userID uint, req model.IdleAcknowledgeRequest) (*model.TruckIdleResponse, error) {
+	if !isValidIdleReasonCode(req.ReasonCode) {
+		return nil, fmt.Errorf("reason_code must be one of: %s", strings.Join(model.IdleReasonCodes, ", "))
+	}
+	
+	idle, err := s.idleRepo.FindByID(idleID)
+	if err != nil {
+		return nil, notFoundError("idle detection", err)
+	}
+	
+	s.closeByUser(idle, userID)
+	idle.AckReasonCode = req.ReasonCode
+	idle.AckComment = strings.TrimSpace(req.Comment)
+	
+	if err := s.idleRepo.Update(idle); err != nil {
+		return nil, fmt.Errorf("failed to acknowledge idle detection: %w", err)
+	}
+	
+	return s.toIdleResponse(idle)
+}
+
+// ExplainIdleDetection stores the driver's explanation of an idle event
+func (s *truckIdleService) ExplainIdleDetection(idleID, driverID uint, req model.IdleExplanationRequest) (*model.TruckIdleResponse, error) {
+	explanation := strings.TrimSpace(req.Explanation)
+	if explanation == "" {
+		return nil, errors.New("explanation is required")
+	}
+	
+	idle, err := s.idleRepo.FindByID(idleID)
+	if err != nil {
+		return nil, notFoundError("idle detection", err)
+	}
+	
+	// Driver hanya boleh menjelaskan idle pada rute yang ia kendarai
+	if idle.DriverID == nil || *idle.DriverID != driverID {
+		return nil, fmt.Errorf("driver is %w to explain this idle detection", ErrNotAllowed)
+	}
+	
+	now := time.Now()
+	idle.DriverExplanation = explanation
+	idle.DriverExplainedAt = &now
+	idle.UpdatedAt = now
+	
+	if err := s.idleRepo.Update(idle); err != nil {
+		return nil, fmt.Errorf("failed to save idle explanation: %w", err)
+	}
+	
+	return s.toIdleResponse(idle)
+}
+
+// closeByUser resolves an open idle detection now and records who handled it
+func (s *truckIdleService) closeByUser(idle *model.TruckIdleDetection, userID uint) {
+	now := time.Now()
+	if !idle.IsResolved {
+		idle.IsResolved = true
+		idle.EndTime = now
+		idle.Duration = int(now.Sub(idle.StartTime).Seconds())
+		idle.CloseReason = model.IdleCloseReasonManual
+	}
+	idle.AcknowledgedByID = &userID
+	idle.AcknowledgedAt = &now
+	idle.UpdatedAt = now
+}
+
+// isValidIdleReasonCode checks the reason code against the known codes
+func isValidIdleReasonCode(code string) bool {
+	for _, valid := range model.IdleReasonCodes {
+		if code == valid {
+			return true
+		}
+	}
+	return false
+}
+
 // GetActiveIdleDetections returns all active (unresolved) idle detections
 func (s *truckIdleService) GetActiveIdleDetections() ([]*model.TruckIdleResponse, error) {
 	idles, err := s.idleRepo.FindAllActive()
@@ -379,6 +643,126 @@ func (s *truckIdleService) GetActiveIdleDetections() ([]*model.TruckIdleResponse
 	return s.mapIdleDetectionsToResponses(idles)
 }
 
+// GetIdleSummary separates idle time inside zones from unexplained stops
+func (s *truckIdleService) GetIdleSummary(start, end time.Time) (*model.IdleSummaryResponse, error) {
+	idles, err := s.idleRepo.FindByDateRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	
+	summary := &model.IdleSummaryResponse{
+		StartDate: start,
+		EndDate:   end,
+		Zones:     []model.IdleZoneSummary{},
+	}
+	zoneIndex := make(map[uint]int)
+	for _, idle := range idles {
+		summary.TotalCount++
+		summary.TotalDuration += idle.Duration
+		
+		if idle.ZoneID == nil {
+			summary.OutsideZoneCount++
+			summary.OutsideZoneDuration += idle.Duration
+			continue
+		}
+		
+		summary.InZoneCount++
+		summary.InZoneDuration += idle.Duration
+		i, ok := zoneIndex[*idle.ZoneID]
+		if !ok {
+			i = len(summary.Zones)
+			zoneIndex[*idle.ZoneID] = i
+			summary.Zones = append(summary.Zones, model.IdleZoneSummary{
+				ZoneID:   *idle.ZoneID,
+				ZoneName: idle.ZoneName,
+			})
+		}
+		summary.Zones[i].Count++
+		summary.Zones[i].TotalDuration += idle.Duration
+	}
+	
+	return summary, nil
+}
+
+// GetIdleReasonStats aggregates idle events by reason code for each driver
+func (s *truckIdleService) GetIdleReasonStats(start, end time.Time) (*model.IdleReasonStatsResponse, error) {
+	idles, err := s.idleRepo.FindByDateRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	
+	stats := &model.IdleReasonStatsResponse{
+		StartDate: start,
+		EndDate:   end,
+		Drivers:   []*model.IdleDriverReasonStats{},
+	}
+	drivers := make(map[uint]*model.IdleDriverReasonStats)
+	for _, idle := range idles {
+		var driverID uint
+		if idle.DriverID != nil {
+			driverID = *idle.DriverID
+		}
+		
+		driver, ok := drivers[driverID]
+		if !ok {
+			driver = &model.IdleDriverReasonStats{
+				DriverID:   driverID,
+				DriverName: s.userName(driverID, "Unassigned"),
+				Reasons:    []model.IdleReasonCount{},
+			}
+			drivers[driverID] = driver
+			stats.Drivers = append(stats.Drivers, driver)
+		}
+		
+		driver.TotalCount++
+		driver.TotalDuration += idle.Duration
+		if idle.AckReasonCode == "" {
+			driver.UnacknowledgedCount++
+			continue
+		}
+		
+		found := false
+		for i := range driver.Reasons {
+			if driver.Reasons[i].ReasonCode == idle.AckReasonCode {
+				driver.Reasons[i].Count++
+				driver.Reasons[i].TotalDuration += idle.Duration
+				found = true
+				break
+			}
+		}
+		if !found {
+			driver.Reasons = append(driver.Reasons, model.IdleReasonCount{
+				ReasonCode:    idle.AckReasonCode,
+				Count:         1,
+				TotalDuration: idle.Duration,
+			})
+		}
+	}
+	
+	return stats, nil
+}
+
+// userName returns the name of a user, or fallback when the user is unknown
+func (s *truckIdleService) userName(userID uint, fallback string) string {
+	if userID == 0 || s.userRepo == nil {
+		return fallback
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || user == nil {
+		return fallback
+	}
+	return user.Name
+}
+
+// toIdleResponse maps a single idle detection to its response DTO
+func (s *truckIdleService) toIdleResponse(idle *model.TruckIdleDetection) (*model.TruckIdleResponse, error) {
+	responses, err := s.mapIdleDetectionsToResponses([]*model.TruckIdleDetection{idle})
+	if err != nil {
+		return nil, err
+	}
+	return responses[0], nil
+}
+
 // mapIdleDetectionsToResponses maps model entities to response DTOs
 func (s *truckIdleService) mapIdleDetectionsToResponses(idles []*model.TruckIdleDetection) ([]*model.TruckIdleResponse, error) {
 	responses := make([]*model.TruckIdleResponse, len(idles))
@@ -402,8 +786,29 @@ func (s *truckIdleService) mapIdleDetectionsToResponses(idles []*model.TruckIdle
 			EndTime:    idle.EndTime,
 			Duration:   idle.Duration,
 			IsResolved: idle.IsResolved,
+			RuleID:     idle.RuleID,
+			RuleName:   idle.RuleName,
+			ZoneID:     idle.ZoneID,
+			ZoneName:   idle.ZoneName,
+			IsSuppressed: idle.IsSuppressed,
+			CloseReason: idle.CloseReason,
+			IdleType:   idle.IdleType,
+			EngineOnSeconds: idle.EngineOnSeconds,
+			DriverID:   idle.DriverID,
+			AckReasonCode: idle.AckReasonCode,
+			AckComment: idle.AckComment,
+			AcknowledgedByID: idle.AcknowledgedByID,
+			AcknowledgedAt: idle.AcknowledgedAt,
+			DriverExplanation: idle.DriverExplanation,
+			DriverExplainedAt: idle.DriverExplainedAt,
 			CreatedAt:  idle.CreatedAt,
 		}
+		if idle.DriverID != nil {
+			responses[i].DriverName = s.userName(*idle.DriverID, "")
+		}
+		if idle.AcknowledgedByID != nil {
+			responses[i].AcknowledgedByName = s.userName(*idle.AcknowledgedByID, "")
+		}
 	}
 	
 	return responses, nil
@@ -411,15 +816,6 @@ func (s *truckIdleService) mapIdleDetectionsToResponses(idles []*model.TruckIdle
 
 // sendIdlePushNotification sends a push notification about idle detection
 func (s *truckIdleService) sendIdlePushNotification(truck *model.Truck, duration int) error {
-	// Get notification service URL from environment
-	notificationServiceURL := os.Getenv("NOTIFICATION_SERVICE_URL")
-	if notificationServiceURL == "" {
-		// Default URL for container environment
-		notificationServiceURL = "http://getstok-notification:8081/api/v1/push/send"
-	} else {
-		notificationServiceURL = fmt.Sprintf("%s/api/v1/push/send", notificationServiceURL)
-	}
-
 	// Get vehicle plate number for notification
 	plateNumber := truck.PlateNumber
 	if plateNumber == "" {
@@ -427,6 +823,20 @@ func (s *truckIdleService) sendIdlePushNotification(truck *model.Truck, duration
 	}
 
 	// Get driver information if available
+	_, driverName := s.findDriver(truck)
+
+	// Convert duration to minutes for user-friendly message
+	durationMinutes := duration / 60
+
+	// Prepare notification message
+	message := fmt.Sprintf("Vehicle %s operated by %s has been idle for %d minutes.", 
+		plateNumber, driverName, durationMinutes)
+
+	return s.sendPushNotification(truck, "Vehicle Idle Alert", message)
+}
+
+// findDriver returns the driver of the truck's active route plan, if any
+func (s *truckIdleService) findDriver(truck *model.Truck) (uint, string) {
 	var driverID uint
 	var driverName string
 
@@ -454,45 +864,11 @@ func (s *truckIdleService) sendIdlePushNotification(truck *model.Truck, duration
 		}
 	}
 
-	// Convert duration to minutes for user-friendly message
-	durationMinutes := duration / 60
-
-	// Prepare notification message
-	title := "Vehicle Idle Alert"
-	message := fmt.Sprintf("Vehicle %s operated by %s has been idle for %d minutes.", 
-		plateNumber, driverName, durationMinutes)
-
-	// Optional: Add URL to redirect to when notification is clicked
-	// This could be the dashboard page with the active truck selected
-	url := fmt.Sprintf("/management/dashboard?truck=%s", truck.MacID)
-
-	// Create notification request
-	notificationPayload := NotificationRequest{
-		Title:         title,
-		Message:       message,
-		URL:           url,
-		TargetRoles:   []string{"management"}, // Send to all management users
-		TargetUserIDs: []uint{driverID},       // Also notify the driver
-	}
-
-	// Convert to JSON
-	jsonData, err := json.Marshal(notificationPayload)
-	if err != nil {
-		return fmt.Errorf("error marshaling notification payload: %w", err)
-	}
-
-	// Send POST request to notification service
-	resp, err := http.Post(notificationServiceURL, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("error sending notification request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("notification service returned non-OK status: %d", resp.StatusCode)
-	}
+	return driverID, driverName
+}
 
-	log.Printf("Idle push notification sent for truck %s (Driver ID: %d)", truck.MacID, driverID)
-	return nil
+// sendPushNotification sends a push notification about a truck to management and its driver
+func (s *truckIdleService) sendPushNotification(truck *model.Truck, title, message string) error {
+	driverID, _ := s.findDriver(truck)
+	return postPushNotification(truck, title, message, driverID)
 }
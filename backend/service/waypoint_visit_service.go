@@ -0,0 +1,177 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
+)
+
+const (
+	// WaypointArrivalRadius is the distance in meters at which a truck has arrived at a waypoint
+	WaypointArrivalRadius = 100.0
+
+	// WaypointDepartureRadius is the distance in meters at which a truck has left a waypoint.
+	// It is larger than the arrival radius so GPS jitter at the edge does not split a visit.
+	WaypointDepartureRadius = 150.0
+)
+
+// WaypointVisitService mendeteksi kunjungan truk ke waypoint rute aktif
+type WaypointVisitService interface {
+	ProcessPosition(macID string, latitude, longitude float64, timestamp time.Time) error
+	GetVisitsByRoutePlanID(routePlanID uint) ([]model.WaypointVisitResponse, error)
+}
+
+type waypointVisitService struct {
+	visitRepo     repository.WaypointVisitRepository
+	truckRepo     repository.TruckRepository
+	routePlanRepo repository.RoutePlanRepository
+}
+
+// NewWaypointVisitService creates a new instance of WaypointVisitService
+func NewWaypointVisitService(
+	visitRepo repository.WaypointVisitRepository,
+	truckRepo repository.TruckRepository,
+	routePlanRepo repository.RoutePlanRepository,
+) WaypointVisitService {
+	return &waypointVisitService{
+		visitRepo:     visitRepo,
+		truckRepo:     truckRepo,
+		routePlanRepo: routePlanRepo,
+	}
+}
+
+// ProcessPosition opens a visit when the truck comes within reach of a waypoint of its
+// active route plan and closes it once the truck has driven away again
+func (s *waypointVisitService) ProcessPosition(macID string, latitude, longitude float64, timestamp time.Time) error {
+	truck, err := s.truckRepo.FindByMacID(macID)
+	if err != nil {
+		return err
+	}
+
+	routePlan, err := s.routePlanRepo.FindActiveRoutePlansByTruckID(truck.ID)
+	if err != nil {
+		// No active route plan, nothing to visit
+		return nil
+	}
+
+	waypoints, err := s.routePlanRepo.FindWaypointsByRoutePlanID(routePlan.ID)
+	if err != nil {
+		return err
+	}
+
+	// Truk masih berada di waypoint yang sedang dikunjungi
+	if visit, err := s.visitRepo.FindOpenByRoutePlanID(routePlan.ID); err == nil {
+		waypoint := findWaypoint(waypoints, visit.WaypointID)
+		if waypoint == nil {
+			return nil
+		}
+
+		distance := calculateDistance(latitude, longitude, waypoint.Latitude, waypoint.Longitude)
+		if distance < visit.DistanceToWaypoint {
+			visit.DistanceToWaypoint = distance
+		}
+		visit.DwellSeconds = int(timestamp.Sub(visit.ArrivalTime).Seconds())
+		visit.UpdatedAt = time.Now()
+
+		departed := distance > WaypointDepartureRadius
+		if departed {
+			departure := timestamp
+			visit.DepartureTime = &departure
+		}
+
+		if err := s.visitRepo.Update(visit); err != nil {
+			return err
+		}
+
+		if departed {
+			log.Printf("Truck %s departed waypoint %d of route plan %d after %d seconds", macID, waypoint.Order, routePlan.ID, visit.DwellSeconds)
+			s.publishVisit("waypoint_departure", macID, visit, waypoint)
+		}
+		return nil
+	}
+
+	// Cari waypoint terdekat dalam radius kedatangan
+	var nearest *model.RouteWaypoint
+	nearestDistance := WaypointArrivalRadius
+	for _, waypoint := range waypoints {
+		distance := calculateDistance(latitude, longitude, waypoint.Latitude, waypoint.Longitude)
+		if distance <= nearestDistance {
+			nearest = waypoint
+			nearestDistance = distance
+		}
+	}
+	if nearest == nil {
+		return nil
+	}
+
+	visit := &model.WaypointVisit{
+		RoutePlanID:        routePlan.ID,
+		WaypointID:         nearest.ID,
+		TruckID:            truck.ID,
+		MacID:              macID,
+		ArrivalTime:        timestamp,
+		DistanceToWaypoint: nearestDistance,
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
+	}
+	if err := s.visitRepo.Create(visit); err != nil {
+		return err
+	}
+
+	log.Printf("Truck %s arrived at waypoint %d of route plan %d", macID, nearest.Order, routePlan.ID)
+	s.publishVisit("waypoint_arrival", macID, visit, nearest)
+	return nil
+}
+
+// GetVisitsByRoutePlanID returns the visits of a route plan in arrival order
+func (s *waypointVisitService) GetVisitsByRoutePlanID(routePlanID uint) ([]model.WaypointVisitResponse, error) {
+	visits, err := s.visitRepo.FindByRoutePlanID(routePlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	waypoints, err := s.routePlanRepo.FindWaypointsByRoutePlanID(routePlanID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]model.WaypointVisitResponse, len(visits))
+	for i, visit := range visits {
+		responses[i] = visit.ToWaypointVisitResponse(findWaypoint(waypoints, visit.WaypointID))
+	}
+	return responses, nil
+}
+
+// publishVisit sends a visit update to the route and truck WebSocket subscribers
+func (s *waypointVisitService) publishVisit(eventType, macID string, visit *model.WaypointVisit, waypoint *model.RouteWaypoint) {
+	wsHub := websocket.GetHub()
+	if wsHub == nil {
+		return
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"type":   eventType,
+		"mac_id": macID,
+		"visit":  visit.ToWaypointVisitResponse(waypoint),
+	})
+	if err != nil {
+		log.Printf("Error marshaling waypoint visit update: %v", err)
+		return
+	}
+
+	wsHub.Publish(jsonData, websocket.RouteChannel(visit.RoutePlanID), websocket.TruckChannel(macID))
+}
+
+// findWaypoint returns the waypoint with the given ID, or nil
+func findWaypoint(waypoints []*model.RouteWaypoint, id uint) *model.RouteWaypoint {
+	for _, waypoint := range waypoints {
+		if waypoint.ID == id {
+			return waypoint
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
+)
+
+// websocketAuthorizer decides which WebSocket channels a user may subscribe to.
+// Management and planners see the whole fleet, drivers only their own truck and routes.
+type websocketAuthorizer struct {
+	routePlanRepo repository.RoutePlanRepository
+	truckRepo     repository.TruckRepository
+}
+
+// NewWebSocketAuthorizer creates the channel authorizer used by the WebSocket hub
+func NewWebSocketAuthorizer(
+	routePlanRepo repository.RoutePlanRepository,
+	truckRepo repository.TruckRepository,
+) websocket.ChannelAuthorizer {
+	return &websocketAuthorizer{
+		routePlanRepo: routePlanRepo,
+		truckRepo:     truckRepo,
+	}
+}
+
+// CanSubscribe implements websocket.ChannelAuthorizer
+func (a *websocketAuthorizer) CanSubscribe(userID uint, role string, channel string) bool {
+	switch role {
+	case "management", "planner":
+		return true
+	case "driver":
+		return a.driverCanSubscribe(userID, channel)
+	default:
+		return false
+	}
+}
+
+// driverCanSubscribe allows a driver to follow their own routes and the truck
+// assigned to a route they are currently driving
+func (a *websocketAuthorizer) driverCanSubscribe(driverID uint, channel string) bool {
+	if routePlanID, ok := websocket.RouteFromChannel(channel); ok {
+		routePlan, err := a.routePlanRepo.FindByID(routePlanID)
+		return err == nil && routePlan.DriverID == driverID
+	}
+
+	if macID, ok := websocket.TruckFromChannel(channel); ok {
+		truck, err := a.truckRepo.FindByMacID(macID)
+		if err != nil {
+			return false
+		}
+
+		routePlans, err := a.routePlanRepo.FindByDriverID(driverID)
+		if err != nil {
+			return false
+		}
+		for _, routePlan := range routePlans {
+			if routePlan.TruckID == truck.ID && (routePlan.Status == "active" || routePlan.Status == "on confirmation") {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Fleet-wide channels are not available to drivers
+	return false
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/repository"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/websocket"
+)
+
+// fakeAuthorizerRoutePlanRepo serves a fixed set of route plans
+type fakeAuthorizerRoutePlanRepo struct {
+	repository.RoutePlanRepository
+	plans []*model.RoutePlan
+}
+
+func (r *fakeAuthorizerRoutePlanRepo) FindByID(id uint) (*model.RoutePlan, error) {
+	for _, plan := range r.plans {
+		if plan.ID == id {
+			return plan, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeAuthorizerRoutePlanRepo) FindByDriverID(driverID uint) ([]*model.RoutePlan, error) {
+	var plans []*model.RoutePlan
+	for _, plan := range r.plans {
+		if plan.DriverID == driverID {
+			plans = append(plans, plan)
+		}
+	}
+	return plans, nil
+}
+
+// fakeAuthorizerTruckRepo serves a fixed set of trucks by MAC ID
+type fakeAuthorizerTruckRepo struct {
+	repository.TruckRepository
+	trucks []*model.Truck
+}
+
+func (r *fakeAuthorizerTruckRepo) FindByMacID(macID string) (*model.Truck, error) {
+	for _, truck := range r.trucks {
+		if truck.MacID == macID {
+			return truck, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func TestWebSocketAuthorizerCanSubscribe(t *testing.T) {
+	authorizer := NewWebSocketAuthorizer(
+		&fakeAuthorizerRoutePlanRepo{plans: []*model.RoutePlan{
+			{ID: 1, DriverID: 10, TruckID: 100, Status: model.RoutePlanStatusActive},
+			{ID: 2, DriverID: 10, TruckID: 200, Status: model.RoutePlanStatusCompleted},
+			{ID: 3, DriverID: 10, TruckID: 300, Status: "on confirmation"},
+			{ID: 4, DriverID: 11, TruckID: 400, Status: model.RoutePlanStatusActive},
+			{ID: 5, DriverID: 10, TruckID: 500, Status: model.RoutePlanStatusPlanned},
+		}},
+		&fakeAuthorizerTruckRepo{trucks: []*model.Truck{
+			{ID: 100, MacID: "AA"},
+			{ID: 200, MacID: "BB"},
+			{ID: 300, MacID: "CC"},
+			{ID: 400, MacID: "DD"},
+			{ID: 500, MacID: "EE"},
+		}},
+	)
+
+	tests := []struct {
+		name    string
+		userID  uint
+		role    string
+		channel string
+		want    bool
+	}{
+		{"management fleet", 1, "management", websocket.ChannelFleet, true},
+		{"management any truck", 1, "management", websocket.TruckChannel("DD"), true},
+		{"planner alerts", 2, "planner", websocket.ChannelAlerts, true},
+		{"planner any route", 2, "planner", websocket.RouteChannel(4), true},
+		{"unknown role", 3, "guest", websocket.RouteChannel(1), false},
+		{"no role", 3, "", websocket.ChannelFleet, false},
+		{"driver own route", 10, "driver", websocket.RouteChannel(1), true},
+		{"driver own completed route", 10, "driver", websocket.RouteChannel(2), true},
+		{"driver other driver's route", 10, "driver", websocket.RouteChannel(4), false},
+		{"driver missing route", 10, "driver", websocket.RouteChannel(99), false},
+		{"driver truck of active route", 10, "driver", websocket.TruckChannel("AA"), true},
+		{"driver truck of route on confirmation", 10, "driver", websocket.TruckChannel("CC"), true},
+		{"driver truck of completed route", 10, "driver", websocket.TruckChannel("BB"), false},
+		{"driver truck of planned route", 10, "driver", websocket.TruckChannel("EE"), false},
+		{"driver other driver's truck", 10, "driver", websocket.TruckChannel("DD"), false},
+		{"driver unknown truck", 10, "driver", websocket.TruckChannel("FF"), false},
+		{"driver fleet", 10, "driver", websocket.ChannelFleet, false},
+		{"driver alerts", 10, "driver", websocket.ChannelAlerts, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authorizer.CanSubscribe(tt.userID, tt.role, tt.channel); got != tt.want {
+				t.Errorf("CanSubscribe(%d, %q, %q) = %v, want %v", tt.userID, tt.role, tt.channel, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// EncodeRouteGeometry encodes points in the format of ORS route geometries: a Google encoded
+// polyline with precision 5 and an elevation value per point, which DecodeRouteGeometry and the
+// frontend decoder expect. Routing backends without elevation data encode 0.
+func EncodeRouteGeometry(points []LatLng) string {
+	var builder strings.Builder
+	prevLat, prevLng := 0, 0
+
+	for _, point := range points {
+		lat := int(math.Round(point.Lat * 1e5))
+		lng := int(math.Round(point.Lng * 1e5))
+		encodePolylineValue(&builder, lat-prevLat)
+		encodePolylineValue(&builder, lng-prevLng)
+		encodePolylineValue(&builder, 0)
+		prevLat, prevLng = lat, lng
+	}
+
+	return builder.String()
+}
+
+// DecodePolyline decodes a two dimensional Google encoded polyline with the given precision,
+// e.g. 5 for OSRM and 6 for Valhalla
+func DecodePolyline(encoded string, precision int) ([]LatLng, error) {
+	if encoded == "" {
+		return nil, errors.New("empty polyline string")
+	}
+
+	factor := math.Pow10(precision)
+	var points []LatLng
+	index, lat, lng := 0, 0, 0
+
+	for index < len(encoded) {
+		deltaLat, next, err := decodePolylineValue(encoded, index)
+		if err != nil {
+			return nil, err
+		}
+		deltaLng, next, err := decodePolylineValue(encoded, next)
+		if err != nil {
+			return nil, err
+		}
+		index = next
+
+		lat += deltaLat
+		lng += deltaLng
+		points = append(points, LatLng{
+			Lat: float64(lat) / factor,
+			Lng: float64(lng) / factor,
+		})
+	}
+
+	return points, nil
+}
+
+// encodePolylineValue appends one signed polyline value
+func encodePolylineValue(builder *strings.Builder, value int) {
+	shifted := value << 1
+	if value < 0 {
+		shifted = ^shifted
+	}
+	for shifted >= 0x20 {
+		builder.WriteByte(byte((0x20 | (shifted & 0x1f)) + 63))
+		shifted >>= 5
+	}
+	builder.WriteByte(byte(shifted + 63))
+}
+
+// decodePolylineValue reads one signed polyline value starting at index
+func decodePolylineValue(encoded string, index int) (int, int, error) {
+	result, shift := 0, 0
+	for {
+		if index >= len(encoded) {
+			return 0, index, errors.New("invalid polyline format")
+		}
+		b := int(encoded[index]) - 63
+		index++
+		result |= (b & 0x1f) << shift
+		shift += 5
+		if b < 0x20 {
+			break
+		}
+	}
+	if result&1 != 0 {
+		return ^(result >> 1), index, nil
+	}
+	return result >> 1, index, nil
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"math"
+)
+
+const (
+	// routeIndexCellDegrees is the grid cell size of the route index, about 550 m at the equator
+	routeIndexCellDegrees = 0.005
+
+	// routeIndexWindow is how many segments around the last match are checked first
+	routeIndexWindow = 50
+
+	// metersPerDegree is the length of one degree of latitude
+	metersPerDegree = 111320.0
+)
+
+type gridCell struct {
+	X int
+	Y int
+}
+
+// RouteIndex is a grid spatial index over the segments of a route polyline. It finds
+// the same nearest segment as CalculateDistanceToPolyline without scanning every segment.
+type RouteIndex struct {
+	points     []LatLng
+	cumulative []float64 // Jarak sepanjang rute sampai titik ke-i, dalam meter
+	cells      map[gridCell][]int
+	minLat     float64
+	maxLat     float64
+	minLng     float64
+	maxLng     float64
+}
+
+// NewRouteIndex builds a spatial index for the segments of a polyline
+func NewRouteIndex(points []LatLng) *RouteIndex {
+	index := &RouteIndex{
+		points:     points,
+		cumulative: make([]float64, len(points)),
+		cells:      make(map[gridCell][]int),
+		minLat:     math.Inf(1),
+		maxLat:     math.Inf(-1),
+		minLng:     math.Inf(1),
+		maxLng:     math.Inf(-1),
+	}
+
+	for i, point := range points {
+		if i > 0 {
+			index.cumulative[i] = index.cumulative[i-1] + CalculateHaversineDistance(points[i-1], point)
+		}
+		index.minLat = math.Min(index.minLat, point.Lat)
+		index.maxLat = math.Max(index.maxLat, point.Lat)
+		index.minLng = math.Min(index.minLng, point.Lng)
+		index.maxLng = math.Max(index.maxLng, point.Lng)
+	}
+
+	// Register every segment in each cell its bounding box touches
+	for i := 0; i < len(points)-1; i++ {
+		a, b := points[i], points[i+1]
+		minCell := cellOf(math.Min(a.Lat, b.Lat), math.Min(a.Lng, b.Lng))
+		maxCell := cellOf(math.Max(a.Lat, b.Lat), math.Max(a.Lng, b.Lng))
+		for x := minCell.X; x <= maxCell.X; x++ {
+			for y := minCell.Y; y <= maxCell.Y; y++ {
+				cell := gridCell{X: x, Y: y}
+				index.cells[cell] = append(index.cells[cell], i)
+			}
+		}
+	}
+
+	return index
+}
+
+// Points returns the indexed polyline
+func (r *RouteIndex) Points() []LatLng {
+	return r.points
+}
+
+// Length returns the length of the route in meters
+func (r *RouteIndex) Length() float64 {
+	if len(r.cumulative) == 0 {
+		return 0
+	}
+	return r.cumulative[len(r.cumulative)-1]
+}
+
+// DistanceAlong returns how far along the route a point on the given segment is, in meters
+func (r *RouteIndex) DistanceAlong(segmentIndex int, point LatLng) float64 {
+	if segmentIndex < 0 || segmentIndex >= len(r.points) {
+		return 0
+	}
+	return r.cumulative[segmentIndex] + CalculateHaversineDistance(r.points[segmentIndex], point)
+}
+
+// Nearest returns the distance to the closest segment, the closest point on it and its index.
+// hint is the segment matched for the previous position, -1 if unknown; the segments around
+// it are checked first so the grid search usually covers only a few cells.
+func (r *RouteIndex) Nearest(point LatLng, hint int) (float64, LatLng, int) {
+	if len(r.points) < 2 {
+		return CalculateDistanceToPolyline(point, r.points)
+	}
+
+	best := math.Inf(1)
+	var bestPoint LatLng
+	bestIndex := -1
+	check := func(i int) {
+		distance, reference := CalculateDistanceToSegment(point, r.points[i], r.points[i+1])
+		if distance < best || (distance == best && i < bestIndex) {
+			best, bestPoint, bestIndex = distance, reference, i
+		}
+	}
+
+	// Search window around the last matched segment
+	if hint >= 0 && hint < len(r.points)-1 {
+		from := hint - routeIndexWindow
+		if from < 0 {
+			from = 0
+		}
+		to := hint + routeIndexWindow
+		if to > len(r.points)-2 {
+			to = len(r.points) - 2
+		}
+		for i := from; i <= to; i++ {
+			check(i)
+		}
+	}
+
+	// Any closer segment must touch a cell within the current best distance. Without a
+	// good window match, grow the search radius until it covers the whole route.
+	radius := best
+	if math.IsInf(radius, 1) {
+		radius = routeIndexCellDegrees * metersPerDegree
+	}
+	for {
+		if r.coversRoute(point, radius) {
+			// The grid would visit most of the route, a plain scan is cheaper
+			return CalculateDistanceToPolyline(point, r.points)
+		}
+
+		r.visit(point, radius, check)
+		if best <= radius {
+			return best, bestPoint, bestIndex
+		}
+		radius *= 4
+	}
+}
+
+// visit calls fn once for every segment registered in the cells within radius meters of point
+func (r *RouteIndex) visit(point LatLng, radius float64, fn func(int)) {
+	latDelta, lngDelta := degreesAround(point, radius)
+	minCell := cellOf(point.Lat-latDelta, point.Lng-lngDelta)
+	maxCell := cellOf(point.Lat+latDelta, point.Lng+lngDelta)
+
+	seen := make(map[int]bool)
+	for x := minCell.X; x <= maxCell.X; x++ {
+		for y := minCell.Y; y <= maxCell.Y; y++ {
+			for _, i := range r.cells[gridCell{X: x, Y: y}] {
+				if !seen[i] {
+					seen[i] = true
+					fn(i)
+				}
+			}
+		}
+	}
+}
+
+// coversRoute reports whether the search box around point contains the route's bounding box
+func (r *RouteIndex) coversRoute(point LatLng, radius float64) bool {
+	latDelta, lngDelta := degreesAround(point, radius)
+	return point.Lat-latDelta <= r.minLat && point.Lat+latDelta >= r.maxLat &&
+		point.Lng-lngDelta <= r.minLng && point.Lng+lngDelta >= r.maxLng
+}
+
+// degreesAround converts a radius in meters to a conservative latitude and longitude delta
+func degreesAround(point LatLng, radius float64) (float64, float64) {
+	// 10% margin keeps the box larger than the haversine circle
+	latDelta := radius * 1.1 / metersPerDegree
+	maxLat := math.Min(math.Abs(point.Lat)+latDelta, 89.0)
+	lngDelta := radius * 1.1 / (metersPerDegree * math.Cos(maxLat*math.Pi/180))
+	return latDelta, lngDelta
+}
+
+// cellOf returns the grid cell containing a coordinate
+func cellOf(lat, lng float64) gridCell {
+	return gridCell{
+		X: int(math.Floor(lng / routeIndexCellDegrees)),
+		Y: int(math.Floor(lat / routeIndexCellDegrees)),
+	}
+}
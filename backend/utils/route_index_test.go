@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testRoute builds a winding route of n points starting in Jakarta, about 20 m between points
+func testRoute(n int, seed int64) []LatLng {
+	rng := rand.New(rand.NewSource(seed))
+	points := make([]LatLng, n)
+	point := LatLng{Lat: -6.2, Lng: 106.8}
+	heading := 0.0
+	for i := range points {
+		points[i] = point
+		heading += (rng.Float64() - 0.5) * 0.6
+		point.Lat += math.Cos(heading) * 0.00018
+		point.Lng += math.Sin(heading) * 0.00018
+	}
+	return points
+}
+
+func TestRouteIndexNearestMatchesLinearScan(t *testing.T) {
+	route := testRoute(12000, 1)
+	index := NewRouteIndex(route)
+
+	// Titik pada rute, di sekitar rute dan jauh dari rute
+	tests := []struct {
+		name  string
+		point LatLng
+		hint  int
+	}{
+		{"first point", route[0], -1},
+		{"last point", route[len(route)-1], -1},
+		{"shared segment endpoint", route[5000], -1},
+		{"shared segment endpoint with hint", route[5000], 4999},
+		{"midpoint of segment", LatLng{Lat: (route[700].Lat + route[701].Lat) / 2, Lng: (route[700].Lng + route[701].Lng) / 2}, -1},
+		{"near route without hint", LatLng{Lat: route[3000].Lat + 0.0003, Lng: route[3000].Lng - 0.0002}, -1},
+		{"near route with correct hint", LatLng{Lat: route[3000].Lat + 0.0003, Lng: route[3000].Lng - 0.0002}, 3000},
+		{"near route with stale hint", LatLng{Lat: route[3000].Lat + 0.0003, Lng: route[3000].Lng - 0.0002}, 9000},
+		{"hint out of range", route[8000], len(route) + 10},
+		{"negative hint", route[8000], -5},
+		{"1 km off route", LatLng{Lat: route[6000].Lat + 0.009, Lng: route[6000].Lng}, 6000},
+		{"far from route", LatLng{Lat: -7.5, Lng: 110.4}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantDistance, wantPoint, wantSegment := CalculateDistanceToPolyline(tt.point, route)
+			gotDistance, gotPoint, gotSegment := index.Nearest(tt.point, tt.hint)
+
+			if gotSegment != wantSegment {
+				t.Errorf("segment = %d, want %d", gotSegment, wantSegment)
+			}
+			if math.Abs(gotDistance-wantDistance) > 1e-9 {
+				t.Errorf("distance = %f, want %f", gotDistance, wantDistance)
+			}
+			if gotPoint != wantPoint {
+				t.Errorf("point = %v, want %v", gotPoint, wantPoint)
+			}
+		})
+	}
+}
+
+func TestRouteIndexNearestRandomPoints(t *testing.T) {
+	route := testRoute(12000, 2)
+	index := NewRouteIndex(route)
+	rng := rand.New(rand.NewSource(3))
+
+	hint := -1
+	for i := 0; i < 300; i++ {
+		base := route[rng.Intn(len(route))]
+		point := LatLng{
+			Lat: base.Lat + (rng.Float64()-0.5)*0.01,
+			Lng: base.Lng + (rng.Float64()-0.5)*0.01,
+		}
+
+		wantDistance, _, wantSegment := CalculateDistanceToPolyline(point, route)
+		gotDistance, _, gotSegment := index.Nearest(point, hint)
+		if gotSegment != wantSegment || math.Abs(gotDistance-wantDistance) > 1e-9 {
+			t.Fatalf("point %v hint %d: got segment %d at %f m, want segment %d at %f m",
+				point, hint, gotSegment, gotDistance, wantSegment, wantDistance)
+		}
+		hint = gotSegment
+	}
+}
+
+func TestRouteIndexShortRoutes(t *testing.T) {
+	point := LatLng{Lat: -6.2001, Lng: 106.8001}
+	tests := []struct {
+		name  string
+		route []LatLng
+	}{
+		{"empty", nil},
+		{"single point", []LatLng{{Lat: -6.2, Lng: 106.8}}},
+		{"single segment", []LatLng{{Lat: -6.2, Lng: 106.8}, {Lat: -6.2, Lng: 106.801}}},
+		{"repeated point", []LatLng{{Lat: -6.2, Lng: 106.8}, {Lat: -6.2, Lng: 106.8}, {Lat: -6.2, Lng: 106.801}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantDistance, _, wantSegment := CalculateDistanceToPolyline(point, tt.route)
+			gotDistance, _, gotSegment := NewRouteIndex(tt.route).Nearest(point, -1)
+			if gotSegment != wantSegment {
+				t.Errorf("segment = %d, want %d", gotSegment, wantSegment)
+			}
+			if gotDistance != wantDistance && !(math.IsInf(gotDistance, 1) && math.IsInf(wantDistance, 1)) {
+				t.Errorf("distance = %f, want %f", gotDistance, wantDistance)
+			}
+		})
+	}
+}
+
+// benchmarkPoints are positions around the route, as a truck reports them while driving it
+func benchmarkPoints(route []LatLng) []LatLng {
+	rng := rand.New(rand.NewSource(4))
+	points := make([]LatLng, 1000)
+	for i := range points {
+		base := route[i*len(route)/len(points)]
+		points[i] = LatLng{
+			Lat: base.Lat + (rng.Float64()-0.5)*0.0005,
+			Lng: base.Lng + (rng.Float64()-0.5)*0.0005,
+		}
+	}
+	return points
+}
+
+func BenchmarkRouteIndexNearest(b *testing.B) {
+	route := testRoute(12000, 1)
+	index := NewRouteIndex(route)
+	points := benchmarkPoints(route)
+
+	b.ResetTimer()
+	hint := -1
+	for i := 0; i < b.N; i++ {
+		_, _, hint = index.Nearest(points[i%len(points)], hint)
+	}
+}
+
+func BenchmarkRouteIndexNearestWithoutHint(b *testing.B) {
+	route := testRoute(12000, 1)
+	index := NewRouteIndex(route)
+	points := benchmarkPoints(route)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Nearest(points[i%len(points)], -1)
+	}
+}
+
+func BenchmarkCalculateDistanceToPolyline(b *testing.B) {
+	route := testRoute(12000, 1)
+	points := benchmarkPoints(route)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CalculateDistanceToPolyline(points[i%len(points)], route)
+	}
+}
@@ -206,3 +206,23 @@ func CalculateDistanceToPolyline(point LatLng, polyline []LatLng) (float64, LatL
 
 	return minDistance, closestReferencePoint, closestSegmentIndex
 }
+
+// PointInPolygon reports whether a point lies inside a polygon using ray casting.
+// The polygon does not need to repeat its first point at the end.
+func PointInPolygon(point LatLng, polygon []LatLng) bool {
+	if len(polygon) < 3 {
+		return false
+	}
+
+	inside := false
+	j := len(polygon) - 1
+	for i := 0; i < len(polygon); i++ {
+		pi, pj := polygon[i], polygon[j]
+		if (pi.Lat > point.Lat) != (pj.Lat > point.Lat) &&
+			point.Lng < (pj.Lng-pi.Lng)*(point.Lat-pi.Lat)/(pj.Lat-pi.Lat)+pi.Lng {
+			inside = !inside
+		}
+		j = i
+	}
+	return inside
+}
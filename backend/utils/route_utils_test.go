@@ -0,0 +1,48 @@
+package utils
+
+import "testing"
+
+func TestPointInPolygon(t *testing.T) {
+	// Gudang persegi di Jakarta
+	square := []LatLng{
+		{Lat: -6.20, Lng: 106.80},
+		{Lat: -6.20, Lng: 106.81},
+		{Lat: -6.21, Lng: 106.81},
+		{Lat: -6.21, Lng: 106.80},
+	}
+	closedSquare := append(append([]LatLng{}, square...), square[0])
+	// L-shaped site: the notch at the top right is outside
+	lShape := []LatLng{
+		{Lat: -6.20, Lng: 106.80},
+		{Lat: -6.20, Lng: 106.805},
+		{Lat: -6.205, Lng: 106.805},
+		{Lat: -6.205, Lng: 106.81},
+		{Lat: -6.21, Lng: 106.81},
+		{Lat: -6.21, Lng: 106.80},
+	}
+
+	tests := []struct {
+		name    string
+		point   LatLng
+		polygon []LatLng
+		want    bool
+	}{
+		{"center of square", LatLng{Lat: -6.205, Lng: 106.805}, square, true},
+		{"north of square", LatLng{Lat: -6.19, Lng: 106.805}, square, false},
+		{"east of square", LatLng{Lat: -6.205, Lng: 106.82}, square, false},
+		{"closed polygon", LatLng{Lat: -6.205, Lng: 106.805}, closedSquare, true},
+		{"outside closed polygon", LatLng{Lat: -6.215, Lng: 106.805}, closedSquare, false},
+		{"inside L shape", LatLng{Lat: -6.208, Lng: 106.808}, lShape, true},
+		{"in the notch of the L shape", LatLng{Lat: -6.202, Lng: 106.808}, lShape, false},
+		{"too few points", LatLng{Lat: -6.205, Lng: 106.805}, square[:2], false},
+		{"no polygon", LatLng{Lat: -6.205, Lng: 106.805}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PointInPolygon(tt.point, tt.polygon); got != tt.want {
+				t.Errorf("PointInPolygon(%v) = %v, want %v", tt.point, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// backend/websocket/auth.go
+package websocket
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+// AuthTimeout is how long a client may stay connected without authenticating
+const AuthTimeout = 10 * time.Second
+
+// KindAccessChange marks events after which users may have lost access to channels,
+// e.g. a route plan that was completed or deleted. Every replica rechecks the
+// subscriptions of its clients when it receives one.
+const KindAccessChange = "access_change"
+
+// ChannelAuthorizer decides whether an authenticated user may subscribe to a channel
+type ChannelAuthorizer interface {
+	CanSubscribe(userID uint, role string, channel string) bool
+}
+
+// ErrNotAuthenticated is returned when a client uses the socket before authenticating
+var ErrNotAuthenticated = errors.New("websocket client is not authenticated")
+
+// SetAuthorizer sets the authorizer used to check subscribe requests
+func (h *Hub) SetAuthorizer(authorizer ChannelAuthorizer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.authorizer = authorizer
+}
+
+// SetIdentity attaches an already validated user to the client
+func (h *Hub) SetIdentity(client *Client, userID uint, role string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client.UserID = userID
+	client.Role = role
+}
+
+// Authenticate validates a JWT and attaches the user to the client
+func (h *Hub) Authenticate(client *Client, token string) error {
+	if token == "" {
+		return errors.New("token is required")
+	}
+
+	userID, role, err := utils.ValidateToken(token)
+	if err != nil {
+		return err
+	}
+
+	h.SetIdentity(client, userID, role)
+	return nil
+}
+
+// RequireAuthentication calls closeConn when the client has not authenticated within timeout.
+// The caller stops the returned timer when the connection ends first.
+func (h *Hub) RequireAuthentication(client *Client, timeout time.Duration, closeConn func()) *time.Timer {
+	return time.AfterFunc(timeout, func() {
+		if !h.IsAuthenticated(client) {
+			closeConn()
+		}
+	})
+}
+
+// IsAuthenticated reports whether a user has been attached to the client
+func (h *Hub) IsAuthenticated(client *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return client.UserID != 0
+}
+
+// authorize checks whether the client may subscribe to the channel
+func (h *Hub) authorize(client *Client, channel string) error {
+	h.mu.Lock()
+	userID, role, authorizer := client.UserID, client.Role, h.authorizer
+	h.mu.Unlock()
+
+	if userID == 0 {
+		return ErrNotAuthenticated
+	}
+	if authorizer == nil || !authorizer.CanSubscribe(userID, role, channel) {
+		return errors.New("not allowed to subscribe to this channel")
+	}
+	return nil
+}
+
+// PublishAccessChange sends a message to the subscribers of the channels like Publish,
+// then makes every replica recheck which channels its clients may still follow
+func (h *Hub) PublishAccessChange(message []byte, channels ...string) {
+	if len(channels) == 0 {
+		return
+	}
+	h.publish(Envelope{Kind: KindAccessChange, Channels: channels, Data: message})
+}
+
+// heldSubscriptions is a copy of the subscriptions of an authenticated client
+type heldSubscriptions struct {
+	client   *Client
+	userID   uint
+	role     string
+	channels []string
+}
+
+// RecheckSubscriptions drops the subscriptions that clients are no longer allowed to hold
+// and tells them which channels they lost. The authorizer runs without the hub mutex held.
+func (h *Hub) RecheckSubscriptions() {
+	h.mu.Lock()
+	authorizer := h.authorizer
+	held := make([]heldSubscriptions, 0, len(h.clients))
+	for client := range h.clients {
+		if client.UserID == 0 || len(client.subscriptions) == 0 {
+			continue
+		}
+		channels := make([]string, 0, len(client.subscriptions))
+		for channel := range client.subscriptions {
+			channels = append(channels, channel)
+		}
+		held = append(held, heldSubscriptions{client: client, userID: client.UserID, role: client.Role, channels: channels})
+	}
+	h.mu.Unlock()
+
+	for _, subscriptions := range held {
+		var revoked []string
+		for _, channel := range subscriptions.channels {
+			if authorizer == nil || !authorizer.CanSubscribe(subscriptions.userID, subscriptions.role, channel) {
+				revoked = append(revoked, channel)
+			}
+		}
+		if len(revoked) == 0 {
+			continue
+		}
+
+		sort.Strings(revoked)
+		h.Unsubscribe(subscriptions.client, revoked...)
+		h.sendToClient(subscriptions.client, map[string]interface{}{
+			"type":          "unsubscribed",
+			"channels":      revoked,
+			"reason":        "access revoked",
+			"subscriptions": h.Subscriptions(subscriptions.client),
+		})
+	}
+}
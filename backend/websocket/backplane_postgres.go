@@ -0,0 +1,145 @@
+// backend/websocket/backplane_postgres.go
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresNotifyLimit is the maximum NOTIFY payload size accepted by Postgres
+const postgresNotifyLimit = 8000
+
+// postgresSeqFieldSize leaves room for the seq field the publish function adds to the payload
+const postgresSeqFieldSize = 30
+
+// postgresPublishFunction increments the event ID and notifies the envelope with it. The advisory
+// lock is held until commit, when the notification is queued, so concurrent publishers from every
+// replica are delivered in event ID order. The payload is the envelope JSON without a seq field.
+const postgresPublishFunction = `CREATE OR REPLACE FUNCTION ` + backplaneTopic + `_publish(topic text, payload text) RETURNS bigint AS $$
+DECLARE
+	seq bigint;
+BEGIN
+	PERFORM pg_advisory_xact_lock(hashtext(topic));
+	seq := nextval('` + backplaneSeq + `');
+	PERFORM pg_notify(topic, '{"seq":' || seq || ',' || substr(payload, 2));
+	RETURN seq;
+END
+$$ LANGUAGE plpgsql`
+
+// postgresBackplane distributes envelopes with Postgres LISTEN/NOTIFY
+type postgresBackplane struct {
+	dsn    string
+	pool   *pgxpool.Pool
+	cancel context.CancelFunc
+}
+
+// NewPostgresBackplane connects to Postgres for publishing notifications
+func NewPostgresBackplane(dsn string) (Backplane, error) {
+	if dsn == "" {
+		return nil, errors.New("a database DSN is required for the postgres backplane")
+	}
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	// Event IDs come from a sequence so they stay monotonic across replicas and restarts
+	if _, err := pool.Exec(context.Background(), "CREATE SEQUENCE IF NOT EXISTS "+backplaneSeq); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to create %s sequence: %w", backplaneSeq, err)
+	}
+	if _, err := pool.Exec(context.Background(), postgresPublishFunction); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to create %s_publish function: %w", backplaneTopic, err)
+	}
+
+	return &postgresBackplane{dsn: dsn, pool: pool}, nil
+}
+
+func (b *postgresBackplane) Publish(ctx context.Context, envelope Envelope) error {
+	envelope.Seq = 0
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	if len(payload)+postgresSeqFieldSize > postgresNotifyLimit {
+		return fmt.Errorf("message of %d bytes exceeds the postgres NOTIFY limit", len(payload))
+	}
+
+	_, err = b.pool.Exec(ctx, "SELECT "+backplaneTopic+"_publish($1, $2)", backplaneTopic, string(payload))
+	return err
+}
+
+func (b *postgresBackplane) Subscribe(ctx context.Context, handler func(Envelope)) error {
+	conn, err := b.listen(ctx)
+	if err != nil {
+		return err
+	}
+
+	listenCtx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+
+	go func() {
+		for {
+			notification, err := conn.WaitForNotification(listenCtx)
+			if err != nil {
+				conn.Close(context.Background())
+				if listenCtx.Err() != nil {
+					return
+				}
+
+				// Keep retrying until the listener is back
+				log.Printf("Postgres backplane listener lost: %v", err)
+				for {
+					time.Sleep(2 * time.Second)
+					if listenCtx.Err() != nil {
+						return
+					}
+					if conn, err = b.listen(listenCtx); err == nil {
+						break
+					}
+					log.Printf("Failed to restart postgres backplane listener: %v", err)
+				}
+				continue
+			}
+
+			var envelope Envelope
+			if err := json.Unmarshal([]byte(notification.Payload), &envelope); err != nil {
+				log.Printf("Error decoding postgres backplane message: %v", err)
+				continue
+			}
+			handler(envelope)
+		}
+	}()
+
+	return nil
+}
+
+// listen opens a dedicated connection listening on the backplane topic
+func (b *postgresBackplane) listen(ctx context.Context) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, b.dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres listener: %w", err)
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+backplaneTopic); err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("failed to listen on %s: %w", backplaneTopic, err)
+	}
+	return conn, nil
+}
+
+func (b *postgresBackplane) Close() error {
+	if b.cancel != nil {
+		b.cancel()
+	}
+	b.pool.Close()
+	return nil
+}
@@ -0,0 +1,90 @@
+// backend/websocket/backplane_redis.go
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPublishScript increments the event ID and publishes the envelope with it in one
+// atomic step, so subscribers receive the envelopes in event ID order. ARGV[2] is the
+// envelope JSON without a seq field.
+var redisPublishScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('PUBLISH', ARGV[1], '{"seq":' .. seq .. ',' .. string.sub(ARGV[2], 2))
+return seq
+`)
+
+// redisBackplane distributes envelopes through Redis pub/sub
+type redisBackplane struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+}
+
+// NewRedisBackplane connects to Redis using a redis:// URL
+func NewRedisBackplane(redisURL string) (Backplane, error) {
+	if redisURL == "" {
+		return nil, errors.New("REDIS_URL is required for the redis backplane")
+	}
+
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return &redisBackplane{client: client}, nil
+}
+
+func (b *redisBackplane) Publish(ctx context.Context, envelope Envelope) error {
+	envelope.Seq = 0
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return redisPublishScript.Run(ctx, b.client, []string{backplaneSeq}, backplaneTopic, payload).Err()
+}
+
+func (b *redisBackplane) Subscribe(ctx context.Context, handler func(Envelope)) error {
+	pubsub := b.client.Subscribe(ctx, backplaneTopic)
+
+	// Wait for the subscription to be confirmed before returning
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe to redis channel: %w", err)
+	}
+	b.pubsub = pubsub
+
+	go func() {
+		// The channel is closed when the pubsub is closed; go-redis reconnects on its own
+		for msg := range pubsub.Channel() {
+			var envelope Envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+				log.Printf("Error decoding redis backplane message: %v", err)
+				continue
+			}
+			handler(envelope)
+		}
+	}()
+
+	return nil
+}
+
+func (b *redisBackplane) Close() error {
+	if b.pubsub != nil {
+		if err := b.pubsub.Close(); err != nil {
+			log.Printf("Error closing redis subscription: %v", err)
+		}
+	}
+	return b.client.Close()
+}
@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestMemoryBackplaneDeliversInSeqOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		publishers int
+		messages   int
+	}{
+		{"single publisher", 1, 200},
+		{"concurrent publishers", 16, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backplane := NewMemoryBackplane()
+			var received []uint64
+			backplane.Subscribe(context.Background(), func(envelope Envelope) {
+				received = append(received, envelope.Seq)
+			})
+
+			var wg sync.WaitGroup
+			for p := 0; p < tt.publishers; p++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < tt.messages; i++ {
+						backplane.Publish(context.Background(), Envelope{Channels: []string{ChannelAlerts}, Data: []byte(`{}`)})
+					}
+				}()
+			}
+			wg.Wait()
+
+			if len(received) != tt.publishers*tt.messages {
+				t.Fatalf("received %d envelopes, want %d", len(received), tt.publishers*tt.messages)
+			}
+			for i, seq := range received {
+				if seq != uint64(i+1) {
+					t.Fatalf("envelope %d has seq %d, want %d", i, seq, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestHubPublishStampsEventID(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"object", `{"type":"alert"}`, `{"event_id":1,"type":"alert"}`},
+		{"empty object", `{}`, `{"event_id":1}`},
+		{"array is left unchanged", `[1,2]`, `[1,2]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backplane := NewMemoryBackplane()
+			hub := NewHub(backplane)
+			backplane.Subscribe(context.Background(), hub.deliver)
+
+			hub.Publish([]byte(tt.data), ChannelAlerts)
+
+			message := <-hub.broadcast
+			if message.seq != 1 {
+				t.Errorf("seq = %d, want 1", message.seq)
+			}
+			if string(message.data) != tt.want {
+				t.Errorf("data = %s, want %s", message.data, tt.want)
+			}
+		})
+	}
+}
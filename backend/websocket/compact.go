@@ -0,0 +1,129 @@
+// backend/websocket/compact.go
+package websocket
+
+import (
+	"log"
+	"time"
+
+	ws "github.com/gofiber/contrib/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Stream modes a client can negotiate at connect with ?stream=
+const (
+	StreamJSON    = "json"    // Default, one JSON message per update
+	StreamCompact = "compact" // Coalesced MessagePack fleet snapshots
+)
+
+// SnapshotInterval is how often compact clients receive fleet snapshots
+const SnapshotInterval = time.Second
+
+// KindTelemetry marks per-update truck telemetry, which compact clients
+// receive through fleet snapshots instead
+const KindTelemetry = "telemetry"
+
+// TruckState is the latest known telemetry of a truck
+type TruckState struct {
+	MacID     string  `json:"mac_id" msgpack:"m"`
+	Latitude  float64 `json:"latitude" msgpack:"la"`
+	Longitude float64 `json:"longitude" msgpack:"lo"`
+	Fuel      float64 `json:"fuel" msgpack:"f"`
+	Timestamp int64   `json:"timestamp" msgpack:"t"` // Unix milliseconds
+}
+
+// FleetSnapshot is the binary message sent to compact clients every tick
+type FleetSnapshot struct {
+	Type      string       `msgpack:"type"`
+	EventID   uint64       `msgpack:"event_id"`
+	Timestamp int64        `msgpack:"ts"` // Unix milliseconds
+	Full      bool         `msgpack:"full"`
+	Trucks    []TruckState `msgpack:"trucks"`
+}
+
+// PublishTelemetry sends a truck telemetry message to JSON clients subscribed
+// to the channels. When state is set it also updates the fleet snapshot
+// streamed to compact clients.
+func (h *Hub) PublishTelemetry(message []byte, state *TruckState, channels ...string) {
+	if len(channels) == 0 {
+		return
+	}
+	h.publish(Envelope{Kind: KindTelemetry, Channels: channels, Data: message, Telemetry: state})
+}
+
+// updateFleet merges a telemetry state into the fleet snapshot.
+// Only position or fuel changes mark the truck for the next tick.
+func (h *Hub) updateFleet(state TruckState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if current, ok := h.fleet[state.MacID]; ok &&
+		current.Latitude == state.Latitude &&
+		current.Longitude == state.Longitude &&
+		current.Fuel == state.Fuel {
+		h.fleet[state.MacID] = state
+		return
+	}
+	h.fleet[state.MacID] = state
+	h.changed[state.MacID] = true
+}
+
+// runSnapshots sends the trucks that changed since the previous tick to compact clients
+func (h *Hub) runSnapshots() {
+	ticker := time.NewTicker(SnapshotInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.sendSnapshots()
+	}
+}
+
+func (h *Hub) sendSnapshots() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	for client := range h.clients {
+		if !client.Compact {
+			continue
+		}
+
+		snapshot := FleetSnapshot{
+			Type:      "fleet_snapshot",
+			EventID:   h.lastSeq,
+			Timestamp: now,
+			Full:      client.needsFullSnapshot,
+		}
+		for macID, state := range h.fleet {
+			if !client.needsFullSnapshot && !h.changed[macID] {
+				continue
+			}
+			if client.matches([]string{ChannelFleet, TruckChannel(macID)}) {
+				snapshot.Trucks = append(snapshot.Trucks, state)
+			}
+		}
+		if len(snapshot.Trucks) == 0 && !snapshot.Full {
+			continue
+		}
+
+		data, err := msgpack.Marshal(snapshot)
+		if err != nil {
+			log.Printf("Error encoding fleet snapshot: %v", err)
+			continue
+		}
+		select {
+		case client.binary <- data:
+			client.needsFullSnapshot = false
+		default:
+			// Drop this tick, the next full snapshot brings the client up to date
+			client.needsFullSnapshot = true
+		}
+	}
+	h.changed = make(map[string]bool)
+}
+
+// writeBinary writes a binary frame to the client
+func (c *Client) writeBinary(data []byte) error {
+	c.Mu.Lock()
+	defer c.Mu.Unlock()
+	return c.Conn.WriteMessage(ws.BinaryMessage, data)
+}
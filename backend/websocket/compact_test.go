@@ -0,0 +1,224 @@
+package websocket
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// nextSnapshot decodes the next fleet snapshot queued for a compact client
+func nextSnapshot(t *testing.T, client *Client) (FleetSnapshot, bool) {
+	t.Helper()
+	select {
+	case data := <-client.binary:
+		var snapshot FleetSnapshot
+		if err := msgpack.Unmarshal(data, &snapshot); err != nil {
+			t.Fatalf("invalid snapshot: %v", err)
+		}
+		return snapshot, true
+	default:
+		return FleetSnapshot{}, false
+	}
+}
+
+func snapshotTrucks(snapshot FleetSnapshot) []string {
+	macIDs := make([]string, 0, len(snapshot.Trucks))
+	for _, truck := range snapshot.Trucks {
+		macIDs = append(macIDs, truck.MacID)
+	}
+	sort.Strings(macIDs)
+	return macIDs
+}
+
+func TestFleetSnapshotEncoding(t *testing.T) {
+	snapshot := FleetSnapshot{
+		Type:      "fleet_snapshot",
+		EventID:   42,
+		Timestamp: 1700000000000,
+		Full:      true,
+		Trucks:    []TruckState{{MacID: "AA", Latitude: -6.2, Longitude: 106.8, Fuel: 55.5, Timestamp: 1700000000000}},
+	}
+
+	data, err := msgpack.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	// Clients decode the short field names, not the Go struct
+	var decoded map[string]interface{}
+	if err := msgpack.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	for _, key := range []string{"type", "event_id", "ts", "full", "trucks"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("snapshot has no %q field: %v", key, decoded)
+		}
+	}
+	trucks, ok := decoded["trucks"].([]interface{})
+	if !ok || len(trucks) != 1 {
+		t.Fatalf("trucks = %v, want one truck", decoded["trucks"])
+	}
+	truck, ok := trucks[0].(map[string]interface{})
+	if !ok {
+		t.Fatalf("truck = %T, want a map", trucks[0])
+	}
+	tests := []struct {
+		key  string
+		want interface{}
+	}{
+		{"m", "AA"},
+		{"la", -6.2},
+		{"lo", 106.8},
+		{"f", 55.5},
+	}
+	for _, tt := range tests {
+		if truck[tt.key] != tt.want {
+			t.Errorf("truck[%q] = %v, want %v", tt.key, truck[tt.key], tt.want)
+		}
+	}
+
+	var roundTrip FleetSnapshot
+	if err := msgpack.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if roundTrip.EventID != 42 || !roundTrip.Full || len(roundTrip.Trucks) != 1 || roundTrip.Trucks[0] != snapshot.Trucks[0] {
+		t.Errorf("round trip = %+v, want %+v", roundTrip, snapshot)
+	}
+}
+
+func TestSendSnapshots(t *testing.T) {
+	fleet := map[string]TruckState{
+		"AA": {MacID: "AA", Latitude: -6.2, Longitude: 106.8, Fuel: 50},
+		"BB": {MacID: "BB", Latitude: -6.3, Longitude: 106.9, Fuel: 60},
+		"CC": {MacID: "CC", Latitude: -6.4, Longitude: 107.0, Fuel: 70},
+	}
+
+	tests := []struct {
+		name          string
+		subscriptions []string
+		compact       bool
+		full          bool
+		changed       []string
+		want          []string // Trucks in the snapshot, nil when none is sent
+	}{
+		{"fleet gets changed trucks", []string{ChannelFleet}, true, false, []string{"AA", "BB"}, []string{"AA", "BB"}},
+		{"truck channel gets only that truck", []string{TruckChannel("BB")}, true, false, []string{"AA", "BB"}, []string{"BB"}},
+		{"unchanged subscribed truck", []string{TruckChannel("CC")}, true, false, []string{"AA", "BB"}, nil},
+		{"full snapshot includes unchanged trucks", []string{ChannelFleet}, true, true, nil, []string{"AA", "BB", "CC"}},
+		{"full snapshot of a truck channel", []string{TruckChannel("CC")}, true, true, []string{"AA"}, []string{"CC"}},
+		{"other channels only", []string{ChannelAlerts}, true, false, []string{"AA"}, nil},
+		{"JSON client", []string{ChannelFleet}, false, true, []string{"AA"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(nil)
+			for macID, state := range fleet {
+				hub.fleet[macID] = state
+			}
+			for _, macID := range tt.changed {
+				hub.changed[macID] = true
+			}
+			hub.lastSeq = 7
+
+			client := &Client{Compact: tt.compact, binary: make(chan []byte, 1), subscriptions: map[string]bool{}, needsFullSnapshot: tt.full}
+			for _, channel := range tt.subscriptions {
+				client.subscriptions[channel] = true
+			}
+			hub.clients[client] = true
+
+			hub.sendSnapshots()
+
+			snapshot, ok := nextSnapshot(t, client)
+			if tt.want == nil {
+				if ok && len(snapshot.Trucks) > 0 {
+					t.Fatalf("unexpected snapshot with trucks %v", snapshotTrucks(snapshot))
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("no snapshot, want trucks %v", tt.want)
+			}
+			if got := snapshotTrucks(snapshot); !equalStrings(got, tt.want) {
+				t.Errorf("trucks = %v, want %v", got, tt.want)
+			}
+			if snapshot.Type != "fleet_snapshot" || snapshot.EventID != 7 || snapshot.Full != tt.full {
+				t.Errorf("snapshot = %+v, want type fleet_snapshot, event_id 7 and full %v", snapshot, tt.full)
+			}
+			if client.needsFullSnapshot {
+				t.Error("needsFullSnapshot still set after the snapshot was queued")
+			}
+			if len(hub.changed) != 0 {
+				t.Errorf("changed = %v, want it reset after the tick", hub.changed)
+			}
+		})
+	}
+}
+
+func TestSendSnapshotsFullBufferRequestsFullSnapshot(t *testing.T) {
+	hub := NewHub(nil)
+	hub.fleet["AA"] = TruckState{MacID: "AA"}
+	hub.changed["AA"] = true
+
+	client := &Client{Compact: true, binary: make(chan []byte), subscriptions: map[string]bool{ChannelFleet: true}}
+	hub.clients[client] = true
+
+	hub.sendSnapshots()
+
+	if !client.needsFullSnapshot {
+		t.Error("a dropped snapshot must make the next one full")
+	}
+}
+
+func TestPublishTelemetry(t *testing.T) {
+	hub := NewHub(nil)
+	if err := hub.backplane.Subscribe(context.Background(), hub.deliver); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	jsonClient := &Client{Send: make(chan []byte, 4), subscriptions: map[string]bool{ChannelFleet: true}}
+	compact := &Client{Send: make(chan []byte, 4), binary: make(chan []byte, 4), Compact: true, subscriptions: map[string]bool{ChannelFleet: true}}
+	hub.clients[jsonClient] = true
+	hub.clients[compact] = true
+	go hub.Run()
+
+	state := TruckState{MacID: "AA", Latitude: -6.2, Longitude: 106.8, Fuel: 50}
+	hub.PublishTelemetry([]byte(`{"type":"location_update"}`), &state, ChannelFleet, TruckChannel("AA"))
+
+	select {
+	case <-jsonClient.Send:
+	case <-time.After(time.Second):
+		t.Fatal("JSON client did not receive the telemetry message")
+	}
+	select {
+	case data := <-compact.Send:
+		t.Errorf("compact client received the telemetry message as JSON: %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	hub.sendSnapshots()
+	snapshot, ok := nextSnapshot(t, compact)
+	if !ok {
+		t.Fatal("compact client got no snapshot")
+	}
+	if len(snapshot.Trucks) != 1 || snapshot.Trucks[0] != state {
+		t.Errorf("snapshot trucks = %+v, want %+v", snapshot.Trucks, state)
+	}
+	if _, ok := nextSnapshot(t, jsonClient); ok {
+		t.Error("JSON client received a fleet snapshot")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
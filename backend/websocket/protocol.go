@@ -0,0 +1,194 @@
+// backend/websocket/protocol.go
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Channel names clients can subscribe to
+const (
+	ChannelFleet  = "fleet"  // Telemetry for every truck
+	ChannelAlerts = "alerts" // Idle and other fleet alerts
+
+	truckChannelPrefix = "truck:"
+	routeChannelPrefix = "route:"
+)
+
+// ClientMessage is a control message sent by a client over the socket
+type ClientMessage struct {
+	Type     string   `json:"type"`
+	Channels []string `json:"channels,omitempty"`
+	Token    string   `json:"token,omitempty"` // JWT for first-message authentication
+
+	// LastEventID asks a subscribe to replay the events missed since this ID
+	LastEventID uint64 `json:"last_event_id,omitempty"`
+}
+
+// TruckChannel returns the channel carrying updates for a single truck
+func TruckChannel(macID string) string {
+	return truckChannelPrefix + macID
+}
+
+// RouteChannel returns the channel carrying updates for a single route plan
+func RouteChannel(routePlanID uint) string {
+	return fmt.Sprintf("%s%d", routeChannelPrefix, routePlanID)
+}
+
+// TruckFromChannel returns the truck MAC ID addressed by a truck channel
+func TruckFromChannel(channel string) (string, bool) {
+	if !strings.HasPrefix(channel, truckChannelPrefix) {
+		return "", false
+	}
+	macID := strings.TrimPrefix(channel, truckChannelPrefix)
+	return macID, macID != ""
+}
+
+// RouteFromChannel returns the route plan ID addressed by a route channel
+func RouteFromChannel(channel string) (uint, bool) {
+	if !strings.HasPrefix(channel, routeChannelPrefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(strings.TrimPrefix(channel, routeChannelPrefix), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// ValidateChannel checks that a channel name is one the hub knows how to route
+func ValidateChannel(channel string) error {
+	switch {
+	case channel == ChannelFleet, channel == ChannelAlerts:
+		return nil
+	case strings.HasPrefix(channel, truckChannelPrefix):
+		if strings.TrimPrefix(channel, truckChannelPrefix) == "" {
+			return fmt.Errorf("channel %q is missing a truck MAC ID", channel)
+		}
+		return nil
+	case strings.HasPrefix(channel, routeChannelPrefix):
+		if _, err := strconv.ParseUint(strings.TrimPrefix(channel, routeChannelPrefix), 10, 32); err != nil {
+			return fmt.Errorf("channel %q has an invalid route plan ID", channel)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown channel %q", channel)
+	}
+}
+
+// HandleClientMessage processes a control message received from a client.
+// Messages that are not valid JSON or have an unknown type are ignored.
+// It returns ErrNotAuthenticated when an unauthenticated client fails to
+// authenticate, after which the caller should close the connection.
+func (h *Hub) HandleClientMessage(client *Client, message []byte) error {
+	var msg ClientMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return nil
+	}
+
+	if !h.IsAuthenticated(client) {
+		if msg.Type == "pong" {
+			h.UpdateClientPing(client)
+			return nil
+		}
+		if msg.Type != "auth" {
+			h.sendToClient(client, map[string]interface{}{
+				"type":    "error",
+				"message": "authentication required",
+			})
+			return nil
+		}
+		if err := h.Authenticate(client, msg.Token); err != nil {
+			h.sendToClient(client, map[string]interface{}{
+				"type":    "auth_failed",
+				"message": "invalid or expired token",
+			})
+			return ErrNotAuthenticated
+		}
+		h.sendToClient(client, map[string]interface{}{
+			"type":    "authenticated",
+			"user_id": client.UserID,
+			"role":    client.Role,
+		})
+		return nil
+	}
+
+	switch msg.Type {
+	case "pong":
+		// Explicitly update LastPing in the hub
+		h.UpdateClientPing(client)
+
+	case "subscribe", "unsubscribe":
+		if len(msg.Channels) == 0 {
+			h.sendToClient(client, map[string]interface{}{
+				"type":    "error",
+				"message": "channels are required",
+			})
+			return nil
+		}
+
+		accepted := make([]string, 0, len(msg.Channels))
+		rejected := make(map[string]string)
+		for _, channel := range msg.Channels {
+			if err := ValidateChannel(channel); err != nil {
+				rejected[channel] = err.Error()
+				continue
+			}
+			if msg.Type == "subscribe" {
+				if err := h.authorize(client, channel); err != nil {
+					rejected[channel] = err.Error()
+					continue
+				}
+			}
+			accepted = append(accepted, channel)
+		}
+
+		replayed, resumed := 0, true
+		if msg.Type == "subscribe" {
+			// A resume point from the connect URL applies to the first subscribe
+			lastEventID := h.takeResumePoint(client)
+			if msg.LastEventID > 0 {
+				lastEventID = msg.LastEventID
+			}
+			replayed, resumed = h.SubscribeFrom(client, lastEventID, accepted...)
+		} else {
+			h.Unsubscribe(client, accepted...)
+		}
+
+		response := map[string]interface{}{
+			"type":          msg.Type + "d",
+			"channels":      accepted,
+			"subscriptions": h.Subscriptions(client),
+		}
+		if len(rejected) > 0 {
+			response["rejected"] = rejected
+		}
+		if replayed > 0 {
+			response["replayed"] = replayed
+		}
+		h.sendToClient(client, response)
+
+		if !resumed {
+			// The missed events are gone, the client has to reload its state
+			h.sendToClient(client, map[string]interface{}{
+				"type":            "resync_required",
+				"channels":        accepted,
+				"latest_event_id": h.LatestEventID(),
+			})
+		}
+	}
+	return nil
+}
+
+// sendToClient marshals a payload and queues it for a single client
+func (h *Hub) sendToClient(client *Client, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshaling websocket response: %v", err)
+		return
+	}
+	h.BroadcastToClient(client, data)
+}
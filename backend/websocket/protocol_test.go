@@ -0,0 +1,329 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/model"
+	"github.com/hafidzyami/GetstokFleetMonitoring/backend/utils"
+)
+
+func TestValidateChannel(t *testing.T) {
+	tests := []struct {
+		channel string
+		valid   bool
+	}{
+		{ChannelFleet, true},
+		{ChannelAlerts, true},
+		{"truck:AA:BB:CC:DD:EE:FF", true},
+		{"truck:", false},
+		{"route:42", true},
+		{"route:", false},
+		{"route:abc", false},
+		{"route:-1", false},
+		{"route:99999999999", false},
+		{"Fleet", false},
+		{"", false},
+		{"drivers", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			err := ValidateChannel(tt.channel)
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateChannel(%q) = %v, want valid %v", tt.channel, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestChannelRoundTrip(t *testing.T) {
+	if macID, ok := TruckFromChannel(TruckChannel("AA:BB")); !ok || macID != "AA:BB" {
+		t.Errorf("TruckFromChannel(TruckChannel) = %q, %v", macID, ok)
+	}
+	if id, ok := RouteFromChannel(RouteChannel(42)); !ok || id != 42 {
+		t.Errorf("RouteFromChannel(RouteChannel) = %d, %v", id, ok)
+	}
+
+	tests := []struct {
+		name    string
+		channel string
+	}{
+		{"fleet", ChannelFleet},
+		{"empty truck", "truck:"},
+		{"route with letters", "route:x1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := TruckFromChannel(tt.channel); ok {
+				t.Errorf("TruckFromChannel(%q) reported a truck", tt.channel)
+			}
+			if _, ok := RouteFromChannel(tt.channel); ok {
+				t.Errorf("RouteFromChannel(%q) reported a route", tt.channel)
+			}
+		})
+	}
+}
+
+// fakeAuthorizer allows the channels listed per user ID
+type fakeAuthorizer struct {
+	mu      sync.Mutex
+	allowed map[uint]map[string]bool
+}
+
+func (a *fakeAuthorizer) CanSubscribe(userID uint, role string, channel string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return role == "management" || a.allowed[userID][channel]
+}
+
+func (a *fakeAuthorizer) revoke(userID uint, channel string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.allowed[userID], channel)
+}
+
+// newTestClient registers a client on the hub without a connection
+func newTestClient(hub *Hub) *Client {
+	client := &Client{Send: make(chan []byte, 16), subscriptions: make(map[string]bool)}
+	hub.clients[client] = true
+	return client
+}
+
+// nextMessage returns the next JSON message queued for the client
+func nextMessage(t *testing.T, client *Client) map[string]interface{} {
+	t.Helper()
+	select {
+	case data := <-client.Send:
+		var message map[string]interface{}
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Fatalf("invalid message %s: %v", data, err)
+		}
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("no message sent to the client")
+		return nil
+	}
+}
+
+// subscriptionsOf returns the sorted subscriptions of a client
+func subscriptionsOf(hub *Hub, client *Client) []string {
+	channels := hub.Subscriptions(client)
+	sort.Strings(channels)
+	return channels
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Setenv("JWT_SECRET", "other-secret")
+	foreignToken, err := utils.GenerateToken(model.User{ID: 7, Role: "driver"})
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	t.Setenv("JWT_SECRET", "test-secret")
+	validToken, err := utils.GenerateToken(model.User{ID: 7, Role: "driver"})
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantErr  bool
+		wantUser uint
+		wantRole string
+	}{
+		{"valid token", validToken, false, 7, "driver"},
+		{"empty token", "", true, 0, ""},
+		{"malformed token", "not-a-jwt", true, 0, ""},
+		{"signed with another secret", foreignToken, true, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(nil)
+			client := newTestClient(hub)
+
+			err := hub.Authenticate(client, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if client.UserID != tt.wantUser || client.Role != tt.wantRole {
+				t.Errorf("identity = %d/%q, want %d/%q", client.UserID, client.Role, tt.wantUser, tt.wantRole)
+			}
+			if hub.IsAuthenticated(client) != !tt.wantErr {
+				t.Errorf("IsAuthenticated() = %v, want %v", hub.IsAuthenticated(client), !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequireAuthentication(t *testing.T) {
+	tests := []struct {
+		name          string
+		authenticate  bool
+		stopEarly     bool
+		wantConnClose bool
+	}{
+		{"never authenticates", false, false, true},
+		{"authenticates in time", true, false, false},
+		{"connection ends first", false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(nil)
+			client := newTestClient(hub)
+
+			var closed atomic.Bool
+			timer := hub.RequireAuthentication(client, 20*time.Millisecond, func() { closed.Store(true) })
+			if tt.authenticate {
+				hub.SetIdentity(client, 7, "driver")
+			}
+			if tt.stopEarly {
+				timer.Stop()
+			}
+
+			time.Sleep(100 * time.Millisecond)
+			if closed.Load() != tt.wantConnClose {
+				t.Errorf("connection closed = %v, want %v", closed.Load(), tt.wantConnClose)
+			}
+		})
+	}
+}
+
+func TestHandleClientMessageAuthorization(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	driverToken, err := utils.GenerateToken(model.User{ID: 7, Role: "driver"})
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		messages          []string
+		wantErr           error // Returned by the last message
+		wantTypes         []string
+		wantSubscriptions []string
+	}{
+		{
+			"subscribe before authenticating",
+			[]string{`{"type":"subscribe","channels":["route:1"]}`},
+			nil, []string{"error"}, []string{},
+		},
+		{
+			"invalid token",
+			[]string{`{"type":"auth","token":"not-a-jwt"}`},
+			ErrNotAuthenticated, []string{"auth_failed"}, []string{},
+		},
+		{
+			"own channels accepted, fleet rejected",
+			[]string{`{"type":"auth","token":"` + driverToken + `"}`, `{"type":"subscribe","channels":["route:1","truck:AA","fleet"]}`},
+			nil, []string{"authenticated", "subscribed"}, []string{"route:1", "truck:AA"},
+		},
+		{
+			"other driver's route rejected",
+			[]string{`{"type":"auth","token":"` + driverToken + `"}`, `{"type":"subscribe","channels":["route:2"]}`},
+			nil, []string{"authenticated", "subscribed"}, []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(nil)
+			hub.SetAuthorizer(&fakeAuthorizer{allowed: map[uint]map[string]bool{
+				7: {"route:1": true, "truck:AA": true},
+			}})
+			client := newTestClient(hub)
+
+			var err error
+			for _, message := range tt.messages {
+				err = hub.HandleClientMessage(client, []byte(message))
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("HandleClientMessage() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, wantType := range tt.wantTypes {
+				if message := nextMessage(t, client); message["type"] != wantType {
+					t.Errorf("message type = %v, want %s (%v)", message["type"], wantType, message)
+				}
+			}
+			if got := subscriptionsOf(hub, client); !reflect.DeepEqual(got, tt.wantSubscriptions) {
+				t.Errorf("subscriptions = %v, want %v", got, tt.wantSubscriptions)
+			}
+		})
+	}
+}
+
+func TestRecheckSubscriptions(t *testing.T) {
+	tests := []struct {
+		name              string
+		role              string
+		revoke            []string
+		viaBackplane      bool
+		wantRevoked       []string
+		wantSubscriptions []string
+	}{
+		{"nothing revoked", "driver", nil, false, nil, []string{"route:1", "truck:AA"}},
+		{"route completed", "driver", []string{"truck:AA"}, false, []string{"truck:AA"}, []string{"route:1"}},
+		{"route reassigned", "driver", []string{"route:1", "truck:AA"}, false, []string{"route:1", "truck:AA"}, []string{}},
+		{"access change from the backplane", "driver", []string{"truck:AA"}, true, []string{"truck:AA"}, []string{"route:1"}},
+		{"management keeps access", "management", []string{"route:1", "truck:AA"}, false, nil, []string{"route:1", "truck:AA"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(nil)
+			authorizer := &fakeAuthorizer{allowed: map[uint]map[string]bool{
+				7: {"route:1": true, "truck:AA": true},
+			}}
+			hub.SetAuthorizer(authorizer)
+			client := newTestClient(hub)
+			hub.SetIdentity(client, 7, tt.role)
+			hub.Subscribe(client, "route:1", "truck:AA")
+
+			// An unauthenticated client holds no subscriptions to recheck
+			anonymous := newTestClient(hub)
+
+			for _, channel := range tt.revoke {
+				authorizer.revoke(7, channel)
+			}
+			if tt.viaBackplane {
+				hub.deliver(Envelope{Kind: KindAccessChange, Channels: []string{"route:1"}, Data: []byte(`{"type":"route_plan_status"}`)})
+			} else {
+				hub.RecheckSubscriptions()
+			}
+
+			if tt.wantRevoked != nil {
+				message := nextMessage(t, client)
+				if message["type"] != "unsubscribed" || message["reason"] != "access revoked" {
+					t.Fatalf("message = %v, want an access revoked unsubscribe", message)
+				}
+				var revoked []string
+				for _, channel := range message["channels"].([]interface{}) {
+					revoked = append(revoked, channel.(string))
+				}
+				if !reflect.DeepEqual(revoked, tt.wantRevoked) {
+					t.Errorf("revoked = %v, want %v", revoked, tt.wantRevoked)
+				}
+			} else {
+				select {
+				case data := <-client.Send:
+					t.Errorf("unexpected message %s", data)
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+			if got := subscriptionsOf(hub, client); !reflect.DeepEqual(got, tt.wantSubscriptions) {
+				t.Errorf("subscriptions = %v, want %v", got, tt.wantSubscriptions)
+			}
+			if len(anonymous.Send) != 0 {
+				t.Errorf("unauthenticated client received %d messages", len(anonymous.Send))
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// backend/websocket/replay.go
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+const (
+	// ReplayBufferSize is the number of recent events kept per channel for resuming clients
+	ReplayBufferSize = 500
+	// FleetReplayBufferSize is the number of recent events kept on the fleet channel, which carries
+	// every telemetry update of every truck. 100 trucks reporting every 5 seconds fill it in about 40 minutes.
+	FleetReplayBufferSize = 50000
+)
+
+// bufferedEvent is a sequenced message kept for replay
+type bufferedEvent struct {
+	seq  uint64
+	kind string
+	data []byte
+}
+
+// eventRing is a bounded buffer of the most recent events of one channel
+type eventRing struct {
+	events  []bufferedEvent
+	next    int
+	full    bool
+	evicted uint64 // highest event ID dropped from the ring
+}
+
+func newEventRing(size int) *eventRing {
+	return &eventRing{events: make([]bufferedEvent, size)}
+}
+
+func (r *eventRing) add(event bufferedEvent) {
+	if r.full {
+		r.evicted = r.events[r.next].seq
+	}
+	r.events[r.next] = event
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// since returns the buffered events newer than lastEventID, oldest first
+func (r *eventRing) since(lastEventID uint64) []bufferedEvent {
+	var events []bufferedEvent
+	count := r.next
+	start := 0
+	if r.full {
+		count = len(r.events)
+		start = r.next
+	}
+	for i := 0; i < count; i++ {
+		event := r.events[(start+i)%len(r.events)]
+		if event.seq > lastEventID {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// record stores a sequenced message in the history of its channels.
+// The caller must hold the hub mutex.
+func (h *Hub) record(message outboundMessage) {
+	if message.seq == 0 {
+		return
+	}
+	if h.firstSeq == 0 {
+		h.firstSeq = message.seq
+	}
+	if message.seq > h.lastSeq {
+		h.lastSeq = message.seq
+	}
+
+	for _, channel := range message.channels {
+		ring, ok := h.history[channel]
+		if !ok {
+			ring = newEventRing(h.replayBufferSize(channel))
+			h.history[channel] = ring
+		}
+		ring.add(bufferedEvent{seq: message.seq, kind: message.kind, data: message.data})
+	}
+}
+
+// replayBufferSize returns how many events are kept for a channel
+func (h *Hub) replayBufferSize(channel string) int {
+	switch {
+	case channel == ChannelFleet && h.fleetReplaySize > 0:
+		return h.fleetReplaySize
+	case channel == ChannelFleet:
+		return FleetReplayBufferSize
+	case h.replaySize > 0:
+		return h.replaySize
+	default:
+		return ReplayBufferSize
+	}
+}
+
+// SetReplayBufferSizes sets how many events are kept per channel and on the fleet channel.
+// Sizes of zero or less keep the defaults. Channels that already have a history keep their size.
+func (h *Hub) SetReplayBufferSizes(size, fleetSize int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if size > 0 {
+		h.replaySize = size
+	}
+	if fleetSize > 0 {
+		h.fleetReplaySize = fleetSize
+	}
+}
+
+// missedEvents collects the events after lastEventID on the given channels.
+// It returns false when some of them are no longer buffered.
+// The caller must hold the hub mutex.
+func (h *Hub) missedEvents(lastEventID uint64, channels []string) ([]bufferedEvent, bool) {
+	// Ahead of this replica means the counter was reset, behind the first
+	// event seen means this replica started after the client went away
+	if lastEventID > h.lastSeq || (h.firstSeq > 0 && lastEventID+1 < h.firstSeq) {
+		return nil, false
+	}
+
+	seen := make(map[uint64]bool)
+	var events []bufferedEvent
+	for _, channel := range channels {
+		ring, ok := h.history[channel]
+		if !ok {
+			continue
+		}
+		if ring.evicted > lastEventID {
+			return nil, false
+		}
+		for _, event := range ring.since(lastEventID) {
+			// An event published to several channels is only replayed once
+			if !seen[event.seq] {
+				seen[event.seq] = true
+				events = append(events, event)
+			}
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].seq < events[j].seq })
+	return events, true
+}
+
+// SubscribeFrom subscribes the client to the channels and replays the events
+// it missed on them since lastEventID. Subscribing and replaying happen
+// atomically so no event is both replayed and delivered live. The replayed
+// events are queued apart from the send buffer, so a long gap is not limited
+// by its size, and written before any later message. It returns the number of
+// replayed events, or false when the gap is too large to replay and the client
+// has to reload its state instead.
+func (h *Hub) SubscribeFrom(client *Client, lastEventID uint64, channels ...string) (int, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.subscribeLocked(client, channels)
+	if lastEventID == 0 {
+		return 0, true
+	}
+
+	events, ok := h.missedEvents(lastEventID, channels)
+	if !ok {
+		return 0, false
+	}
+	replayed := 0
+	for _, event := range events {
+		// Compact clients catch up on telemetry with their next full snapshot
+		if client.Compact && event.kind == KindTelemetry {
+			continue
+		}
+		client.replay = append(client.replay, event.data)
+		replayed++
+	}
+	if replayed > 0 {
+		select {
+		case client.replayReady <- struct{}{}:
+		default:
+			// The writer has been signalled already
+		}
+	}
+	return replayed, true
+}
+
+// takeReplay returns and clears the replayed events queued for the client
+func (h *Hub) takeReplay(client *Client) [][]byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	replay := client.replay
+	client.replay = nil
+	return replay
+}
+
+// SetResumePoint records the last event ID a reconnecting client saw. The
+// events it missed are replayed when it subscribes to its channels.
+func (h *Hub) SetResumePoint(client *Client, lastEventID uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	client.resumeFrom = lastEventID
+}
+
+// takeResumePoint returns and clears the client's pending resume point
+func (h *Hub) takeResumePoint(client *Client) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	lastEventID := client.resumeFrom
+	client.resumeFrom = 0
+	return lastEventID
+}
+
+// LatestEventID returns the newest event ID this replica has seen
+func (h *Hub) LatestEventID() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastSeq
+}
+
+// withEventID stamps the event ID into a JSON object message so clients can
+// resume from it. Messages that are not JSON objects are returned unchanged.
+func withEventID(data []byte, seq uint64) []byte {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) < 2 || trimmed[0] != '{' || !json.Valid(trimmed) {
+		return data
+	}
+
+	stamped := make([]byte, 0, len(trimmed)+32)
+	stamped = append(stamped, `{"event_id":`...)
+	stamped = strconv.AppendUint(stamped, seq, 10)
+	body := bytes.TrimSpace(trimmed[1:])
+	if body[0] != '}' {
+		stamped = append(stamped, ',')
+	}
+	return append(stamped, body...)
+}
@@ -0,0 +1,210 @@
+package websocket
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithEventID(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		seq  uint64
+		want string
+	}{
+		{"object", `{"type":"alert"}`, 7, `{"event_id":7,"type":"alert"}`},
+		{"empty object", `{}`, 7, `{"event_id":7}`},
+		{"surrounding whitespace", "  {\"a\":1}\n", 12, `{"event_id":12,"a":1}`},
+		{"whitespace inside empty object", `{ }`, 3, `{"event_id":3}`},
+		{"array", `[1,2]`, 7, `[1,2]`},
+		{"string", `"text"`, 7, `"text"`},
+		{"invalid JSON", `{"type":`, 7, `{"type":`},
+		{"not JSON", `hello`, 7, `hello`},
+		{"empty", ``, 7, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(withEventID([]byte(tt.data), tt.seq)); got != tt.want {
+				t.Errorf("withEventID(%q, %d) = %q, want %q", tt.data, tt.seq, got, tt.want)
+			}
+		})
+	}
+}
+
+// seqs returns the event IDs of the events
+func seqs(events []bufferedEvent) []uint64 {
+	ids := []uint64{}
+	for _, event := range events {
+		ids = append(ids, event.seq)
+	}
+	return ids
+}
+
+func TestEventRingSince(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		added       []uint64
+		lastEventID uint64
+		want        []uint64
+		wantEvicted uint64
+	}{
+		{"empty ring", 3, nil, 0, []uint64{}, 0},
+		{"all events", 3, []uint64{1, 2}, 0, []uint64{1, 2}, 0},
+		{"events after the last seen", 3, []uint64{1, 2, 3}, 1, []uint64{2, 3}, 0},
+		{"up to date", 3, []uint64{1, 2, 3}, 3, []uint64{}, 0},
+		{"wrapped ring keeps order", 3, []uint64{1, 2, 3, 4, 5}, 0, []uint64{3, 4, 5}, 2},
+		{"wrapped ring after the last seen", 3, []uint64{1, 2, 3, 4, 5}, 3, []uint64{4, 5}, 2},
+		{"gaps from other channels", 4, []uint64{2, 5, 9}, 4, []uint64{5, 9}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ring := newEventRing(tt.size)
+			for _, seq := range tt.added {
+				ring.add(bufferedEvent{seq: seq})
+			}
+			if got := seqs(ring.since(tt.lastEventID)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("since(%d) = %v, want %v", tt.lastEventID, got, tt.want)
+			}
+			if ring.evicted != tt.wantEvicted {
+				t.Errorf("evicted = %d, want %d", ring.evicted, tt.wantEvicted)
+			}
+		})
+	}
+}
+
+func TestHubMissedEvents(t *testing.T) {
+	hub := NewHub(nil)
+	hub.record(outboundMessage{seq: 10, channels: []string{ChannelAlerts}})
+	hub.record(outboundMessage{seq: 11, channels: []string{"truck:AA", ChannelFleet}})
+	hub.record(outboundMessage{seq: 12}) // Without channels, not kept
+	hub.record(outboundMessage{seq: 13, channels: []string{"truck:BB"}})
+	hub.record(outboundMessage{seq: 0, channels: []string{ChannelAlerts}}) // Unsequenced, not kept
+
+	tests := []struct {
+		name        string
+		lastEventID uint64
+		channels    []string
+		want        []uint64
+		ok          bool
+	}{
+		{"alerts since start", 9, []string{ChannelAlerts}, []uint64{10}, true},
+		{"several channels once each", 9, []string{"truck:AA", ChannelFleet}, []uint64{11}, true},
+		{"after the last seen", 11, []string{"truck:AA", "truck:BB"}, []uint64{13}, true},
+		{"up to date", 13, []string{ChannelAlerts}, []uint64{}, true},
+		{"ahead of this replica", 20, []string{ChannelAlerts}, nil, false},
+		{"before this replica started", 5, []string{ChannelAlerts}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, ok := hub.missedEvents(tt.lastEventID, tt.channels)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && !reflect.DeepEqual(seqs(events), tt.want) {
+				t.Errorf("events = %v, want %v", seqs(events), tt.want)
+			}
+		})
+	}
+}
+
+func TestHubMissedEventsEvicted(t *testing.T) {
+	hub := NewHub(nil)
+	for seq := uint64(1); seq <= ReplayBufferSize+10; seq++ {
+		hub.record(outboundMessage{seq: seq, channels: []string{ChannelAlerts}})
+	}
+
+	if _, ok := hub.missedEvents(5, []string{ChannelAlerts}); ok {
+		t.Error("events evicted from the buffer were reported as replayable")
+	}
+	events, ok := hub.missedEvents(ReplayBufferSize, []string{ChannelAlerts})
+	if !ok || len(events) != 10 {
+		t.Errorf("missedEvents(%d) = %d events, %v, want 10 events", ReplayBufferSize, len(events), ok)
+	}
+}
+
+func TestSubscribeFromFleetAfterGap(t *testing.T) {
+	// 100 trucks reporting every 5 seconds, with an alert every 100 events
+	const eventsPerMinute = 100 * 12
+
+	tests := []struct {
+		name         string
+		events       int
+		lastEventID  uint64
+		compact      bool
+		channels     []string
+		wantReplayed int
+		wantResumed  bool
+	}{
+		{"fleet after a 10 minute gap", 10 * eventsPerMinute, 100, false, []string{ChannelFleet, ChannelAlerts}, 10*eventsPerMinute - 100, true},
+		{"alerts after a 10 minute gap", 10 * eventsPerMinute, 100, false, []string{ChannelAlerts}, (10*eventsPerMinute - 100) / 100, true},
+		{"compact client skips telemetry", 10 * eventsPerMinute, 100, true, []string{ChannelFleet, ChannelAlerts}, (10*eventsPerMinute - 100) / 100, true},
+		{"gap longer than the fleet buffer", FleetReplayBufferSize + 1000, 100, false, []string{ChannelFleet}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(nil)
+			for seq := uint64(1); seq <= uint64(tt.events); seq++ {
+				if seq%100 == 0 {
+					hub.record(outboundMessage{seq: seq, channels: []string{ChannelAlerts}, data: []byte("alert")})
+					continue
+				}
+				hub.record(outboundMessage{seq: seq, kind: KindTelemetry, channels: []string{ChannelFleet, "truck:AA"}, data: []byte("telemetry")})
+			}
+
+			client := &Client{Send: make(chan []byte, 256), Compact: tt.compact, replayReady: make(chan struct{}, 1)}
+			replayed, resumed := hub.SubscribeFrom(client, tt.lastEventID, tt.channels...)
+			if resumed != tt.wantResumed || replayed != tt.wantReplayed {
+				t.Fatalf("SubscribeFrom() = %d, %v, want %d, %v", replayed, resumed, tt.wantReplayed, tt.wantResumed)
+			}
+			if len(client.Send) != 0 {
+				t.Errorf("replayed events went through the send buffer: %d queued", len(client.Send))
+			}
+
+			queued := hub.takeReplay(client)
+			if len(queued) != tt.wantReplayed {
+				t.Errorf("queued %d replayed events, want %d", len(queued), tt.wantReplayed)
+			}
+			if tt.wantReplayed > 0 {
+				select {
+				case <-client.replayReady:
+				default:
+					t.Error("writer was not woken up for the replayed events")
+				}
+			}
+			if rest := hub.takeReplay(client); len(rest) != 0 {
+				t.Errorf("takeReplay returned %d events twice", len(rest))
+			}
+		})
+	}
+}
+
+func TestReplayBufferSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		fleetSize int
+		channel   string
+		want      int
+	}{
+		{"default", 0, 0, ChannelAlerts, ReplayBufferSize},
+		{"default fleet", 0, 0, ChannelFleet, FleetReplayBufferSize},
+		{"configured", 1000, 0, "truck:AA", 1000},
+		{"configured fleet", 1000, 200000, ChannelFleet, 200000},
+		{"negative keeps default", -1, -1, ChannelFleet, FleetReplayBufferSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub(nil)
+			hub.SetReplayBufferSizes(tt.size, tt.fleetSize)
+			if got := hub.replayBufferSize(tt.channel); got != tt.want {
+				t.Errorf("replayBufferSize(%q) = %d, want %d", tt.channel, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package websocket
 
 import (
+    "context"
     "log"
     "sync"
     "time"
@@ -15,6 +16,36 @@ type Client struct {
     Mu        sync.Mutex
     Send      chan []byte   // Channel for outbound messages
     LastPing  time.Time     // Track last ping time
+    UserID    uint          // Authenticated user, zero until the client has authenticated
+    Role      string        // Role of the authenticated user
+    Compact   bool          // Receives fleet telemetry as binary snapshots instead of JSON updates
+
+    // subscriptions holds the channels this client listens to, guarded by the hub mutex
+    subscriptions map[string]bool
+    // resumeFrom is the last event ID the client saw before reconnecting,
+    // replayed from on its first subscribe. Guarded by the hub mutex.
+    resumeFrom uint64
+    // replay holds missed events waiting to be written ahead of Send, guarded by the hub mutex
+    replay [][]byte
+    // replayReady wakes the writer when replayed events are queued
+    replayReady chan struct{}
+
+    // binary carries compact fleet snapshots, written as binary frames
+    binary chan []byte
+    // needsFullSnapshot makes the next snapshot include every subscribed truck
+    needsFullSnapshot bool
+}
+
+// backplaneTimeout bounds how long publishing to the backplane may block a caller
+const backplaneTimeout = 2 * time.Second
+
+// outboundMessage is a message queued on the hub together with its target channels.
+// A message without channels is not delivered to any client.
+type outboundMessage struct {
+    seq      uint64
+    kind     string
+    channels []string
+    data     []byte
 }
 
 // Hub maintains the set of active clients
@@ -22,17 +53,40 @@ type Hub struct {
     clients    map[*Client]bool
     register   chan *Client
     unregister chan *Client
-    broadcast  chan []byte
+    broadcast  chan outboundMessage
+    authorizer ChannelAuthorizer
+    backplane  Backplane
     mu         sync.Mutex
+
+    // history keeps recent sequenced events per channel for resuming clients
+    history  map[string]*eventRing
+    firstSeq uint64
+    lastSeq  uint64
+
+    // replaySize and fleetReplaySize override the replay buffer sizes, zero keeps the default
+    replaySize      int
+    fleetReplaySize int
+
+    // fleet keeps the latest telemetry per truck for compact snapshots,
+    // changed the trucks updated since the previous tick
+    fleet   map[string]TruckState
+    changed map[string]bool
 }
 
-// NewHub creates a new Hub instance
-func NewHub() *Hub {
+// NewHub creates a new Hub instance that distributes messages through the backplane
+func NewHub(backplane Backplane) *Hub {
+    if backplane == nil {
+        backplane = NewMemoryBackplane()
+    }
     return &Hub{
         clients:    make(map[*Client]bool),
         register:   make(chan *Client),
         unregister: make(chan *Client),
-        broadcast:  make(chan []byte, 256), // Buffered channel
+        broadcast:  make(chan outboundMessage, 256), // Buffered channel
+        backplane:  backplane,
+        history:    make(map[string]*eventRing),
+        fleet:      make(map[string]TruckState),
+        changed:    make(map[string]bool),
     }
 }
 
@@ -49,6 +103,9 @@ func (h *Hub) Run() {
         case client := <-h.register:
             h.mu.Lock()
             client.LastPing = time.Now() // Set initial ping time
+            if client.subscriptions == nil {
+                client.subscriptions = make(map[string]bool)
+            }
             h.clients[client] = true
             h.mu.Unlock()
             // log.Println("New client connected, total clients:", len(h.clients))
@@ -64,10 +121,18 @@ func (h *Hub) Run() {
             
         case message := <-h.broadcast:
             h.mu.Lock()
+            h.record(message)
             clientCount := len(h.clients)
             for client := range h.clients {
+                if !client.matches(message.channels) {
+                    continue
+                }
+                // Compact clients get telemetry through fleet snapshots
+                if client.Compact && message.kind == KindTelemetry {
+                    continue
+                }
                 select {
-                case client.Send <- message:
+                case client.Send <- message.data:
                     // Message sent to client's send channel
                 default:
                     // Client's send buffer is full, assume it's dead
@@ -97,24 +162,66 @@ func (h *Hub) Run() {
     }
 }
 
+// matches reports whether the client is subscribed to at least one of the channels.
+// The caller must hold the hub mutex.
+func (c *Client) matches(channels []string) bool {
+    for _, channel := range channels {
+        if c.subscriptions[channel] {
+            return true
+        }
+    }
+    return false
+}
+
 // Register registers a new client
 func (h *Hub) Register(client *Client) {
     // Initialize the send channel if not already done
     if client.Send == nil {
         client.Send = make(chan []byte, 256)
     }
+    if client.binary == nil {
+        client.binary = make(chan []byte, 4)
+    }
+    if client.replayReady == nil {
+        client.replayReady = make(chan struct{}, 1)
+    }
     h.register <- client
     
     // Start a goroutine to pump messages from the hub to the client
     go func() {
-        for message := range client.Send {
-            client.Mu.Lock()
-            err := client.Conn.WriteMessage(ws.TextMessage, message)
-            client.Mu.Unlock()
-            
-            if err != nil {
-                log.Printf("Error writing to client: %v", err)
-                break
+        for {
+            // Missed events go out before anything queued after them
+            for _, message := range h.takeReplay(client) {
+                client.Mu.Lock()
+                err := client.Conn.WriteMessage(ws.TextMessage, message)
+                client.Mu.Unlock()
+
+                if err != nil {
+                    log.Printf("Error writing replayed event to client: %v", err)
+                    return
+                }
+            }
+
+            select {
+            case <-client.replayReady:
+                // Replayed events are written at the top of the loop
+            case message, ok := <-client.Send:
+                if !ok {
+                    return
+                }
+                client.Mu.Lock()
+                err := client.Conn.WriteMessage(ws.TextMessage, message)
+                client.Mu.Unlock()
+
+                if err != nil {
+                    log.Printf("Error writing to client: %v", err)
+                    return
+                }
+            case data := <-client.binary:
+                if err := client.writeBinary(data); err != nil {
+                    log.Printf("Error writing snapshot to client: %v", err)
+                    return
+                }
             }
         }
     }()
@@ -125,9 +232,87 @@ func (h *Hub) Unregister(client *Client) {
     h.unregister <- client
 }
 
-// Broadcast sends a message to all clients
-func (h *Hub) Broadcast(message []byte) {
-    h.broadcast <- message
+// Publish sends a message to the clients on every replica subscribed to any of the given channels
+func (h *Hub) Publish(message []byte, channels ...string) {
+    if len(channels) == 0 {
+        return
+    }
+    h.publish(Envelope{Channels: channels, Data: message})
+}
+
+// publish hands an envelope to the backplane, which assigns its event ID, falling
+// back to local delivery so clients on this replica still get the message if the
+// backplane is down. Unsequenced events are still delivered but cannot be replayed.
+func (h *Hub) publish(envelope Envelope) {
+    ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+    defer cancel()
+
+    if err := h.backplane.Publish(ctx, envelope); err != nil {
+        log.Printf("Error publishing to websocket backplane, delivering locally: %v", err)
+        envelope.Seq = 0
+        h.deliver(envelope)
+    }
+}
+
+// deliver queues an envelope received from the backplane for local clients,
+// stamping its event ID into the message so clients can resume from it
+func (h *Hub) deliver(envelope Envelope) {
+    if envelope.Seq != 0 {
+        envelope.Data = withEventID(envelope.Data, envelope.Seq)
+    }
+    if envelope.Telemetry != nil {
+        h.updateFleet(*envelope.Telemetry)
+    }
+    h.broadcast <- outboundMessage{seq: envelope.Seq, kind: envelope.Kind, channels: envelope.Channels, data: envelope.Data}
+    if envelope.Kind == KindAccessChange {
+        // The authorizer may query the database, keep it off the backplane goroutine
+        go h.RecheckSubscriptions()
+    }
+}
+
+// Subscribe adds channels to the client's subscription set
+func (h *Hub) Subscribe(client *Client, channels ...string) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    h.subscribeLocked(client, channels)
+}
+
+// subscribeLocked adds channels to the client's subscription set.
+// The caller must hold the hub mutex.
+func (h *Hub) subscribeLocked(client *Client, channels []string) {
+    if client.subscriptions == nil {
+        client.subscriptions = make(map[string]bool)
+    }
+    for _, channel := range channels {
+        client.subscriptions[channel] = true
+    }
+    if client.Compact {
+        // Send the current state of the newly subscribed trucks on the next tick
+        client.needsFullSnapshot = true
+    }
+}
+
+// Unsubscribe removes channels from the client's subscription set
+func (h *Hub) Unsubscribe(client *Client, channels ...string) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    for _, channel := range channels {
+        delete(client.subscriptions, channel)
+    }
+}
+
+// Subscriptions returns the channels the client is currently subscribed to
+func (h *Hub) Subscriptions(client *Client) []string {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+
+    channels := make([]string, 0, len(client.subscriptions))
+    for channel := range client.subscriptions {
+        channels = append(channels, channel)
+    }
+    return channels
 }
 
 // UpdateClientPing updates the last ping time for a client
@@ -172,11 +357,15 @@ var (
     initOnce sync.Once
 )
 
-// InitHub initializes the global hub
-func InitHub() {
+// InitHub initializes the global hub on top of the given backplane
+func InitHub(backplane Backplane) {
     initOnce.Do(func() {
-        WSHub = NewHub()
+        WSHub = NewHub(backplane)
+        if err := WSHub.backplane.Subscribe(context.Background(), WSHub.deliver); err != nil {
+            log.Printf("Error subscribing to websocket backplane: %v", err)
+        }
         go WSHub.Run()
+        go WSHub.runSnapshots()
     })
 }
 
@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientMatches(t *testing.T) {
+	client := &Client{subscriptions: map[string]bool{ChannelAlerts: true, "truck:AA": true}}
+
+	tests := []struct {
+		name     string
+		channels []string
+		want     bool
+	}{
+		{"no channels", nil, false},
+		{"subscribed channel", []string{ChannelAlerts}, true},
+		{"one of several channels", []string{ChannelFleet, "truck:AA"}, true},
+		{"other truck", []string{"truck:BB"}, false},
+		{"not subscribed", []string{ChannelFleet, "route:1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := client.matches(tt.channels); got != tt.want {
+				t.Errorf("matches(%v) = %v, want %v", tt.channels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHubRoutesToSubscribers(t *testing.T) {
+	hub := NewHub(nil)
+	fleet := &Client{Send: make(chan []byte, 4), subscriptions: map[string]bool{ChannelFleet: true}}
+	alerts := &Client{Send: make(chan []byte, 4), subscriptions: map[string]bool{ChannelAlerts: true}}
+	compact := &Client{Send: make(chan []byte, 4), Compact: true, subscriptions: map[string]bool{ChannelFleet: true}}
+	hub.clients[fleet] = true
+	hub.clients[alerts] = true
+	hub.clients[compact] = true
+	go hub.Run()
+
+	tests := []struct {
+		name    string
+		message outboundMessage
+		want    map[*Client]bool
+	}{
+		{"fleet channel", outboundMessage{channels: []string{ChannelFleet}, data: []byte("a")}, map[*Client]bool{fleet: true, compact: true}},
+		{"alerts channel", outboundMessage{channels: []string{ChannelAlerts}, data: []byte("b")}, map[*Client]bool{alerts: true}},
+		{"no channels", outboundMessage{data: []byte("c")}, map[*Client]bool{}},
+		{"telemetry skips compact clients", outboundMessage{kind: KindTelemetry, channels: []string{ChannelFleet}, data: []byte("d")}, map[*Client]bool{fleet: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub.broadcast <- tt.message
+			for _, client := range []*Client{fleet, alerts, compact} {
+				select {
+				case data := <-client.Send:
+					if !tt.want[client] {
+						t.Errorf("unsubscribed client received %s", data)
+					} else if string(data) != string(tt.message.data) {
+						t.Errorf("client received %s, want %s", data, tt.message.data)
+					}
+				case <-time.After(100 * time.Millisecond):
+					if tt.want[client] {
+						t.Errorf("subscribed client did not receive %s", tt.message.data)
+					}
+				}
+			}
+		})
+	}
+}
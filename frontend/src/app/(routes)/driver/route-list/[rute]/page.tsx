@@ -794,19 +794,6 @@ const DriverRouteDetailPage = () => {
             </button>
           )}
 
-          {/* Button untuk membatalkan rute */}
-          {(routePlan.status === "planned" ||
-            routePlan.status === "active" ||
-            routePlan.status === "on confirmation") && (
-            <button
-              onClick={() => handleUpdateStatus("cancelled")}
-              className="px-4 py-2 bg-red-500 hover:bg-red-600 text-white rounded-md flex items-center gap-2 transition-colors"
-            >
-              <i className="bx bx-x"></i>
-              <span>Batalkan Rute</span>
-            </button>
-          )}
-
           {/* Button untuk menandai area */}
           {routePlan.status !== "completed" &&
             routePlan.status !== "cancelled" &&
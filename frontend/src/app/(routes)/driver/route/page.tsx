@@ -598,14 +598,6 @@ const DriverActiveRoutePage = () => {
               <i className="bx bx-check"></i>
               <span>Selesaikan Rute</span>
             </button>
-            
-            <button
-              onClick={() => handleUpdateStatus("cancelled")}
-              className="px-4 py-2 bg-red-500 hover:bg-red-600 text-white rounded-md flex items-center gap-2"
-            >
-              <i className="bx bx-x"></i>
-              <span>Batalkan Rute</span>
-            </button>
           </div>
         </div>
       </div>
@@ -174,6 +174,7 @@ const DashboardPage = () => {
   const [deviationThreshold] = useState(35); // Deviation threshold in meters
   const [deviationReferences, setDeviationReferences] = useState({});
   const socketRef = useRef(null);
+  const lastEventIdRef = useRef(0);
   const componentMountedRef = useRef(true);
   const chartRef = useRef(null);
   const mapRef = useRef(null);
@@ -1674,6 +1675,8 @@ const DashboardPage = () => {
         // For local development, use localhost with the backend port
         wsUrl = "ws://localhost:8080/ws";
       }
+      // Authenticate the socket with the user's JWT
+      wsUrl = `${wsUrl}?token=${encodeURIComponent(getToken() || "")}`;
       // console.log(`Attempting to connect to WebSocket at: ${wsUrl}`);
 
       try {
@@ -1691,6 +1694,16 @@ const DashboardPage = () => {
               timestamp: Date.now(),
             })
           );
+
+          // Subscribe to fleet telemetry and alerts, resuming after the
+          // last event we saw so updates missed while offline are replayed
+          socketRef.current.send(
+            JSON.stringify({
+              type: "subscribe",
+              channels: ["fleet", "alerts"],
+              last_event_id: lastEventIdRef.current || undefined,
+            })
+          );
         };
 
         socketRef.current.onmessage = (event) => {
@@ -1722,6 +1735,18 @@ const DashboardPage = () => {
               return;
             }
 
+            // Remember the last event so a reconnect can resume from it
+            if (message.event_id) {
+              lastEventIdRef.current = message.event_id;
+            }
+
+            // Too many events were missed to replay, reload the full state
+            if (message.type === "resync_required") {
+              lastEventIdRef.current = message.latest_event_id || 0;
+              fetchTrucks();
+              return;
+            }
+
             // Process truck updates
             if (
               message.mac_id &&
@@ -3010,15 +3035,19 @@ const DashboardPage = () => {
                               )
                               .map((position, index) => {
                                 // Check if this position is a deviation or idle point
+                                // Each deviation is an episode, its path holds every off-route sample
                                 const isDeviation = historicalDeviations.some(
                                   (d) =>
                                     d &&
-                                    typeof d.latitude === "number" &&
-                                    typeof d.longitude === "number" &&
-                                    Math.abs(d.latitude - position.latitude) <
-                                      0.0001 &&
-                                    Math.abs(d.longitude - position.longitude) <
-                                      0.0001
+                                    [d, ...(d.path || [])].some(
+                                      (p) =>
+                                        typeof p.latitude === "number" &&
+                                        typeof p.longitude === "number" &&
+                                        Math.abs(p.latitude - position.latitude) <
+                                          0.0001 &&
+                                        Math.abs(p.longitude - position.longitude) <
+                                          0.0001
+                                    )
                                 );
 
                                 const isIdle = historicalIdleDetections.some(
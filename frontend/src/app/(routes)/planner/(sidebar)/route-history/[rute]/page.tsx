@@ -569,6 +569,7 @@ const RouteHistoryPage = () => {
 				elevation: true,
 				instructions_format: "html",
 				language: "id",
+				route_plan_id: Number(params.rute),
 			};
 
 			// Add avoidance areas if any
@@ -890,6 +891,7 @@ const RouteHistoryPage = () => {
 					elevation: true,
 					instructions_format: "html",
 					language: "id",
+					route_plan_id: Number(params.rute),
 				};
 
 				// Add remaining avoidance areas if any
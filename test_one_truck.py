@@ -100,6 +100,7 @@ payload = {
     "Lat":  -6.898968611981169,
     "Lon": 107.61500888964427,
     "F": 21.0,
+    "Ign": 1,  # kunci kontak, opsional: true/false, 0/1, atau "on"/"off"
 }
 
 